@@ -0,0 +1,462 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	asgtype "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+)
+
+// Auto Scaling groups are stopped by saving their capacity in tags on the group and scaling it to zero, and started
+// by restoring the saved capacity. Their instances are left to the group rather than being stopped one by one.
+
+const (
+	asgMinSizeTagKey         string = "instance-scheduler:min-size"
+	asgMaxSizeTagKey         string = "instance-scheduler:max-size"
+	asgDesiredCapacityTagKey string = "instance-scheduler:desired-capacity"
+)
+
+type AutoScalingGroupCount struct {
+	ASGActedUpon int
+	ASGSkipped   int
+	ASGFailed    int
+}
+
+type IAutoScalingAPI interface {
+	DescribeAutoScalingGroups(ctx context.Context, params *autoscaling.DescribeAutoScalingGroupsInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DescribeAutoScalingGroupsOutput, error)
+	UpdateAutoScalingGroup(ctx context.Context, params *autoscaling.UpdateAutoScalingGroupInput, optFns ...func(*autoscaling.Options)) (*autoscaling.UpdateAutoScalingGroupOutput, error)
+	CreateOrUpdateTags(ctx context.Context, params *autoscaling.CreateOrUpdateTagsInput, optFns ...func(*autoscaling.Options)) (*autoscaling.CreateOrUpdateTagsOutput, error)
+	DeleteTags(ctx context.Context, params *autoscaling.DeleteTagsInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DeleteTagsOutput, error)
+}
+
+// AutoScalingGroupCapacity is the capacity of a group before the scheduler scaled it to zero
+type AutoScalingGroupCapacity struct {
+	MinSize         int32
+	MaxSize         int32
+	DesiredCapacity int32
+}
+
+func stopStartTestAutoScalingGroupsInMemberAccount(client IAutoScalingAPI, run *SchedulingRun) (*AutoScalingGroupCount, error) {
+	action := run.Action
+	if action == "stop" {
+		return stopAutoScalingGroups(client, run)
+	}
+	if action == "start" {
+		return startAutoScalingGroups(client, run)
+	}
+	if action == "test" {
+		return testAutoScalingGroups(client, run)
+	}
+	if action == "reconcile" {
+		return reconcileAutoScalingGroups(client, run)
+	}
+	return nil, invalidActionError(action)
+}
+
+func listAutoScalingGroups(client IAutoScalingAPI, run *SchedulingRun) ([]asgtype.AutoScalingGroup, error) {
+	groups := []asgtype.AutoScalingGroup{}
+	pages := autoscaling.NewDescribeAutoScalingGroupsPaginator(client, &autoscaling.DescribeAutoScalingGroupsInput{})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(run.ctx())
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, page.AutoScalingGroups...)
+	}
+	return groups, nil
+}
+
+// parseAutoScalingGroupTags reads the scheduling tags of a group and the capacity saved by the stop action. Groups
+// behind an EKS node group are skipped, as they are left to EKS.
+func parseAutoScalingGroupTags(run *SchedulingRun, resource autoScalingGroupResource, skippedGroups []string) (string, time.Time, *AutoScalingGroupCapacity, bool, []string) {
+	instanceSchedulingTag, overrideUntil, _, isSkipSchedulingTag, skippedGroups := parseResourceTags(run, resource, skippedGroups)
+	tags := resource.tags()
+
+	savedCapacity := map[string]string{}
+	for _, key := range []string{asgMinSizeTagKey, asgMaxSizeTagKey, asgDesiredCapacityTagKey} {
+		if value, ok := tags[key]; ok {
+			savedCapacity[key] = value
+		}
+	}
+
+	if eksNodegroupName := tags[eksNodegroupTagKey]; !isSkipSchedulingTag && eksNodegroupName != "" {
+		run.Printf("INFO: Skip Auto Scaling group because it belongs to EKS node group %v\n", eksNodegroupName)
+		skippedGroups = append(skippedGroups, resource.id())
+		isSkipSchedulingTag = true
+	}
+
+	return instanceSchedulingTag, overrideUntil, parseAutoScalingGroupCapacity(run, savedCapacity), isSkipSchedulingTag, skippedGroups
+}
+
+// parseAutoScalingGroupCapacity returns the capacity saved in tags by the stop action, or nil if there is none
+func parseAutoScalingGroupCapacity(run *SchedulingRun, savedCapacity map[string]string) *AutoScalingGroupCapacity {
+	if len(savedCapacity) == 0 {
+		return nil
+	}
+	values := map[string]int32{}
+	for _, key := range []string{asgMinSizeTagKey, asgMaxSizeTagKey, asgDesiredCapacityTagKey} {
+		value, err := strconv.ParseInt(savedCapacity[key], 10, 32)
+		if err != nil || value < 0 {
+			run.Printf("WARN: Ignoring saved Auto Scaling group capacity because tag %v has invalid value '%v'\n", key, savedCapacity[key])
+			return nil
+		}
+		values[key] = int32(value)
+	}
+	return &AutoScalingGroupCapacity{
+		MinSize:         values[asgMinSizeTagKey],
+		MaxSize:         values[asgMaxSizeTagKey],
+		DesiredCapacity: values[asgDesiredCapacityTagKey],
+	}
+}
+
+func isScaledToZero(group asgtype.AutoScalingGroup) bool {
+	return aws.ToInt32(group.MinSize) == 0 && aws.ToInt32(group.MaxSize) == 0 && aws.ToInt32(group.DesiredCapacity) == 0
+}
+
+func stopAutoScalingGroups(client IAutoScalingAPI, run *SchedulingRun) (*AutoScalingGroupCount, error) {
+	groups, err := listAutoScalingGroups(client, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about Auto Scaling groups: %w", err)
+	}
+
+	groupsActedUpon := []string{}
+	skippedGroups := []string{}
+	failedGroups := []string{}
+	for _, group := range groups {
+		run.Printf("INFO: Auto Scaling group: [ %v ]\n", *group.AutoScalingGroupName)
+		resource := autoScalingGroupResource{client: client, group: group}
+		instanceSchedulingTag, overrideUntil, savedCapacity, skipGroup, skippedGroupsModified := parseAutoScalingGroupTags(run, resource, skippedGroups)
+		skippedGroups = skippedGroupsModified
+
+		if skipGroup {
+			continue
+		}
+
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		if isOverrideActive(overrideUntil, run.Now) {
+			run.Printf("INFO: Skipped Auto Scaling group because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+			skippedGroups = append(skippedGroups, *group.AutoScalingGroupName)
+			continue
+		}
+
+		if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+			run.Printf("INFO: Skipped Auto Scaling group because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+			skippedGroups = append(skippedGroups, *group.AutoScalingGroupName)
+			continue
+		}
+
+		if instanceSchedulingTag == "skip-auto-stop" {
+			run.Printf("INFO: Skipped Auto Scaling group because instance-scheduling tag having value 'skip-auto-stop'\n")
+			skippedGroups = append(skippedGroups, *group.AutoScalingGroupName)
+			continue
+		}
+
+		if savedCapacity != nil || isScaledToZero(group) {
+			run.Printf("INFO: Skipped Auto Scaling group because it is already scaled to zero\n")
+			skippedGroups = append(skippedGroups, *group.AutoScalingGroupName)
+			continue
+		}
+
+		run.Printf("INFO: Stopping Auto Scaling group because instance-scheduling tag is absent\n")
+		if err := stopAutoScalingGroup(client, run, group); err != nil {
+			failedGroups = append(failedGroups, *group.AutoScalingGroupName)
+			continue
+		}
+		groupsActedUpon = append(groupsActedUpon, *group.AutoScalingGroupName)
+	}
+
+	run.Printf("INFO: Stopped %v Auto Scaling groups: %v\n", len(groupsActedUpon), groupsActedUpon)
+	run.Printf("INFO: Skipped %v Auto Scaling groups due to instance-scheduling tag or capacity: %v\n", len(skippedGroups), skippedGroups)
+	run.Printf("INFO: Could not stop %v Auto Scaling groups: %v\n", len(failedGroups), failedGroups)
+
+	return &AutoScalingGroupCount{ASGActedUpon: len(groupsActedUpon), ASGSkipped: len(skippedGroups), ASGFailed: len(failedGroups)}, nil
+}
+
+func startAutoScalingGroups(client IAutoScalingAPI, run *SchedulingRun) (*AutoScalingGroupCount, error) {
+	groups, err := listAutoScalingGroups(client, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about Auto Scaling groups: %w", err)
+	}
+
+	groupsActedUpon := []string{}
+	skippedGroups := []string{}
+	failedGroups := []string{}
+	for _, group := range groups {
+		run.Printf("INFO: Auto Scaling group: [ %v ]\n", *group.AutoScalingGroupName)
+		resource := autoScalingGroupResource{client: client, group: group}
+		instanceSchedulingTag, overrideUntil, savedCapacity, skipGroup, skippedGroupsModified := parseAutoScalingGroupTags(run, resource, skippedGroups)
+		skippedGroups = skippedGroupsModified
+
+		if skipGroup {
+			continue
+		}
+
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+			run.Printf("INFO: Skipped Auto Scaling group because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+			skippedGroups = append(skippedGroups, *group.AutoScalingGroupName)
+			continue
+		}
+
+		if instanceSchedulingTag == "skip-auto-start" {
+			run.Printf("INFO: Skipped Auto Scaling group because instance-scheduling tag having value 'skip-auto-start'\n")
+			skippedGroups = append(skippedGroups, *group.AutoScalingGroupName)
+			continue
+		}
+
+		if savedCapacity == nil {
+			run.Printf("INFO: Skipped Auto Scaling group because it was not scaled to zero by the scheduler\n")
+			skippedGroups = append(skippedGroups, *group.AutoScalingGroupName)
+			continue
+		}
+
+		run.Printf("INFO: Starting Auto Scaling group because it was scaled to zero by the scheduler\n")
+		if err := startAutoScalingGroup(client, run, group, savedCapacity); err != nil {
+			failedGroups = append(failedGroups, *group.AutoScalingGroupName)
+			continue
+		}
+		groupsActedUpon = append(groupsActedUpon, *group.AutoScalingGroupName)
+	}
+
+	run.Printf("INFO: Started %v Auto Scaling groups: %v\n", len(groupsActedUpon), groupsActedUpon)
+	run.Printf("INFO: Skipped %v Auto Scaling groups due to instance-scheduling tag or not being stopped by the scheduler: %v\n", len(skippedGroups), skippedGroups)
+	run.Printf("INFO: Could not start %v Auto Scaling groups: %v\n", len(failedGroups), failedGroups)
+
+	return &AutoScalingGroupCount{ASGActedUpon: len(groupsActedUpon), ASGSkipped: len(skippedGroups), ASGFailed: len(failedGroups)}, nil
+}
+
+func testAutoScalingGroups(client IAutoScalingAPI, run *SchedulingRun) (*AutoScalingGroupCount, error) {
+	groups, err := listAutoScalingGroups(client, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about Auto Scaling groups: %w", err)
+	}
+
+	groupsActedUpon := []string{}
+	skippedGroups := []string{}
+	for _, group := range groups {
+		run.Printf("INFO: Auto Scaling group: [ %v ]\n", *group.AutoScalingGroupName)
+		resource := autoScalingGroupResource{client: client, group: group}
+		instanceSchedulingTag, overrideUntil, _, skipGroup, skippedGroupsModified := parseAutoScalingGroupTags(run, resource, skippedGroups)
+		skippedGroups = skippedGroupsModified
+
+		if skipGroup {
+			continue
+		}
+
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		if isOverrideActive(overrideUntil, run.Now) {
+			run.Printf("INFO: Skipped Auto Scaling group because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+			skippedGroups = append(skippedGroups, *group.AutoScalingGroupName)
+			continue
+		}
+
+		if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+			run.Printf("INFO: Skipped Auto Scaling group because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+			skippedGroups = append(skippedGroups, *group.AutoScalingGroupName)
+			continue
+		}
+
+		if instanceSchedulingTag == "skip-auto-stop" || instanceSchedulingTag == "skip-auto-start" {
+			run.Printf("INFO: Skipped Auto Scaling group because instance-scheduling tag having value 'skip-auto-stop' or 'skip-auto-start'\n")
+			skippedGroups = append(skippedGroups, *group.AutoScalingGroupName)
+			continue
+		}
+
+		groupsActedUpon = append(groupsActedUpon, *group.AutoScalingGroupName)
+		run.Printf("INFO: Successfully tested Auto Scaling group %v\n", *group.AutoScalingGroupName)
+	}
+
+	run.Printf("INFO: Tested %v Auto Scaling groups: %v\n", len(groupsActedUpon), groupsActedUpon)
+	run.Printf("INFO: Skipped %v Auto Scaling groups due to instance-scheduling tag: %v\n", len(skippedGroups), skippedGroups)
+
+	return &AutoScalingGroupCount{ASGActedUpon: len(groupsActedUpon), ASGSkipped: len(skippedGroups)}, nil
+}
+
+func reconcileAutoScalingGroups(client IAutoScalingAPI, run *SchedulingRun) (*AutoScalingGroupCount, error) {
+	groups, err := listAutoScalingGroups(client, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about Auto Scaling groups: %w", err)
+	}
+
+	groupsStarted := []string{}
+	groupsStopped := []string{}
+	skippedGroups := []string{}
+	failedGroups := []string{}
+	for _, group := range groups {
+		run.Printf("INFO: Auto Scaling group: [ %v ]\n", *group.AutoScalingGroupName)
+		resource := autoScalingGroupResource{client: client, group: group}
+		instanceSchedulingTag, overrideUntil, savedCapacity, skipGroup, skippedGroupsModified := parseAutoScalingGroupTags(run, resource, skippedGroups)
+		skippedGroups = skippedGroupsModified
+
+		if skipGroup {
+			continue
+		}
+
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		schedule := run.Schedules.getSchedule(run, instanceSchedulingTag)
+		if schedule == nil {
+			run.Printf("INFO: Skipped Auto Scaling group because its instance-scheduling tag does not reference a schedule\n")
+			skippedGroups = append(skippedGroups, *group.AutoScalingGroupName)
+			continue
+		}
+
+		desiredState := run.desiredState(schedule)
+
+		if desiredState == scheduleStateRunning && savedCapacity != nil {
+			run.Printf("INFO: Starting Auto Scaling group because schedule '%v' expects it to be running\n", schedule.Name)
+			if err := startAutoScalingGroup(client, run, group, savedCapacity); err != nil {
+				failedGroups = append(failedGroups, *group.AutoScalingGroupName)
+				continue
+			}
+			groupsStarted = append(groupsStarted, *group.AutoScalingGroupName)
+			continue
+		}
+
+		if desiredState == scheduleStateStopped && savedCapacity == nil && !isScaledToZero(group) && isOverrideActive(overrideUntil, run.Now) {
+			run.Printf("INFO: Skipped stopping Auto Scaling group because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+			skippedGroups = append(skippedGroups, *group.AutoScalingGroupName)
+			continue
+		}
+
+		if desiredState == scheduleStateStopped && savedCapacity == nil && !isScaledToZero(group) {
+			run.Printf("INFO: Stopping Auto Scaling group because schedule '%v' expects it to be stopped\n", schedule.Name)
+			if err := stopAutoScalingGroup(client, run, group); err != nil {
+				failedGroups = append(failedGroups, *group.AutoScalingGroupName)
+				continue
+			}
+			groupsStopped = append(groupsStopped, *group.AutoScalingGroupName)
+			continue
+		}
+
+		run.Printf("INFO: Skipped Auto Scaling group because schedule '%v' expects it to be '%v'\n", schedule.Name, desiredState)
+		skippedGroups = append(skippedGroups, *group.AutoScalingGroupName)
+	}
+
+	run.Printf("INFO: Started %v Auto Scaling groups: %v\n", len(groupsStarted), groupsStarted)
+	run.Printf("INFO: Stopped %v Auto Scaling groups: %v\n", len(groupsStopped), groupsStopped)
+	run.Printf("INFO: Skipped %v Auto Scaling groups due to instance-scheduling tag or schedule: %v\n", len(skippedGroups), skippedGroups)
+	run.Printf("INFO: Could not start or stop %v Auto Scaling groups: %v\n", len(failedGroups), failedGroups)
+
+	return &AutoScalingGroupCount{ASGActedUpon: len(groupsStarted) + len(groupsStopped), ASGSkipped: len(skippedGroups), ASGFailed: len(failedGroups)}, nil
+}
+
+// stopAutoScalingGroup saves the capacity of a group in tags and then scales it to zero. The group is left alone
+// if its capacity cannot be saved, as it could not be restored afterwards, and the saved capacity is removed again if
+// the group cannot be scaled to zero, as later runs would take it to be stopped.
+func stopAutoScalingGroup(client IAutoScalingAPI, run *SchedulingRun, group asgtype.AutoScalingGroup) error {
+	groupName := *group.AutoScalingGroupName
+	savedCapacity := []resourceTag{
+		{key: asgMinSizeTagKey, value: strconv.Itoa(int(aws.ToInt32(group.MinSize)))},
+		{key: asgMaxSizeTagKey, value: strconv.Itoa(int(aws.ToInt32(group.MaxSize)))},
+		{key: asgDesiredCapacityTagKey, value: strconv.Itoa(int(aws.ToInt32(group.DesiredCapacity)))},
+	}
+	err := autoScalingGroupResource{client: client, group: group}.addTags(run, append(savedCapacity, stoppedBySchedulerTags(run)...))
+	if err != nil {
+		run.Printf("ERROR: Could not save the capacity of Auto Scaling group %v, so it was not stopped: %v\n", groupName, err)
+		return err
+	}
+
+	_, err = client.UpdateAutoScalingGroup(run.ctx(), &autoscaling.UpdateAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String(groupName),
+		MinSize:              aws.Int32(0),
+		MaxSize:              aws.Int32(0),
+		DesiredCapacity:      aws.Int32(0),
+	})
+	if err != nil {
+		run.Printf("ERROR: Could not stop Auto Scaling group: %v\n", err)
+		removeSavedCapacityTags(client, run, group)
+		return err
+	}
+	run.Printf("INFO: Successfully stopped Auto Scaling group %v\n", groupName)
+	return nil
+}
+
+// startAutoScalingGroup restores the capacity saved by stopAutoScalingGroup and then removes the tags holding it
+func startAutoScalingGroup(client IAutoScalingAPI, run *SchedulingRun, group asgtype.AutoScalingGroup, capacity *AutoScalingGroupCapacity) error {
+	groupName := *group.AutoScalingGroupName
+	_, err := client.UpdateAutoScalingGroup(run.ctx(), &autoscaling.UpdateAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String(groupName),
+		MinSize:              aws.Int32(capacity.MinSize),
+		MaxSize:              aws.Int32(capacity.MaxSize),
+		DesiredCapacity:      aws.Int32(capacity.DesiredCapacity),
+	})
+	if err != nil {
+		run.Printf("ERROR: Could not start Auto Scaling group: %v\n", err)
+		return err
+	}
+	run.Printf("INFO: Successfully started Auto Scaling group %v\n", groupName)
+
+	removeSavedCapacityTags(client, run, group)
+	return nil
+}
+
+// removeSavedCapacityTags removes the tags in which stopAutoScalingGroup saved the capacity of a group
+func removeSavedCapacityTags(client IAutoScalingAPI, run *SchedulingRun, group asgtype.AutoScalingGroup) {
+	err := autoScalingGroupResource{client: client, group: group}.removeTags(run, append([]string{asgMinSizeTagKey, asgMaxSizeTagKey, asgDesiredCapacityTagKey}, stoppedBySchedulerTagKeys...))
+	if err != nil {
+		run.Printf("ERROR: Could not remove the saved capacity tags from Auto Scaling group %v: %v\n", *group.AutoScalingGroupName, err)
+	}
+}
+
+// autoScalingGroupResource adapts an Auto Scaling group to the tag helpers shared by every service
+type autoScalingGroupResource struct {
+	client IAutoScalingAPI
+	group  asgtype.AutoScalingGroup
+}
+
+func (resource autoScalingGroupResource) kind() string {
+	return "Auto Scaling group"
+}
+
+func (resource autoScalingGroupResource) id() string {
+	return aws.ToString(resource.group.AutoScalingGroupName)
+}
+
+func (resource autoScalingGroupResource) tags() map[string]string {
+	tags := map[string]string{}
+	for _, tag := range resource.group.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags
+}
+
+func (resource autoScalingGroupResource) addTags(run *SchedulingRun, tags []resourceTag) error {
+	asgTags := []asgtype.Tag{}
+	for _, tag := range tags {
+		asgTags = append(asgTags, resource.tag(tag.key, tag.value))
+	}
+	_, err := resource.client.CreateOrUpdateTags(run.ctx(), &autoscaling.CreateOrUpdateTagsInput{Tags: asgTags})
+	return err
+}
+
+func (resource autoScalingGroupResource) removeTags(run *SchedulingRun, keys []string) error {
+	asgTags := []asgtype.Tag{}
+	for _, key := range keys {
+		asgTags = append(asgTags, resource.tag(key, ""))
+	}
+	_, err := resource.client.DeleteTags(run.ctx(), &autoscaling.DeleteTagsInput{Tags: asgTags})
+	return err
+}
+
+// tag returns a tag on the group which is not propagated to the instances it launches
+func (resource autoScalingGroupResource) tag(key string, value string) asgtype.Tag {
+	return asgtype.Tag{
+		ResourceId:        resource.group.AutoScalingGroupName,
+		ResourceType:      aws.String("auto-scaling-group"),
+		Key:               aws.String(key),
+		Value:             aws.String(value),
+		PropagateAtLaunch: aws.Bool(false),
+	}
+}
+
+func getAutoScalingClientForMemberAccount(session *MemberAccountSession) IAutoScalingAPI {
+	return autoscaling.NewFromConfig(session.Config)
+}
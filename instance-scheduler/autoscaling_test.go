@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	asgtype "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockIAutoScalingAPI struct {
+	DescribeAutoScalingGroupsOutput *autoscaling.DescribeAutoScalingGroupsOutput
+	DescribeAutoScalingGroupsPages  []*autoscaling.DescribeAutoScalingGroupsOutput
+	UpdateAutoScalingGroupInputs    []*autoscaling.UpdateAutoScalingGroupInput
+	CreateOrUpdateTagsInputs        []*autoscaling.CreateOrUpdateTagsInput
+	DeleteTagsInputs                []*autoscaling.DeleteTagsInput
+	UpdateAutoScalingGroupError     error
+}
+
+func (m *mockIAutoScalingAPI) DescribeAutoScalingGroups(ctx context.Context, params *autoscaling.DescribeAutoScalingGroupsInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	if len(m.DescribeAutoScalingGroupsPages) == 0 {
+		return m.DescribeAutoScalingGroupsOutput, nil
+	}
+
+	// pages are chained by their index, as NextToken
+	page := 0
+	if params.NextToken != nil {
+		page, _ = strconv.Atoi(*params.NextToken)
+	}
+	output := *m.DescribeAutoScalingGroupsPages[page]
+	if page+1 < len(m.DescribeAutoScalingGroupsPages) {
+		output.NextToken = aws.String(strconv.Itoa(page + 1))
+	}
+	return &output, nil
+}
+
+func (m *mockIAutoScalingAPI) UpdateAutoScalingGroup(ctx context.Context, params *autoscaling.UpdateAutoScalingGroupInput, optFns ...func(*autoscaling.Options)) (*autoscaling.UpdateAutoScalingGroupOutput, error) {
+	m.UpdateAutoScalingGroupInputs = append(m.UpdateAutoScalingGroupInputs, params)
+	if m.UpdateAutoScalingGroupError != nil {
+		return nil, m.UpdateAutoScalingGroupError
+	}
+	return &autoscaling.UpdateAutoScalingGroupOutput{}, nil
+}
+
+func (m *mockIAutoScalingAPI) CreateOrUpdateTags(ctx context.Context, params *autoscaling.CreateOrUpdateTagsInput, optFns ...func(*autoscaling.Options)) (*autoscaling.CreateOrUpdateTagsOutput, error) {
+	m.CreateOrUpdateTagsInputs = append(m.CreateOrUpdateTagsInputs, params)
+	return &autoscaling.CreateOrUpdateTagsOutput{}, nil
+}
+
+func (m *mockIAutoScalingAPI) DeleteTags(ctx context.Context, params *autoscaling.DeleteTagsInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DeleteTagsOutput, error) {
+	m.DeleteTagsInputs = append(m.DeleteTagsInputs, params)
+	return &autoscaling.DeleteTagsOutput{}, nil
+}
+
+func testAutoScalingGroup(name string, minSize int32, maxSize int32, desiredCapacity int32, tags map[string]string) asgtype.AutoScalingGroup {
+	group := asgtype.AutoScalingGroup{
+		AutoScalingGroupName: aws.String(name),
+		MinSize:              aws.Int32(minSize),
+		MaxSize:              aws.Int32(maxSize),
+		DesiredCapacity:      aws.Int32(desiredCapacity),
+	}
+	for key, value := range tags {
+		group.Tags = append(group.Tags, asgtype.TagDescription{Key: aws.String(key), Value: aws.String(value)})
+	}
+	return group
+}
+
+var testSavedCapacityTags = map[string]string{
+	"instance-scheduler:min-size":         "1",
+	"instance-scheduler:max-size":         "4",
+	"instance-scheduler:desired-capacity": "2",
+	"instance-scheduler:stopped-by":       "scheduler",
+}
+
+func TestStopStartTestAutoScalingGroupsInMemberAccount(t *testing.T) {
+	groups := []asgtype.AutoScalingGroup{
+		// no instance-scheduling tag and running, acted upon by stop and test
+		testAutoScalingGroup("bastion_linux_daily", 1, 1, 1, nil),
+		// instance-scheduling = skip-scheduling, skipped: 1
+		testAutoScalingGroup("weblogic-CNOMT1", 1, 2, 1, map[string]string{"instance-scheduling": "skip-scheduling"}),
+		// instance-scheduling = skip-auto-stop, skipped by stop and test, acted upon by start as it carries saved capacity
+		testAutoScalingGroup("weblogic-CNOMT2", 0, 0, 0, map[string]string{
+			"instance-scheduling":                 "skip-auto-stop",
+			"instance-scheduler:min-size":         "1",
+			"instance-scheduler:max-size":         "2",
+			"instance-scheduler:desired-capacity": "1",
+		}),
+		// instance-scheduling = skip-auto-start and running, skipped by start and test, acted upon by stop
+		testAutoScalingGroup("weblogic-CNOMT3", 2, 2, 2, map[string]string{"instance-scheduling": "skip-auto-start"}),
+		// scaled to zero by the scheduler, skipped by stop, acted upon by start and test
+		testAutoScalingGroup("oasys-web", 0, 0, 0, testSavedCapacityTags),
+		// scaled to zero by someone else, skipped by stop and start, acted upon by test
+		testAutoScalingGroup("oasys-batch", 0, 0, 0, nil),
+		// office-hours expects the group to be running at midday and it is scaled to zero by the scheduler, acted upon by reconcile
+		testAutoScalingGroup("nomis-web", 0, 0, 0, map[string]string{
+			"instance-scheduling":                 "office-hours",
+			"instance-scheduler:min-size":         "1",
+			"instance-scheduler:max-size":         "1",
+			"instance-scheduler:desired-capacity": "1",
+		}),
+		// nights-only expects the group to be stopped at midday and it is running, acted upon by reconcile
+		testAutoScalingGroup("nomis-db", 1, 1, 1, map[string]string{"instance-scheduling": "nights-only"}),
+		// behind an EKS managed node group, which is scheduled through EKS, skipped: 1
+		testAutoScalingGroup("eks-workers-1ac6d5e4", 2, 2, 2, map[string]string{"eks:nodegroup-name": "workers"}),
+	}
+
+	tests := []struct {
+		testTitle      string
+		action         string
+		expectedCount  AutoScalingGroupCount
+		expectedUpdate []string
+	}{
+		{
+			testTitle:      "ASG testing Test action",
+			action:         "test",
+			expectedCount:  AutoScalingGroupCount{ASGActedUpon: 3, ASGSkipped: 6},
+			expectedUpdate: nil,
+		},
+		{
+			testTitle:      "ASG testing Stop action",
+			action:         "stop",
+			expectedCount:  AutoScalingGroupCount{ASGActedUpon: 2, ASGSkipped: 7},
+			expectedUpdate: []string{"bastion_linux_daily", "weblogic-CNOMT3"},
+		},
+		{
+			testTitle:      "ASG testing Start action",
+			action:         "start",
+			expectedCount:  AutoScalingGroupCount{ASGActedUpon: 2, ASGSkipped: 7},
+			expectedUpdate: []string{"weblogic-CNOMT2", "oasys-web"},
+		},
+		{
+			testTitle:      "ASG testing Reconcile action",
+			action:         "reconcile",
+			expectedCount:  AutoScalingGroupCount{ASGActedUpon: 2, ASGSkipped: 7},
+			expectedUpdate: []string{"nomis-web", "nomis-db"},
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.testTitle, func(t *testing.T) {
+			client := &mockIAutoScalingAPI{
+				DescribeAutoScalingGroupsOutput: &autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: groups},
+			}
+			actualCount, err := stopStartTestAutoScalingGroupsInMemberAccount(client, &SchedulingRun{Action: subtest.action, Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+			assert.NoError(t, err)
+			assert.Equal(t, subtest.expectedCount, *actualCount)
+
+			var actualUpdate []string
+			for _, input := range client.UpdateAutoScalingGroupInputs {
+				actualUpdate = append(actualUpdate, *input.AutoScalingGroupName)
+			}
+			assert.Equal(t, subtest.expectedUpdate, actualUpdate)
+		})
+	}
+}
+
+func TestStopAndStartAutoScalingGroup(t *testing.T) {
+	client := &mockIAutoScalingAPI{
+		DescribeAutoScalingGroupsOutput: &autoscaling.DescribeAutoScalingGroupsOutput{
+			AutoScalingGroups: []asgtype.AutoScalingGroup{testAutoScalingGroup("bastion_linux_daily", 1, 4, 2, nil)},
+		},
+	}
+	stopStartTestAutoScalingGroupsInMemberAccount(client, &SchedulingRun{Action: "stop", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+
+	assert.Len(t, client.CreateOrUpdateTagsInputs, 1)
+	savedTags := map[string]string{}
+	for _, tag := range client.CreateOrUpdateTagsInputs[0].Tags {
+		assert.Equal(t, "bastion_linux_daily", *tag.ResourceId)
+		assert.False(t, *tag.PropagateAtLaunch)
+		savedTags[*tag.Key] = *tag.Value
+	}
+	assert.Equal(t, map[string]string{
+		"instance-scheduler:min-size":         "1",
+		"instance-scheduler:max-size":         "4",
+		"instance-scheduler:desired-capacity": "2",
+		"instance-scheduler:stopped-by":       "scheduler",
+		"instance-scheduler:stopped-at":       "2026-10-14T12:00:00Z",
+	}, savedTags)
+	assert.Equal(t, []*autoscaling.UpdateAutoScalingGroupInput{{
+		AutoScalingGroupName: aws.String("bastion_linux_daily"),
+		MinSize:              aws.Int32(0),
+		MaxSize:              aws.Int32(0),
+		DesiredCapacity:      aws.Int32(0),
+	}}, client.UpdateAutoScalingGroupInputs)
+
+	client = &mockIAutoScalingAPI{
+		DescribeAutoScalingGroupsOutput: &autoscaling.DescribeAutoScalingGroupsOutput{
+			AutoScalingGroups: []asgtype.AutoScalingGroup{testAutoScalingGroup("bastion_linux_daily", 0, 0, 0, savedTags)},
+		},
+	}
+	stopStartTestAutoScalingGroupsInMemberAccount(client, &SchedulingRun{Action: "start", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+
+	assert.Equal(t, []*autoscaling.UpdateAutoScalingGroupInput{{
+		AutoScalingGroupName: aws.String("bastion_linux_daily"),
+		MinSize:              aws.Int32(1),
+		MaxSize:              aws.Int32(4),
+		DesiredCapacity:      aws.Int32(2),
+	}}, client.UpdateAutoScalingGroupInputs)
+	assert.Len(t, client.DeleteTagsInputs, 1)
+	assert.Len(t, client.DeleteTagsInputs[0].Tags, 5)
+}
+
+func TestStopAutoScalingGroupWhenItCannotBeScaledToZero(t *testing.T) {
+	client := &mockIAutoScalingAPI{
+		DescribeAutoScalingGroupsOutput: &autoscaling.DescribeAutoScalingGroupsOutput{
+			AutoScalingGroups: []asgtype.AutoScalingGroup{testAutoScalingGroup("bastion_linux_daily", 1, 4, 2, nil)},
+		},
+		UpdateAutoScalingGroupError: errors.New("ScalingActivityInProgress"),
+	}
+	actualCount, err := stopStartTestAutoScalingGroupsInMemberAccount(client, &SchedulingRun{Action: "stop", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+	assert.NoError(t, err)
+
+	assert.Equal(t, AutoScalingGroupCount{ASGFailed: 1}, *actualCount)
+	assert.Len(t, client.CreateOrUpdateTagsInputs, 1)
+	assert.Len(t, client.DeleteTagsInputs, 1)
+	assert.Len(t, client.DeleteTagsInputs[0].Tags, 5)
+}
+
+func TestParseAutoScalingGroupCapacity(t *testing.T) {
+	tests := []struct {
+		testTitle     string
+		savedCapacity map[string]string
+		expected      *AutoScalingGroupCapacity
+	}{
+		{
+			testTitle:     "no saved capacity",
+			savedCapacity: map[string]string{},
+			expected:      nil,
+		},
+		{
+			testTitle: "saved capacity",
+			savedCapacity: map[string]string{
+				"instance-scheduler:min-size":         "1",
+				"instance-scheduler:max-size":         "4",
+				"instance-scheduler:desired-capacity": "2",
+			},
+			expected: &AutoScalingGroupCapacity{MinSize: 1, MaxSize: 4, DesiredCapacity: 2},
+		},
+		{
+			testTitle: "partially saved capacity",
+			savedCapacity: map[string]string{
+				"instance-scheduler:min-size": "1",
+			},
+			expected: nil,
+		},
+		{
+			testTitle: "invalid saved capacity",
+			savedCapacity: map[string]string{
+				"instance-scheduler:min-size":         "1",
+				"instance-scheduler:max-size":         "four",
+				"instance-scheduler:desired-capacity": "2",
+			},
+			expected: nil,
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.testTitle, func(t *testing.T) {
+			assert.Equal(t, subtest.expected, parseAutoScalingGroupCapacity(&SchedulingRun{}, subtest.savedCapacity))
+		})
+	}
+}
+
+func TestListAutoScalingGroupsAcrossPages(t *testing.T) {
+	client := &mockIAutoScalingAPI{
+		DescribeAutoScalingGroupsPages: []*autoscaling.DescribeAutoScalingGroupsOutput{
+			{AutoScalingGroups: []asgtype.AutoScalingGroup{testAutoScalingGroup("bastion_linux", 1, 4, 2, nil)}},
+			{AutoScalingGroups: []asgtype.AutoScalingGroup{testAutoScalingGroup("bastion_windows", 1, 2, 1, nil)}},
+		},
+	}
+	actualCount, err := stopStartTestAutoScalingGroupsInMemberAccount(client, &SchedulingRun{Action: "stop", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+	assert.NoError(t, err)
+
+	assert.Equal(t, AutoScalingGroupCount{ASGActedUpon: 2}, *actualCount)
+	var actualUpdate []string
+	for _, input := range client.UpdateAutoScalingGroupInputs {
+		actualUpdate = append(actualUpdate, *input.AutoScalingGroupName)
+	}
+	assert.Equal(t, []string{"bastion_linux", "bastion_windows"}, actualUpdate)
+}
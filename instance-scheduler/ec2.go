@@ -5,82 +5,138 @@ import (
 	"errors"
 	"fmt"
 
-	"log"
-	"strings"
+	"slices"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2type "github.com/aws/aws-sdk-go-v2/service/ec2/types"
-	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/aws/smithy-go"
 )
 
+// stopModeTagKey selects how an instance is stopped. Instances launched with hibernation configured are hibernated
+// unless the tag has the value "stop", and the value "hibernate" asks for hibernation on any instance.
+const stopModeTagKey string = "instance-scheduling-stop-mode"
+
 type InstanceCount struct {
-	actedUpon         int
-	skipped           int
-	skippedAutoScaled int
+	actedUpon             int
+	skipped               int
+	skippedAutoScaled     int
+	stoppedNotByScheduler int
+	hibernated            int
+	alreadyInDesiredState int
+	notActionable         int
+	failed                int
+}
+
+// ec2InstanceBatchSize is the number of instance IDs sent in one StopInstances, StartInstances, CreateTags or
+// DeleteTags call
+const ec2InstanceBatchSize = 100
+
+// ec2MinPageSize and ec2MaxPageSize are the MaxResults accepted by DescribeInstances
+const (
+	ec2MinPageSize int32 = 5
+	ec2MaxPageSize int32 = 1000
+)
+
+// actionableInstanceStates are the instance states requested from DescribeInstances, as instances that are shutting
+// down or terminated can be neither stopped nor started
+var actionableInstanceStates = []string{
+	string(ec2type.InstanceStateNamePending),
+	string(ec2type.InstanceStateNameRunning),
+	string(ec2type.InstanceStateNameStopping),
+	string(ec2type.InstanceStateNameStopped),
 }
 
 type IEC2InstancesAPI interface {
 	StopInstances(ctx context.Context, params *ec2.StopInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error)
 	StartInstances(ctx context.Context, params *ec2.StartInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error)
 	DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+	CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error)
+	DeleteTags(ctx context.Context, params *ec2.DeleteTagsInput, optFns ...func(*ec2.Options)) (*ec2.DeleteTagsOutput, error)
 }
 
-func stopStartTestInstancesInMemberAccount(client IEC2InstancesAPI, action string) *InstanceCount {
+func stopStartTestInstancesInMemberAccount(client IEC2InstancesAPI, run *SchedulingRun) (*InstanceCount, error) {
+	action := run.Action
 	if action == "stop" {
-		return stopEc2Instances(client)
+		return stopEc2Instances(client, run)
 	}
 	if action == "start" {
-		return startEc2Instances(client)
+		return startEc2Instances(client, run)
 	}
 	if action == "test" {
-		return testEc2Instances(client)
+		return testEc2Instances(client, run)
 	}
-	log.Fatalf("Invalid action: [ %v ]", action)
-	return nil
+	if action == "reconcile" {
+		return reconcileEc2Instances(client, run)
+	}
+	return nil, invalidActionError(action)
 }
 
-func parseInstanceTags(instance ec2type.Instance, skippedInstances []string, skippedAutoScaledInstances []string) (string, bool, []string, []string) {
-	var instanceSchedulingTag string
-	isPartOfAutoScalingGroup := false
-	isSkipSchedulingTag := false
-	for _, tag := range instance.Tags {
-		if *tag.Key == "aws:autoscaling:groupName" {
-			log.Printf("INFO: Skip instance because aws:autoscaling:groupName tag because it is part of an Auto Scaling group\n")
-			skippedAutoScaledInstances = append(skippedAutoScaledInstances, *instance.InstanceId)
-			isPartOfAutoScalingGroup = true
-		}
-		if *tag.Key == "instance-scheduling" {
-			instanceSchedulingTag = *tag.Value
-		}
-		if *tag.Key == "instance-scheduling" && *tag.Value == "skip-scheduling" {
-			log.Printf("INFO: Skip instance because instance-scheduling tag having value 'skip-scheduling'\n")
-			skippedInstances = append(skippedInstances, *instance.InstanceId)
-			isSkipSchedulingTag = true
+// listEc2Instances returns the reservations of every instance in an actionable state, requesting the run's page size
+// per page, within the range EC2 accepts, unless it is zero
+func listEc2Instances(client IEC2InstancesAPI, run *SchedulingRun) ([]ec2type.Reservation, error) {
+	input := &ec2.DescribeInstancesInput{
+		Filters: []ec2type.Filter{
+			{Name: aws.String("instance-state-name"), Values: actionableInstanceStates},
+		},
+	}
+	if run.PageSize > 0 {
+		input.MaxResults = aws.Int32(clampPageSize(run.PageSize, ec2MinPageSize, ec2MaxPageSize))
+	}
+
+	reservations := []ec2type.Reservation{}
+	pages := ec2.NewDescribeInstancesPaginator(client, input)
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(run.ctx())
+		if err != nil {
+			return nil, err
 		}
+		reservations = append(reservations, page.Reservations...)
+	}
+	return reservations, nil
+}
+
+func instanceStateName(instance ec2type.Instance) ec2type.InstanceStateName {
+	if instance.State == nil {
+		return ""
+	}
+	return instance.State.Name
+}
+
+// parseInstanceTags reads the scheduling tags of an instance. Instances in an Auto Scaling group are skipped, as the
+// group is scheduled instead.
+func parseInstanceTags(run *SchedulingRun, resource ec2Resource, skippedInstances []string, skippedAutoScaledInstances []string) (string, time.Time, bool, bool, []string, []string) {
+	isPartOfAutoScalingGroup := false
+	if _, ok := resource.tags()["aws:autoscaling:groupName"]; ok {
+		run.Printf("INFO: Skip instance because aws:autoscaling:groupName tag because it is part of an Auto Scaling group\n")
+		skippedAutoScaledInstances = append(skippedAutoScaledInstances, resource.id())
+		isPartOfAutoScalingGroup = true
 	}
+	instanceSchedulingTag, overrideUntil, stoppedByScheduler, isSkipSchedulingTag, skippedInstances := parseResourceTags(run, resource, skippedInstances)
 
-	isSkippable := bool(isPartOfAutoScalingGroup || isSkipSchedulingTag)
-	return instanceSchedulingTag, isSkippable, skippedInstances, skippedAutoScaledInstances
+	isSkippable := isPartOfAutoScalingGroup || isSkipSchedulingTag
+	return instanceSchedulingTag, overrideUntil, stoppedByScheduler, isSkippable, skippedInstances, skippedAutoScaledInstances
 }
 
-func startEc2Instances(client IEC2InstancesAPI) *InstanceCount {
-	result, err := client.DescribeInstances(context.TODO(), &ec2.DescribeInstancesInput{})
+func startEc2Instances(client IEC2InstancesAPI, run *SchedulingRun) (*InstanceCount, error) {
+	reservations, err := listEc2Instances(client, run)
 	if err != nil {
-		log.Println("ERROR: Could not retrieve information about Amazon EC2 instances in member account:", err)
-		return &InstanceCount{actedUpon: 0, skipped: 0, skippedAutoScaled: 0}
+		return nil, fmt.Errorf("could not retrieve information about Amazon EC2 instances: %w", err)
 	}
 
-	instancesActedUpon := []string{}
+	instancesToStart := []string{}
 	skippedInstances := []string{}
 	skippedAutoScaledInstances := []string{}
-	for _, r := range result.Reservations {
-		log.Printf("INFO: Reservation ID: [ %v ]\n", *r.ReservationId)
+	stoppedNotBySchedulerInstances := []string{}
+	alreadyRunningInstances := []string{}
+	notActionableInstances := []string{}
+	for _, r := range reservations {
+		run.Printf("INFO: Reservation ID: [ %v ]\n", *r.ReservationId)
 		for _, i := range r.Instances {
-			log.Printf("INFO: Instance ID: [ %v ]\n", *i.InstanceId)
-			instanceSchedulingTag, skipInstance, skippedInstancesModified, skippedAutoScaledInstancesModified := parseInstanceTags(i, skippedInstances, skippedAutoScaledInstances)
+			run.Printf("INFO: Instance ID: [ %v ]\n", *i.InstanceId)
+			resource := newEc2InstanceResource(client, i)
+			instanceSchedulingTag, overrideUntil, stoppedByScheduler, skipInstance, skippedInstancesModified, skippedAutoScaledInstancesModified := parseInstanceTags(run, resource, skippedInstances, skippedAutoScaledInstances)
 			skippedInstances = skippedInstancesModified
 			skippedAutoScaledInstances = skippedAutoScaledInstancesModified
 
@@ -88,64 +144,138 @@ func startEc2Instances(client IEC2InstancesAPI) *InstanceCount {
 				continue
 			}
 
+			removeExpiredOverride(run, resource, overrideUntil)
+
+			if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+				run.Printf("INFO: Skipped instance because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+				skippedInstances = append(skippedInstances, *i.InstanceId)
+				continue
+			}
+
 			if instanceSchedulingTag == "skip-auto-stop" {
-				log.Printf("INFO: Skipped instance because instance-scheduling tag having value 'skip-auto-stop'\n")
+				run.Printf("INFO: Skipped instance because instance-scheduling tag having value 'skip-auto-stop'\n")
 				skippedInstances = append(skippedInstances, *i.InstanceId)
 				continue
 			}
 
-			log.Printf("INFO: Stopped instance because instance-scheduling tag is absent\n")
-			instancesActedUpon = append(instancesActedUpon, *i.InstanceId)
-			startInstance(client, *i.InstanceId)
+			instanceState := instanceStateName(i)
+			if instanceState == ec2type.InstanceStateNameRunning || instanceState == ec2type.InstanceStateNamePending {
+				run.Printf("INFO: Skipped instance because it is already %v\n", instanceState)
+				alreadyRunningInstances = append(alreadyRunningInstances, *i.InstanceId)
+				continue
+			}
+			if instanceState != ec2type.InstanceStateNameStopped {
+				run.Printf("INFO: Skipped instance because it cannot be started while %v\n", instanceState)
+				notActionableInstances = append(notActionableInstances, *i.InstanceId)
+				continue
+			}
+
+			if !stoppedByScheduler {
+				run.Printf("INFO: Skipped instance because it was not stopped by the scheduler\n")
+				stoppedNotBySchedulerInstances = append(stoppedNotBySchedulerInstances, *i.InstanceId)
+				continue
+			}
+
+			run.Printf("INFO: Starting instance because it was stopped by the scheduler\n")
+			instancesToStart = append(instancesToStart, *i.InstanceId)
 		}
 	}
 
-	log.Printf("INFO: Started %v instances: %v\n", len(instancesActedUpon), instancesActedUpon)
-	log.Printf("INFO: Skipped %v instances due to instance-scheduling tag: %v\n", len(skippedInstances), skippedInstances)
-	log.Printf("INFO: Skipped %v instances due to aws:autoscaling:groupName tag: %v\n", len(skippedAutoScaledInstances), skippedAutoScaledInstances)
+	instancesActedUpon, instancesFailed := startInstances(client, run, instancesToStart)
+	notActionableInstances = append(notActionableInstances, instancesNotReported(instancesToStart, slices.Concat(instancesActedUpon, instancesFailed))...)
+	untagStoppedByScheduler(client, run, instancesActedUpon)
 
-	return &InstanceCount{actedUpon: len(instancesActedUpon), skipped: len(skippedInstances), skippedAutoScaled: len(skippedAutoScaledInstances)}
+	run.Printf("INFO: Started %v instances: %v\n", len(instancesActedUpon), instancesActedUpon)
+	run.Printf("INFO: Could not start %v instances: %v\n", len(instancesFailed), instancesFailed)
+	run.Printf("INFO: Skipped %v instances due to instance-scheduling tag or not being stopped by the scheduler: %v\n", len(skippedInstances), skippedInstances)
+	run.Printf("INFO: Skipped %v instances due to aws:autoscaling:groupName tag: %v\n", len(skippedAutoScaledInstances), skippedAutoScaledInstances)
+	run.Printf("INFO: Found %v stopped instances which were not stopped by the scheduler: %v\n", len(stoppedNotBySchedulerInstances), stoppedNotBySchedulerInstances)
+	run.Printf("INFO: Skipped %v instances which were already running: %v\n", len(alreadyRunningInstances), alreadyRunningInstances)
+	run.Printf("INFO: Skipped %v instances which could not be started in their current state or were not reported as starting by EC2: %v\n", len(notActionableInstances), notActionableInstances)
+
+	return &InstanceCount{actedUpon: len(instancesActedUpon), skipped: len(skippedInstances), skippedAutoScaled: len(skippedAutoScaledInstances), stoppedNotByScheduler: len(stoppedNotBySchedulerInstances), alreadyInDesiredState: len(alreadyRunningInstances), notActionable: len(notActionableInstances), failed: len(instancesFailed)}, nil
 }
 
-func startInstance(client IEC2InstancesAPI, instanceId string) {
-	input := &ec2.StartInstancesInput{
-		InstanceIds: []string{
-			instanceId,
-		},
-		DryRun: aws.Bool(true),
+// startInstances starts instances in batches, each checked first by a dry run that the member account role may start
+// them. A batch that fails is retried an instance at a time, so that one instance cannot keep the others from
+// starting. It returns the IDs of the instances that EC2 reports as starting and of those that could not be started.
+func startInstances(client IEC2InstancesAPI, run *SchedulingRun, instanceIds []string) ([]string, []string) {
+	started := []string{}
+	failed := []string{}
+	for start := 0; start < len(instanceIds); start += ec2InstanceBatchSize {
+		end := min(start+ec2InstanceBatchSize, len(instanceIds))
+		batchStarted, batchFailed := startInstanceBatch(client, run, instanceIds[start:end])
+		started = append(started, batchStarted...)
+		failed = append(failed, batchFailed...)
+	}
+	if len(started) > 0 {
+		run.Printf("Successfully started instances with Ids %v\n", started)
 	}
+	return started, failed
+}
 
-	_, err := client.StartInstances(context.TODO(), input)
+// startInstanceBatch starts one batch of instances, retrying them an instance at a time when the batch fails
+func startInstanceBatch(client IEC2InstancesAPI, run *SchedulingRun, instanceIds []string) ([]string, []string) {
+	started := []string{}
+	failed := []string{}
+	_, err := client.StartInstances(run.ctx(), &ec2.StartInstancesInput{
+		InstanceIds: instanceIds,
+		DryRun:      aws.Bool(true),
+	})
+	err = dryRunError(err)
+	var result *ec2.StartInstancesOutput
+	if err == nil {
+		result, err = client.StartInstances(run.ctx(), &ec2.StartInstancesInput{
+			InstanceIds: instanceIds,
+		})
+	}
+	if err != nil && len(instanceIds) > 1 {
+		run.Printf("WARN: Could not start instances %v, retrying one at a time: %v\n", instanceIds, err)
+		for _, instanceId := range instanceIds {
+			instanceStarted, instanceFailed := startInstanceBatch(client, run, []string{instanceId})
+			started = append(started, instanceStarted...)
+			failed = append(failed, instanceFailed...)
+		}
+		return started, failed
+	}
+	if err != nil {
+		run.Printf("ERROR: Could not start instance %v: %v\n", instanceIds[0], err)
+		return started, instanceIds
+	}
 
-	var apiErr smithy.APIError
-	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "DryRunOperation" {
-		log.Println("User has permission to start an instance.")
-		input.DryRun = aws.Bool(false)
-		_, err = client.StartInstances(context.TODO(), input)
+	for _, change := range result.StartingInstances {
+		started = append(started, *change.InstanceId)
 	}
+	return started, failed
+}
 
-	if err == nil {
-		log.Printf("Successfully started instance with Id %v\n", instanceId)
-	} else {
-		log.Printf("ERROR: Could not start instance: %v\n", err)
+// dryRunError returns the error of a dry run, which is nil when EC2 reports that the call would have succeeded
+func dryRunError(err error) error {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "DryRunOperation" {
+		return nil
 	}
+	return err
 }
 
-func stopEc2Instances(client IEC2InstancesAPI) *InstanceCount {
-	result, err := client.DescribeInstances(context.TODO(), &ec2.DescribeInstancesInput{})
+func stopEc2Instances(client IEC2InstancesAPI, run *SchedulingRun) (*InstanceCount, error) {
+	reservations, err := listEc2Instances(client, run)
 	if err != nil {
-		log.Println("ERROR: Could not retrieve information about Amazon EC2 instances in member account:", err)
-		return &InstanceCount{actedUpon: 0, skipped: 0, skippedAutoScaled: 0}
+		return nil, fmt.Errorf("could not retrieve information about Amazon EC2 instances: %w", err)
 	}
 
-	instancesActedUpon := []string{}
+	instancesToStop := []string{}
 	skippedInstances := []string{}
 	skippedAutoScaledInstances := []string{}
-	for _, r := range result.Reservations {
-		log.Printf("INFO: Reservation ID: [ %v ]\n", *r.ReservationId)
+	instancesToHibernate := []string{}
+	alreadyStoppedInstances := []string{}
+	notActionableInstances := []string{}
+	for _, r := range reservations {
+		run.Printf("INFO: Reservation ID: [ %v ]\n", *r.ReservationId)
 		for _, i := range r.Instances {
-			log.Printf("INFO: Instance ID: [ %v ]\n", *i.InstanceId)
-			instanceSchedulingTag, skipInstance, skippedInstancesModified, skippedAutoScaledInstancesModified := parseInstanceTags(i, skippedInstances, skippedAutoScaledInstances)
+			run.Printf("INFO: Instance ID: [ %v ]\n", *i.InstanceId)
+			resource := newEc2InstanceResource(client, i)
+			instanceSchedulingTag, overrideUntil, _, skipInstance, skippedInstancesModified, skippedAutoScaledInstancesModified := parseInstanceTags(run, resource, skippedInstances, skippedAutoScaledInstances)
 			skippedInstances = skippedInstancesModified
 			skippedAutoScaledInstances = skippedAutoScaledInstancesModified
 
@@ -153,64 +283,174 @@ func stopEc2Instances(client IEC2InstancesAPI) *InstanceCount {
 				continue
 			}
 
+			removeExpiredOverride(run, resource, overrideUntil)
+
+			if isOverrideActive(overrideUntil, run.Now) {
+				run.Printf("INFO: Skipped instance because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+				skippedInstances = append(skippedInstances, *i.InstanceId)
+				continue
+			}
+
+			if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+				run.Printf("INFO: Skipped instance because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+				skippedInstances = append(skippedInstances, *i.InstanceId)
+				continue
+			}
+
 			if instanceSchedulingTag == "skip-auto-stop" {
-				log.Printf("INFO: Skipped instance because instance-scheduling tag having value 'skip-auto-stop'\n")
+				run.Printf("INFO: Skipped instance because instance-scheduling tag having value 'skip-auto-stop'\n")
 				skippedInstances = append(skippedInstances, *i.InstanceId)
 				continue
 			}
 
-			log.Printf("INFO: Stopped instance because instance-scheduling tag is absent\n")
-			instancesActedUpon = append(instancesActedUpon, *i.InstanceId)
-			stopInstance(client, *i.InstanceId)
+			instanceState := instanceStateName(i)
+			if instanceState == ec2type.InstanceStateNameStopped || instanceState == ec2type.InstanceStateNameStopping {
+				run.Printf("INFO: Skipped instance because it is already %v\n", instanceState)
+				alreadyStoppedInstances = append(alreadyStoppedInstances, *i.InstanceId)
+				continue
+			}
+			if instanceState != ec2type.InstanceStateNameRunning {
+				run.Printf("INFO: Skipped instance because it cannot be stopped while %v\n", instanceState)
+				notActionableInstances = append(notActionableInstances, *i.InstanceId)
+				continue
+			}
+
+			run.Printf("INFO: Stopping instance because instance-scheduling tag is absent\n")
+			instancesToStop = append(instancesToStop, *i.InstanceId)
+			if isHibernationRequested(i) {
+				instancesToHibernate = append(instancesToHibernate, *i.InstanceId)
+			}
 		}
 	}
 
-	log.Printf("INFO: Stopped %v instances: %v\n", len(instancesActedUpon), instancesActedUpon)
-	log.Printf("INFO: Skipped %v instances due to instance-scheduling tag: %v\n", len(skippedInstances), skippedInstances)
-	log.Printf("INFO: Skipped %v instances due to aws:autoscaling:groupName tag: %v\n", len(skippedAutoScaledInstances), skippedAutoScaledInstances)
+	instancesActedUpon, instancesHibernated, instancesFailed := stopInstances(client, run, instancesToStop, instancesToHibernate)
+	notActionableInstances = append(notActionableInstances, instancesNotReported(instancesToStop, slices.Concat(instancesActedUpon, instancesFailed))...)
+	tagStoppedByScheduler(client, run, instancesActedUpon)
+
+	run.Printf("INFO: Stopped %v instances: %v\n", len(instancesActedUpon), instancesActedUpon)
+	run.Printf("INFO: Could not stop %v instances: %v\n", len(instancesFailed), instancesFailed)
+	run.Printf("INFO: Hibernated %v of the stopped instances: %v\n", len(instancesHibernated), instancesHibernated)
+	run.Printf("INFO: Skipped %v instances due to instance-scheduling tag: %v\n", len(skippedInstances), skippedInstances)
+	run.Printf("INFO: Skipped %v instances due to aws:autoscaling:groupName tag: %v\n", len(skippedAutoScaledInstances), skippedAutoScaledInstances)
+	run.Printf("INFO: Skipped %v instances which were already stopped: %v\n", len(alreadyStoppedInstances), alreadyStoppedInstances)
+	run.Printf("INFO: Skipped %v instances which could not be stopped in their current state or were not reported as stopping by EC2: %v\n", len(notActionableInstances), notActionableInstances)
 
-	return &InstanceCount{actedUpon: len(instancesActedUpon), skipped: len(skippedInstances), skippedAutoScaled: len(skippedAutoScaledInstances)}
+	return &InstanceCount{actedUpon: len(instancesActedUpon), skipped: len(skippedInstances), skippedAutoScaled: len(skippedAutoScaledInstances), hibernated: len(instancesHibernated), alreadyInDesiredState: len(alreadyStoppedInstances), notActionable: len(notActionableInstances), failed: len(instancesFailed)}, nil
 }
 
-func stopInstance(client IEC2InstancesAPI, instanceId string) {
-	input := &ec2.StopInstancesInput{
-		InstanceIds: []string{
-			instanceId,
-		},
-		DryRun: aws.Bool(true),
+// isHibernationRequested reports whether an instance should be hibernated rather than stopped, according to its
+// instance-scheduling-stop-mode tag or, without one, whether it was launched with hibernation configured
+func isHibernationRequested(instance ec2type.Instance) bool {
+	for _, tag := range instance.Tags {
+		if *tag.Key == stopModeTagKey {
+			return *tag.Value == "hibernate"
+		}
 	}
+	return instance.HibernationOptions != nil && aws.ToBool(instance.HibernationOptions.Configured)
+}
 
-	_, err := client.StopInstances(context.TODO(), input)
+// stopInstances stops instances in batches, each checked first by a dry run that the member account role may stop
+// them. The instances in toHibernate are hibernated where EC2 allows it. A batch that fails is retried an instance at a
+// time, so that one instance cannot keep the others from stopping. It returns the IDs of the instances that EC2 reports
+// as stopping, of those the ones that were hibernated, and the IDs of the instances that could not be stopped.
+func stopInstances(client IEC2InstancesAPI, run *SchedulingRun, instanceIds []string, toHibernate []string) ([]string, []string, []string) {
+	stopped := []string{}
+	hibernated := []string{}
+	failed := []string{}
+	toStop := []string{}
+	for _, instanceId := range instanceIds {
+		if !slices.Contains(toHibernate, instanceId) {
+			toStop = append(toStop, instanceId)
+		}
+	}
+	for start := 0; start < len(toHibernate); start += ec2InstanceBatchSize {
+		end := min(start+ec2InstanceBatchSize, len(toHibernate))
+		batchStopped, batchHibernated, batchFailed := stopInstanceBatch(client, run, toHibernate[start:end], true)
+		stopped = append(stopped, batchStopped...)
+		hibernated = append(hibernated, batchHibernated...)
+		failed = append(failed, batchFailed...)
+	}
+	for start := 0; start < len(toStop); start += ec2InstanceBatchSize {
+		end := min(start+ec2InstanceBatchSize, len(toStop))
+		batchStopped, _, batchFailed := stopInstanceBatch(client, run, toStop[start:end], false)
+		stopped = append(stopped, batchStopped...)
+		failed = append(failed, batchFailed...)
+	}
+	if len(stopped) > 0 {
+		run.Printf("Successfully stopped instances with Ids %v\n", stopped)
+	}
+	return stopped, hibernated, failed
+}
 
-	var apiErr smithy.APIError
-	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "DryRunOperation" {
-		log.Println("User has permission to stop instances.")
-		input.DryRun = aws.Bool(false)
-		_, err = client.StopInstances(context.TODO(), input)
+// stopInstanceBatch stops one batch of instances, hibernating them when asked to. A batch that fails is retried an
+// instance at a time, and an instance that cannot be hibernated is stopped normally instead.
+func stopInstanceBatch(client IEC2InstancesAPI, run *SchedulingRun, instanceIds []string, hibernate bool) ([]string, []string, []string) {
+	stopped := []string{}
+	hibernated := []string{}
+	failed := []string{}
+	stopInput := func(dryRun bool) *ec2.StopInstancesInput {
+		input := &ec2.StopInstancesInput{
+			InstanceIds: instanceIds,
+		}
+		if dryRun {
+			input.DryRun = aws.Bool(true)
+		}
+		if hibernate {
+			input.Hibernate = aws.Bool(true)
+		}
+		return input
 	}
 
+	_, err := client.StopInstances(run.ctx(), stopInput(true))
+	err = dryRunError(err)
+	var result *ec2.StopInstancesOutput
 	if err == nil {
-		log.Printf("Successfully stopped instance with Id %v\n", instanceId)
-	} else {
-		log.Printf("ERROR: Could not stop instance: %v\n", err)
+		result, err = client.StopInstances(run.ctx(), stopInput(false))
+	}
+	if err != nil && len(instanceIds) > 1 {
+		run.Printf("WARN: Could not stop instances %v, retrying one at a time: %v\n", instanceIds, err)
+		for _, instanceId := range instanceIds {
+			instanceStopped, instanceHibernated, instanceFailed := stopInstanceBatch(client, run, []string{instanceId}, hibernate)
+			stopped = append(stopped, instanceStopped...)
+			hibernated = append(hibernated, instanceHibernated...)
+			failed = append(failed, instanceFailed...)
+		}
+		return stopped, hibernated, failed
+	}
+	if err != nil && hibernate {
+		run.Printf("WARN: Could not hibernate instance %v, stopping it instead: %v\n", instanceIds[0], err)
+		return stopInstanceBatch(client, run, instanceIds, false)
+	}
+	if err != nil {
+		run.Printf("ERROR: Could not stop instance %v: %v\n", instanceIds[0], err)
+		return stopped, hibernated, instanceIds
+	}
+
+	for _, change := range result.StoppingInstances {
+		stopped = append(stopped, *change.InstanceId)
+	}
+	if hibernate {
+		hibernated = stopped
+		run.Printf("Successfully hibernated instances with Ids %v\n", hibernated)
 	}
+	return stopped, hibernated, failed
 }
 
-func testEc2Instances(client IEC2InstancesAPI) *InstanceCount {
-	result, err := client.DescribeInstances(context.TODO(), &ec2.DescribeInstancesInput{})
+func testEc2Instances(client IEC2InstancesAPI, run *SchedulingRun) (*InstanceCount, error) {
+	reservations, err := listEc2Instances(client, run)
 	if err != nil {
-		log.Println("ERROR: Could not retrieve information about Amazon EC2 instances in member account:", err)
-		return &InstanceCount{actedUpon: 0, skipped: 0, skippedAutoScaled: 0}
+		return nil, fmt.Errorf("could not retrieve information about Amazon EC2 instances: %w", err)
 	}
 
 	instancesActedUpon := []string{}
 	skippedInstances := []string{}
 	skippedAutoScaledInstances := []string{}
-	for _, r := range result.Reservations {
-		log.Printf("INFO: Reservation ID: [ %v ]\n", *r.ReservationId)
+	for _, r := range reservations {
+		run.Printf("INFO: Reservation ID: [ %v ]\n", *r.ReservationId)
 		for _, i := range r.Instances {
-			log.Printf("INFO: Instance ID: [ %v ]\n", *i.InstanceId)
-			instanceSchedulingTag, skipInstance, skippedInstancesModified, skippedAutoScaledInstancesModified := parseInstanceTags(i, skippedInstances, skippedAutoScaledInstances)
+			run.Printf("INFO: Instance ID: [ %v ]\n", *i.InstanceId)
+			resource := newEc2InstanceResource(client, i)
+			instanceSchedulingTag, overrideUntil, _, skipInstance, skippedInstancesModified, skippedAutoScaledInstancesModified := parseInstanceTags(run, resource, skippedInstances, skippedAutoScaledInstances)
 			skippedInstances = skippedInstancesModified
 			skippedAutoScaledInstances = skippedAutoScaledInstancesModified
 
@@ -218,42 +458,197 @@ func testEc2Instances(client IEC2InstancesAPI) *InstanceCount {
 				continue
 			}
 
+			removeExpiredOverride(run, resource, overrideUntil)
+
+			if isOverrideActive(overrideUntil, run.Now) {
+				run.Printf("INFO: Skipped instance because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+				skippedInstances = append(skippedInstances, *i.InstanceId)
+				continue
+			}
+
+			if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+				run.Printf("INFO: Skipped instance because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+				skippedInstances = append(skippedInstances, *i.InstanceId)
+				continue
+			}
+
 			if instanceSchedulingTag == "skip-auto-stop" || instanceSchedulingTag == "skip-auto-start" {
-				log.Printf("INFO: Skipped instance because instance-scheduling tag having value 'skip-auto-start' or \n")
+				run.Printf("INFO: Skipped instance because instance-scheduling tag having value 'skip-auto-start' or \n")
 				skippedInstances = append(skippedInstances, *i.InstanceId)
 				continue
 			}
 			instancesActedUpon = append(instancesActedUpon, *i.InstanceId)
-			log.Printf("INFO: Successfully tested instance with Id %v\n", *i.InstanceId)
+			run.Printf("INFO: Successfully tested instance with Id %v\n", *i.InstanceId)
 			continue
 		}
 	}
 
-	log.Printf("INFO: Tested %v instances: %v\n", len(instancesActedUpon), instancesActedUpon)
-	log.Printf("INFO: Skipped %v instances due to instance-scheduling tag: %v\n", len(skippedInstances), skippedInstances)
-	log.Printf("INFO: Skipped %v instances due to aws:autoscaling:groupName tag: %v\n", len(skippedAutoScaledInstances), skippedAutoScaledInstances)
+	run.Printf("INFO: Tested %v instances: %v\n", len(instancesActedUpon), instancesActedUpon)
+	run.Printf("INFO: Skipped %v instances due to instance-scheduling tag: %v\n", len(skippedInstances), skippedInstances)
+	run.Printf("INFO: Skipped %v instances due to aws:autoscaling:groupName tag: %v\n", len(skippedAutoScaledInstances), skippedAutoScaledInstances)
 
-	return &InstanceCount{actedUpon: len(instancesActedUpon), skipped: len(skippedInstances), skippedAutoScaled: len(skippedAutoScaledInstances)}
+	return &InstanceCount{actedUpon: len(instancesActedUpon), skipped: len(skippedInstances), skippedAutoScaled: len(skippedAutoScaledInstances)}, nil
 }
 
-func getEc2ClientForMemberAccount(cfg aws.Config, accountName string, accountId string) IEC2InstancesAPI {
-	roleARN := fmt.Sprintf("arn:aws:iam::%v:role/InstanceSchedulerAccess", accountId)
-	stsClient := sts.NewFromConfig(cfg)
-	provider := stscreds.NewAssumeRoleProvider(stsClient, roleARN)
-	cfg.Credentials = aws.NewCredentialsCache(provider)
-
-	// Create EC2 client
-	ec2Client := ec2.NewFromConfig(cfg)
-	ec2Input := &ec2.DescribeInstancesInput{}
-	_, err := ec2Client.DescribeInstances(context.TODO(), ec2Input)
+func reconcileEc2Instances(client IEC2InstancesAPI, run *SchedulingRun) (*InstanceCount, error) {
+	reservations, err := listEc2Instances(client, run)
 	if err != nil {
-		if strings.Contains(err.Error(), "is not authorized to perform: sts:AssumeRole on resource") {
-			log.Printf("WARN: account %v is ignored because it does not have the role InstanceSchedulerAccess, therefore is not a member account\n", accountName)
-			return nil
-		} else {
-			log.Fatal(err)
+		return nil, fmt.Errorf("could not retrieve information about Amazon EC2 instances: %w", err)
+	}
+
+	instancesToStart := []string{}
+	instancesToStop := []string{}
+	skippedInstances := []string{}
+	skippedAutoScaledInstances := []string{}
+	instancesToHibernate := []string{}
+	for _, r := range reservations {
+		run.Printf("INFO: Reservation ID: [ %v ]\n", *r.ReservationId)
+		for _, i := range r.Instances {
+			run.Printf("INFO: Instance ID: [ %v ]\n", *i.InstanceId)
+			resource := newEc2InstanceResource(client, i)
+			instanceSchedulingTag, overrideUntil, _, skipInstance, skippedInstancesModified, skippedAutoScaledInstancesModified := parseInstanceTags(run, resource, skippedInstances, skippedAutoScaledInstances)
+			skippedInstances = skippedInstancesModified
+			skippedAutoScaledInstances = skippedAutoScaledInstancesModified
+
+			if skipInstance {
+				continue
+			}
+
+			removeExpiredOverride(run, resource, overrideUntil)
+
+			schedule := run.Schedules.getSchedule(run, instanceSchedulingTag)
+			if schedule == nil {
+				run.Printf("INFO: Skipped instance because its instance-scheduling tag does not reference a schedule\n")
+				skippedInstances = append(skippedInstances, *i.InstanceId)
+				continue
+			}
+
+			desiredState := run.desiredState(schedule)
+			instanceState := ""
+			if i.State != nil {
+				instanceState = string(i.State.Name)
+			}
+
+			if desiredState == scheduleStateRunning && instanceState == string(ec2type.InstanceStateNameStopped) {
+				run.Printf("INFO: Starting instance because schedule '%v' expects it to be running\n", schedule.Name)
+				instancesToStart = append(instancesToStart, *i.InstanceId)
+				continue
+			}
+
+			if desiredState == scheduleStateStopped && instanceState == string(ec2type.InstanceStateNameRunning) && isOverrideActive(overrideUntil, run.Now) {
+				run.Printf("INFO: Skipped stopping instance because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+				skippedInstances = append(skippedInstances, *i.InstanceId)
+				continue
+			}
+
+			if desiredState == scheduleStateStopped && instanceState == string(ec2type.InstanceStateNameRunning) {
+				run.Printf("INFO: Stopping instance because schedule '%v' expects it to be stopped\n", schedule.Name)
+				instancesToStop = append(instancesToStop, *i.InstanceId)
+				if isHibernationRequested(i) {
+					instancesToHibernate = append(instancesToHibernate, *i.InstanceId)
+				}
+				continue
+			}
+
+			run.Printf("INFO: Skipped instance in state '%v' because schedule '%v' expects it to be '%v'\n", instanceState, schedule.Name, desiredState)
+			skippedInstances = append(skippedInstances, *i.InstanceId)
 		}
 	}
 
-	return ec2Client
+	instancesStarted, instancesNotStarted := startInstances(client, run, instancesToStart)
+	instancesStopped, instancesHibernated, instancesNotStopped := stopInstances(client, run, instancesToStop, instancesToHibernate)
+	instancesFailed := append(instancesNotStarted, instancesNotStopped...)
+	notActionableInstances := append(instancesNotReported(instancesToStart, slices.Concat(instancesStarted, instancesNotStarted)), instancesNotReported(instancesToStop, slices.Concat(instancesStopped, instancesNotStopped))...)
+
+	run.Printf("INFO: Started %v instances: %v\n", len(instancesStarted), instancesStarted)
+	run.Printf("INFO: Stopped %v instances: %v\n", len(instancesStopped), instancesStopped)
+	run.Printf("INFO: Hibernated %v of the stopped instances: %v\n", len(instancesHibernated), instancesHibernated)
+	run.Printf("INFO: Skipped %v instances due to instance-scheduling tag or schedule: %v\n", len(skippedInstances), skippedInstances)
+	run.Printf("INFO: Skipped %v instances due to aws:autoscaling:groupName tag: %v\n", len(skippedAutoScaledInstances), skippedAutoScaledInstances)
+	run.Printf("INFO: Found %v instances which were not reported as starting or stopping by EC2: %v\n", len(notActionableInstances), notActionableInstances)
+	run.Printf("INFO: Could not start or stop %v instances: %v\n", len(instancesFailed), instancesFailed)
+
+	return &InstanceCount{actedUpon: len(instancesStarted) + len(instancesStopped), skipped: len(skippedInstances), skippedAutoScaled: len(skippedAutoScaledInstances), hibernated: len(instancesHibernated), notActionable: len(notActionableInstances), failed: len(instancesFailed)}, nil
+}
+
+// instancesNotReported returns the requested instances that EC2 did not report as changing state
+func instancesNotReported(requested []string, reported []string) []string {
+	notReported := []string{}
+	for _, instanceId := range requested {
+		if !slices.Contains(reported, instanceId) {
+			notReported = append(notReported, instanceId)
+		}
+	}
+	return notReported
+}
+
+// tagStoppedByScheduler records on instances that the scheduler stopped them, so that the start action restarts them
+func tagStoppedByScheduler(client IEC2InstancesAPI, run *SchedulingRun, instanceIds []string) {
+	for start := 0; start < len(instanceIds); start += ec2InstanceBatchSize {
+		end := min(start+ec2InstanceBatchSize, len(instanceIds))
+		tagResourceStoppedByScheduler(run, ec2Resource{client: client, instanceIds: instanceIds[start:end]})
+	}
+}
+
+func untagStoppedByScheduler(client IEC2InstancesAPI, run *SchedulingRun, instanceIds []string) {
+	for start := 0; start < len(instanceIds); start += ec2InstanceBatchSize {
+		end := min(start+ec2InstanceBatchSize, len(instanceIds))
+		untagResourceStoppedByScheduler(run, ec2Resource{client: client, instanceIds: instanceIds[start:end]})
+	}
+}
+
+// ec2Resource adapts one instance, or a batch of instances tagged in a single call, to the tag helpers shared by
+// every service
+type ec2Resource struct {
+	client      IEC2InstancesAPI
+	instanceIds []string
+	tagList     []ec2type.Tag
+}
+
+func newEc2InstanceResource(client IEC2InstancesAPI, instance ec2type.Instance) ec2Resource {
+	return ec2Resource{client: client, instanceIds: []string{aws.ToString(instance.InstanceId)}, tagList: instance.Tags}
+}
+
+func (resource ec2Resource) kind() string {
+	if len(resource.instanceIds) == 1 {
+		return "instance"
+	}
+	return "instances"
+}
+
+func (resource ec2Resource) id() string {
+	if len(resource.instanceIds) == 1 {
+		return resource.instanceIds[0]
+	}
+	return fmt.Sprint(resource.instanceIds)
+}
+
+func (resource ec2Resource) tags() map[string]string {
+	tags := map[string]string{}
+	for _, tag := range resource.tagList {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags
+}
+
+func (resource ec2Resource) addTags(run *SchedulingRun, tags []resourceTag) error {
+	ec2Tags := []ec2type.Tag{}
+	for _, tag := range tags {
+		ec2Tags = append(ec2Tags, ec2type.Tag{Key: aws.String(tag.key), Value: aws.String(tag.value)})
+	}
+	_, err := resource.client.CreateTags(run.ctx(), &ec2.CreateTagsInput{Resources: resource.instanceIds, Tags: ec2Tags})
+	return err
+}
+
+func (resource ec2Resource) removeTags(run *SchedulingRun, keys []string) error {
+	ec2Tags := []ec2type.Tag{}
+	for _, key := range keys {
+		ec2Tags = append(ec2Tags, ec2type.Tag{Key: aws.String(key)})
+	}
+	_, err := resource.client.DeleteTags(run.ctx(), &ec2.DeleteTagsInput{Resources: resource.instanceIds, Tags: ec2Tags})
+	return err
+}
+
+func getEc2ClientForMemberAccount(session *MemberAccountSession) IEC2InstancesAPI {
+	return ec2.NewFromConfig(session.Config)
 }
@@ -2,31 +2,112 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2type "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
 )
 
 type mockIEC2InstancesAPI struct {
 	DescribeInstancesOutput *ec2.DescribeInstancesOutput
-	StartInstancesOutput    *ec2.StartInstancesOutput
-	StopInstancesOutput     *ec2.StopInstancesOutput
+	DescribeInstancesPages  []*ec2.DescribeInstancesOutput
+	DescribeInstancesInputs []*ec2.DescribeInstancesInput
+	DescribeInstancesError  error
+	StartInstancesInputs    []*ec2.StartInstancesInput
+	StopInstancesInputs     []*ec2.StopInstancesInput
+	HibernateError          error
+	DryRunError             error
+	UnchangedInstanceIds    []string
+	FailingInstanceIds      []string
+	CreateTagsInputs        []*ec2.CreateTagsInput
+	DeleteTagsInputs        []*ec2.DeleteTagsInput
 }
 
 func (m *mockIEC2InstancesAPI) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
-	return m.DescribeInstancesOutput, nil
+	m.DescribeInstancesInputs = append(m.DescribeInstancesInputs, params)
+	if m.DescribeInstancesError != nil {
+		return nil, m.DescribeInstancesError
+	}
+	if len(m.DescribeInstancesPages) == 0 {
+		return m.DescribeInstancesOutput, nil
+	}
+
+	// pages are chained by their index, as NextToken
+	page := 0
+	if params.NextToken != nil {
+		page, _ = strconv.Atoi(*params.NextToken)
+	}
+	output := *m.DescribeInstancesPages[page]
+	if page+1 < len(m.DescribeInstancesPages) {
+		output.NextToken = aws.String(strconv.Itoa(page + 1))
+	}
+	return &output, nil
 }
 
 func (m *mockIEC2InstancesAPI) StopInstances(ctx context.Context, params *ec2.StopInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error) {
-	return m.StopInstancesOutput, nil
+	m.StopInstancesInputs = append(m.StopInstancesInputs, params)
+	if aws.ToBool(params.DryRun) {
+		return nil, m.DryRunError
+	}
+	if m.HibernateError != nil && aws.ToBool(params.Hibernate) {
+		return nil, m.HibernateError
+	}
+	if slices.ContainsFunc(params.InstanceIds, m.isFailing) {
+		return nil, &smithy.GenericAPIError{Code: "IncorrectInstanceState"}
+	}
+	output := &ec2.StopInstancesOutput{}
+	for _, instanceId := range slices.DeleteFunc(slices.Clone(params.InstanceIds), m.isUnchanged) {
+		output.StoppingInstances = append(output.StoppingInstances, ec2type.InstanceStateChange{InstanceId: aws.String(instanceId)})
+	}
+	return output, nil
 }
 
 func (m *mockIEC2InstancesAPI) StartInstances(ctx context.Context, params *ec2.StartInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error) {
-	return m.StartInstancesOutput, nil
+	m.StartInstancesInputs = append(m.StartInstancesInputs, params)
+	if aws.ToBool(params.DryRun) {
+		return nil, m.DryRunError
+	}
+	if slices.ContainsFunc(params.InstanceIds, m.isFailing) {
+		return nil, &smithy.GenericAPIError{Code: "IncorrectInstanceState"}
+	}
+	output := &ec2.StartInstancesOutput{}
+	for _, instanceId := range slices.DeleteFunc(slices.Clone(params.InstanceIds), m.isUnchanged) {
+		output.StartingInstances = append(output.StartingInstances, ec2type.InstanceStateChange{InstanceId: aws.String(instanceId)})
+	}
+	return output, nil
 }
 
+// isUnchanged reports whether an instance is left out of the StoppingInstances or StartingInstances in the output
+func (m *mockIEC2InstancesAPI) isUnchanged(instanceId string) bool {
+	return slices.Contains(m.UnchangedInstanceIds, instanceId)
+}
+
+// isFailing reports whether a StopInstances or StartInstances call including the instance fails
+func (m *mockIEC2InstancesAPI) isFailing(instanceId string) bool {
+	return slices.Contains(m.FailingInstanceIds, instanceId)
+}
+
+func (m *mockIEC2InstancesAPI) CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error) {
+	m.CreateTagsInputs = append(m.CreateTagsInputs, params)
+	return &ec2.CreateTagsOutput{}, nil
+}
+
+func (m *mockIEC2InstancesAPI) DeleteTags(ctx context.Context, params *ec2.DeleteTagsInput, optFns ...func(*ec2.Options)) (*ec2.DeleteTagsOutput, error) {
+	m.DeleteTagsInputs = append(m.DeleteTagsInputs, params)
+	return &ec2.DeleteTagsOutput{}, nil
+}
+
+// Midday on a Wednesday, during office hours
+var testSchedulingTime = time.Date(2026, time.October, 14, 12, 0, 0, 0, time.UTC)
+
 func TestStopStartTestInstancesInMemberAccount(t *testing.T) {
 	tests := []struct {
 		testTitle     string
@@ -141,7 +222,7 @@ func TestStopStartTestInstancesInMemberAccount(t *testing.T) {
 				},
 			},
 			action:        "test",
-			expectedCount: InstanceCount{4, 3, 2},
+			expectedCount: InstanceCount{actedUpon: 4, skipped: 3, skippedAutoScaled: 2},
 		},
 		{
 			testTitle: "testing Stop action",
@@ -154,6 +235,7 @@ func TestStopStartTestInstancesInMemberAccount(t *testing.T) {
 								// aws:autoscaling:groupName is set, therefore skip scheduling, skipped auto scaled: 1
 								{
 									InstanceId: aws.String("i-6567788010"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning},
 									Tags: []ec2type.Tag{
 										{
 											Key:   aws.String("aws:autoscaling:groupName"),
@@ -164,6 +246,7 @@ func TestStopStartTestInstancesInMemberAccount(t *testing.T) {
 								// instance-scheduling = default, therefore schedule an instance, acted upon: 1
 								{
 									InstanceId: aws.String("i-6562278100"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning},
 									Tags: []ec2type.Tag{
 										{
 											Key:   aws.String("instance-scheduling"),
@@ -179,6 +262,7 @@ func TestStopStartTestInstancesInMemberAccount(t *testing.T) {
 								// both instance-scheduling and aws:autoscaling:groupName tags are set, skip scheduling due to autoscaling, skipped auto scaled: 1
 								{
 									InstanceId: aws.String("i-6562788010"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning},
 									Tags: []ec2type.Tag{
 										{
 											Key:   aws.String("aws:autoscaling:groupName"),
@@ -193,10 +277,12 @@ func TestStopStartTestInstancesInMemberAccount(t *testing.T) {
 								// no instance-scheduling and no aws:autoscaling:groupName tags, therefore schedule an instance, acted upon: 1
 								{
 									InstanceId: aws.String("i-6562279100"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning},
 								},
 								// instance-scheduling = skip-scheduling, therefore skip scheduling, skipped: 1
 								{
 									InstanceId: aws.String("i-2162279001"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning},
 									Tags: []ec2type.Tag{
 										{
 											Key:   aws.String("instance-scheduling"),
@@ -207,6 +293,7 @@ func TestStopStartTestInstancesInMemberAccount(t *testing.T) {
 								// instance-scheduling is set to an empty string, therefore ignore the tag and auto schedule, acted upon: 1
 								{
 									InstanceId: aws.String("i-7862279100"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning},
 									Tags: []ec2type.Tag{
 										{
 											Key:   aws.String("instance-scheduling"),
@@ -217,6 +304,7 @@ func TestStopStartTestInstancesInMemberAccount(t *testing.T) {
 								// instance-scheduling = "invalid-value", therefore ignore the tag and auto schedule, acted upon: 1
 								{
 									InstanceId: aws.String("i-7863371100"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning},
 									Tags: []ec2type.Tag{
 										{
 											Key:   aws.String("instance-scheduling"),
@@ -227,6 +315,7 @@ func TestStopStartTestInstancesInMemberAccount(t *testing.T) {
 								// instance-scheduling = skip-auto-stop, therefore skip auto stop, skipped: 1
 								{
 									InstanceId: aws.String("i-1265579001"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning},
 									Tags: []ec2type.Tag{
 										{
 											Key:   aws.String("instance-scheduling"),
@@ -237,6 +326,7 @@ func TestStopStartTestInstancesInMemberAccount(t *testing.T) {
 								// instance-scheduling = skip-auto-start, therefore skip auto start, but not stop, acted upon: 1
 								{
 									InstanceId: aws.String("i-9262279100"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning},
 									Tags: []ec2type.Tag{
 										{
 											Key:   aws.String("instance-scheduling"),
@@ -250,7 +340,7 @@ func TestStopStartTestInstancesInMemberAccount(t *testing.T) {
 				},
 			},
 			action:        "stop",
-			expectedCount: InstanceCount{5, 2, 2},
+			expectedCount: InstanceCount{actedUpon: 5, skipped: 2, skippedAutoScaled: 2},
 		},
 		{
 			testTitle: "testing Start action",
@@ -263,7 +353,12 @@ func TestStopStartTestInstancesInMemberAccount(t *testing.T) {
 								// aws:autoscaling:groupName is set, therefore skip scheduling, skipped auto scaled: 1
 								{
 									InstanceId: aws.String("i-6567788001"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameStopped},
 									Tags: []ec2type.Tag{
+										{
+											Key:   aws.String("instance-scheduler:stopped-by"),
+											Value: aws.String("scheduler"),
+										},
 										{
 											Key:   aws.String("aws:autoscaling:groupName"),
 											Value: aws.String("bastion_linux_daily"),
@@ -273,7 +368,12 @@ func TestStopStartTestInstancesInMemberAccount(t *testing.T) {
 								// instance-scheduling = default, therefore schedule an instance, acted upon: 1
 								{
 									InstanceId: aws.String("i-6562278100"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameStopped},
 									Tags: []ec2type.Tag{
+										{
+											Key:   aws.String("instance-scheduler:stopped-by"),
+											Value: aws.String("scheduler"),
+										},
 										{
 											Key:   aws.String("instance-scheduling"),
 											Value: aws.String("default"),
@@ -288,7 +388,12 @@ func TestStopStartTestInstancesInMemberAccount(t *testing.T) {
 								// both instance-scheduling and aws:autoscaling:groupName tags are set, skip scheduling due to autoscaling, skipped auto scaled: 1
 								{
 									InstanceId: aws.String("i-6562788001"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameStopped},
 									Tags: []ec2type.Tag{
+										{
+											Key:   aws.String("instance-scheduler:stopped-by"),
+											Value: aws.String("scheduler"),
+										},
 										{
 											Key:   aws.String("aws:autoscaling:groupName"),
 											Value: aws.String("weblogic-CNOMT1"),
@@ -302,11 +407,33 @@ func TestStopStartTestInstancesInMemberAccount(t *testing.T) {
 								// no instance-scheduling and no aws:autoscaling:groupName tags, therefore schedule an instance, acted upon: 1
 								{
 									InstanceId: aws.String("i-6562279100"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameStopped},
+									Tags: []ec2type.Tag{
+										{
+											Key:   aws.String("instance-scheduler:stopped-by"),
+											Value: aws.String("scheduler"),
+										},
+									},
+								},
+								// stopped without the instance-scheduler:stopped-by tag, therefore not stopped by the scheduler, stopped not by scheduler: 1
+								{
+									InstanceId: aws.String("i-6562279200"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameStopped},
+								},
+								// running without the instance-scheduler:stopped-by tag, therefore already running, already in desired state: 1
+								{
+									InstanceId: aws.String("i-6562279300"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning},
 								},
 								// instance-scheduling = skip-scheduling, therefore skip scheduling, skipped: 1
 								{
 									InstanceId: aws.String("i-2162279010"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameStopped},
 									Tags: []ec2type.Tag{
+										{
+											Key:   aws.String("instance-scheduler:stopped-by"),
+											Value: aws.String("scheduler"),
+										},
 										{
 											Key:   aws.String("instance-scheduling"),
 											Value: aws.String("skip-scheduling"),
@@ -316,7 +443,12 @@ func TestStopStartTestInstancesInMemberAccount(t *testing.T) {
 								// instance-scheduling is set to an empty string, therefore ignore the tag and auto schedule, acted upon: 1
 								{
 									InstanceId: aws.String("i-7862279100"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameStopped},
 									Tags: []ec2type.Tag{
+										{
+											Key:   aws.String("instance-scheduler:stopped-by"),
+											Value: aws.String("scheduler"),
+										},
 										{
 											Key:   aws.String("instance-scheduling"),
 											Value: aws.String(""),
@@ -326,7 +458,12 @@ func TestStopStartTestInstancesInMemberAccount(t *testing.T) {
 								// instance-scheduling = "invalid-value", therefore ignore the tag and auto schedule, acted upon: 1
 								{
 									InstanceId: aws.String("i-7863371100"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameStopped},
 									Tags: []ec2type.Tag{
+										{
+											Key:   aws.String("instance-scheduler:stopped-by"),
+											Value: aws.String("scheduler"),
+										},
 										{
 											Key:   aws.String("instance-scheduling"),
 											Value: aws.String("invalid-value"),
@@ -336,7 +473,12 @@ func TestStopStartTestInstancesInMemberAccount(t *testing.T) {
 								// instance-scheduling = skip-auto-stop, therefore skip auto stop, but not start, acted upon: 1
 								{
 									InstanceId: aws.String("i-1265579100"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameStopped},
 									Tags: []ec2type.Tag{
+										{
+											Key:   aws.String("instance-scheduler:stopped-by"),
+											Value: aws.String("scheduler"),
+										},
 										{
 											Key:   aws.String("instance-scheduling"),
 											Value: aws.String("skip-auto-stop"),
@@ -346,7 +488,12 @@ func TestStopStartTestInstancesInMemberAccount(t *testing.T) {
 								// instance-scheduling = skip-auto-start, therefore skip auto start, skipped: 1
 								{
 									InstanceId: aws.String("i-9262279010"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameStopped},
 									Tags: []ec2type.Tag{
+										{
+											Key:   aws.String("instance-scheduler:stopped-by"),
+											Value: aws.String("scheduler"),
+										},
 										{
 											Key:   aws.String("instance-scheduling"),
 											Value: aws.String("skip-auto-start"),
@@ -359,16 +506,737 @@ func TestStopStartTestInstancesInMemberAccount(t *testing.T) {
 				},
 			},
 			action:        "start",
-			expectedCount: InstanceCount{5, 2, 2},
+			expectedCount: InstanceCount{actedUpon: 5, skipped: 2, skippedAutoScaled: 2, stoppedNotByScheduler: 1, alreadyInDesiredState: 1},
+		},
+		{
+			testTitle: "testing Reconcile action",
+			client: &mockIEC2InstancesAPI{
+				DescribeInstancesOutput: &ec2.DescribeInstancesOutput{
+					Reservations: []ec2type.Reservation{
+						{
+							ReservationId: aws.String("r-0c318eab370f3d57a"),
+							Instances: []ec2type.Instance{
+								// aws:autoscaling:groupName is set, therefore skip scheduling, skipped auto scaled: 1
+								{
+									InstanceId: aws.String("i-6567788001"),
+									Tags: []ec2type.Tag{
+										{
+											Key:   aws.String("aws:autoscaling:groupName"),
+											Value: aws.String("bastion_linux_daily"),
+										},
+									},
+								},
+								// no instance-scheduling tag, therefore left to the stop and start actions, skipped: 1
+								{
+									InstanceId: aws.String("i-6562279100"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning},
+								},
+								// instance-scheduling = skip-scheduling, therefore skip scheduling, skipped: 1
+								{
+									InstanceId: aws.String("i-2162279010"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning},
+									Tags: []ec2type.Tag{
+										{
+											Key:   aws.String("instance-scheduling"),
+											Value: aws.String("skip-scheduling"),
+										},
+									},
+								},
+								// office-hours expects the instance to be running at midday and the instance is stopped, acted upon: 1
+								{
+									InstanceId: aws.String("i-7862279100"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameStopped},
+									Tags: []ec2type.Tag{
+										{
+											Key:   aws.String("instance-scheduling"),
+											Value: aws.String("office-hours"),
+										},
+									},
+								},
+								// office-hours expects the instance to be running at midday and the instance is running, skipped: 1
+								{
+									InstanceId: aws.String("i-7863371100"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning},
+									Tags: []ec2type.Tag{
+										{
+											Key:   aws.String("instance-scheduling"),
+											Value: aws.String("office-hours"),
+										},
+									},
+								},
+								// night time schedule expects the instance to be stopped at midday and the instance is running, acted upon: 1
+								{
+									InstanceId: aws.String("i-1265579100"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning},
+									Tags: []ec2type.Tag{
+										{
+											Key:   aws.String("instance-scheduling"),
+											Value: aws.String("cron(0 19 ? * MON-FRI)/cron(0 7 ? * MON-FRI)"),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			action:        "reconcile",
+			expectedCount: InstanceCount{actedUpon: 2, skipped: 3, skippedAutoScaled: 1},
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.testTitle, func(t *testing.T) {
+			actualInstanceCount, err := stopStartTestInstancesInMemberAccount(subtest.client, &SchedulingRun{Action: subtest.action, Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+			assert.NoError(t, err)
+			if want, got := subtest.expectedCount, actualInstanceCount; want != *got {
+				t.Errorf("want %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestStopInstancesWithOverrideUntilTag(t *testing.T) {
+	tests := []struct {
+		testTitle              string
+		action                 string
+		removeExpiredOverrides bool
+		expectedCount          InstanceCount
+		expectedRemovedTags    int
+	}{
+		{
+			testTitle:     "testing Stop action keeps expired override tags",
+			action:        "stop",
+			expectedCount: InstanceCount{actedUpon: 3, skipped: 1, skippedAutoScaled: 0},
+		},
+		{
+			testTitle:              "testing Stop action removes expired override tags",
+			action:                 "stop",
+			removeExpiredOverrides: true,
+			expectedCount:          InstanceCount{actedUpon: 3, skipped: 1, skippedAutoScaled: 0},
+			expectedRemovedTags:    1,
+		},
+		{
+			testTitle:              "testing Test action never removes override tags",
+			action:                 "test",
+			removeExpiredOverrides: true,
+			expectedCount:          InstanceCount{actedUpon: 3, skipped: 1, skippedAutoScaled: 0},
+		},
+		{
+			testTitle:              "testing Start action removes expired override tags",
+			action:                 "start",
+			removeExpiredOverrides: true,
+			expectedCount:          InstanceCount{actedUpon: 0, skipped: 0, skippedAutoScaled: 0, alreadyInDesiredState: 4},
+			expectedRemovedTags:    1,
 		},
 	}
 
 	for _, subtest := range tests {
 		t.Run(subtest.testTitle, func(t *testing.T) {
-			actualInstanceCount := stopStartTestInstancesInMemberAccount(subtest.client, subtest.action)
+			client := &mockIEC2InstancesAPI{
+				DescribeInstancesOutput: &ec2.DescribeInstancesOutput{
+					Reservations: []ec2type.Reservation{
+						{
+							ReservationId: aws.String("r-0c318eab370f3d57a"),
+							Instances: []ec2type.Instance{
+								// override until later today, therefore skip stopping, skipped: 1
+								{
+									InstanceId: aws.String("i-6562279100"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning},
+									Tags: []ec2type.Tag{
+										{
+											Key:   aws.String("instance-scheduling-override-until"),
+											Value: aws.String("2026-10-14T22:00Z"),
+										},
+									},
+								},
+								// override expired yesterday, therefore stop as normal, acted upon: 1
+								{
+									InstanceId: aws.String("i-7862279100"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning},
+									Tags: []ec2type.Tag{
+										{
+											Key:   aws.String("instance-scheduling-override-until"),
+											Value: aws.String("2026-10-13T22:00Z"),
+										},
+									},
+								},
+								// invalid override, therefore ignore the tag and stop as normal, acted upon: 1
+								{
+									InstanceId: aws.String("i-7863371100"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning},
+									Tags: []ec2type.Tag{
+										{
+											Key:   aws.String("instance-scheduling-override-until"),
+											Value: aws.String("tomorrow"),
+										},
+									},
+								},
+								// no override, acted upon: 1
+								{
+									InstanceId: aws.String("i-1265579100"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning},
+								},
+							},
+						},
+					},
+				},
+			}
+			run := &SchedulingRun{Action: subtest.action, Now: testSchedulingTime, Schedules: testScheduleCatalogue, RemoveExpiredOverrides: subtest.removeExpiredOverrides}
+			actualInstanceCount, err := stopStartTestInstancesInMemberAccount(client, run)
+			assert.NoError(t, err)
 			if want, got := subtest.expectedCount, actualInstanceCount; want != *got {
 				t.Errorf("want %v, got %v", want, got)
 			}
+			if want, got := subtest.expectedRemovedTags, len(client.DeleteTagsInputs); want != got {
+				t.Errorf("want %v removed tags, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestStoppedBySchedulerTags(t *testing.T) {
+	tests := []struct {
+		testTitle           string
+		action              string
+		state               ec2type.InstanceStateName
+		tags                []ec2type.Tag
+		expectedCreatedTags []ec2type.Tag
+		expectedDeletedTags []ec2type.Tag
+	}{
+		{
+			testTitle: "testing Stop action tags the instances it stops",
+			action:    "stop",
+			state:     ec2type.InstanceStateNameRunning,
+			expectedCreatedTags: []ec2type.Tag{
+				{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+				{Key: aws.String("instance-scheduler:stopped-at"), Value: aws.String("2026-10-14T12:00:00Z")},
+			},
+		},
+		{
+			testTitle: "testing Start action removes the tags from the instances it starts",
+			action:    "start",
+			state:     ec2type.InstanceStateNameStopped,
+			tags: []ec2type.Tag{
+				{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+				{Key: aws.String("instance-scheduler:stopped-at"), Value: aws.String("2026-10-13T19:00:00Z")},
+			},
+			expectedDeletedTags: []ec2type.Tag{
+				{Key: aws.String("instance-scheduler:stopped-by")},
+				{Key: aws.String("instance-scheduler:stopped-at")},
+			},
+		},
+		{
+			testTitle: "testing Start action leaves instances stopped by someone else",
+			action:    "start",
+			state:     ec2type.InstanceStateNameStopped,
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.testTitle, func(t *testing.T) {
+			client := &mockIEC2InstancesAPI{
+				DescribeInstancesOutput: &ec2.DescribeInstancesOutput{
+					Reservations: []ec2type.Reservation{
+						{
+							ReservationId: aws.String("r-0c318eab370f3d57a"),
+							Instances: []ec2type.Instance{
+								{
+									InstanceId: aws.String("i-6562279100"),
+									State:      &ec2type.InstanceState{Name: subtest.state},
+									Tags:       subtest.tags,
+								},
+							},
+						},
+					},
+				},
+			}
+			stopStartTestInstancesInMemberAccount(client, &SchedulingRun{Action: subtest.action, Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+
+			var createdTags, deletedTags []ec2type.Tag
+			for _, input := range client.CreateTagsInputs {
+				createdTags = append(createdTags, input.Tags...)
+			}
+			for _, input := range client.DeleteTagsInputs {
+				deletedTags = append(deletedTags, input.Tags...)
+			}
+			assert.Equal(t, subtest.expectedCreatedTags, createdTags)
+			assert.Equal(t, subtest.expectedDeletedTags, deletedTags)
 		})
 	}
 }
+
+func TestStopInstancesWithHibernation(t *testing.T) {
+	tests := []struct {
+		testTitle          string
+		hibernationOptions *ec2type.HibernationOptions
+		tags               []ec2type.Tag
+		hibernateError     error
+		expectedCount      InstanceCount
+		expectedHibernate  []bool
+	}{
+		{
+			testTitle:         "Instance without hibernation configured is stopped",
+			expectedCount:     InstanceCount{actedUpon: 1},
+			expectedHibernate: []bool{false},
+		},
+		{
+			testTitle:          "Instance with hibernation configured is hibernated",
+			hibernationOptions: &ec2type.HibernationOptions{Configured: aws.Bool(true)},
+			expectedCount:      InstanceCount{actedUpon: 1, hibernated: 1},
+			expectedHibernate:  []bool{true},
+		},
+		{
+			testTitle:         "Instance with stop mode tag hibernate is hibernated",
+			tags:              []ec2type.Tag{{Key: aws.String("instance-scheduling-stop-mode"), Value: aws.String("hibernate")}},
+			expectedCount:     InstanceCount{actedUpon: 1, hibernated: 1},
+			expectedHibernate: []bool{true},
+		},
+		{
+			testTitle:          "Instance with hibernation configured and stop mode tag stop is stopped",
+			hibernationOptions: &ec2type.HibernationOptions{Configured: aws.Bool(true)},
+			tags:               []ec2type.Tag{{Key: aws.String("instance-scheduling-stop-mode"), Value: aws.String("stop")}},
+			expectedCount:      InstanceCount{actedUpon: 1},
+			expectedHibernate:  []bool{false},
+		},
+		{
+			testTitle:          "Instance that cannot be hibernated falls back to a regular stop",
+			hibernationOptions: &ec2type.HibernationOptions{Configured: aws.Bool(true)},
+			hibernateError:     errors.New("UnsupportedHibernationConfiguration"),
+			expectedCount:      InstanceCount{actedUpon: 1},
+			expectedHibernate:  []bool{true, false},
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.testTitle, func(t *testing.T) {
+			client := &mockIEC2InstancesAPI{
+				DescribeInstancesOutput: &ec2.DescribeInstancesOutput{
+					Reservations: []ec2type.Reservation{
+						{
+							ReservationId: aws.String("r-0123456789abcdef0"),
+							Instances: []ec2type.Instance{
+								{
+									InstanceId:         aws.String("i-0123456789abcdef0"),
+									State:              &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning},
+									HibernationOptions: subtest.hibernationOptions,
+									Tags:               subtest.tags,
+								},
+							},
+						},
+					},
+				},
+				HibernateError: subtest.hibernateError,
+			}
+			actualCount, err := stopEc2Instances(client, &SchedulingRun{Action: "stop", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+			assert.NoError(t, err)
+			assert.Equal(t, subtest.expectedCount, *actualCount)
+
+			actualHibernate := []bool{}
+			for _, input := range client.StopInstancesInputs {
+				if !aws.ToBool(input.DryRun) {
+					actualHibernate = append(actualHibernate, aws.ToBool(input.Hibernate))
+				}
+			}
+			assert.Equal(t, subtest.expectedHibernate, actualHibernate)
+			assert.Len(t, client.CreateTagsInputs, 1)
+		})
+	}
+}
+
+func TestStopStartInstancesInActionableStates(t *testing.T) {
+	tests := []struct {
+		testTitle     string
+		action        string
+		state         ec2type.InstanceStateName
+		untagged      bool
+		expectedCount InstanceCount
+	}{
+		{
+			testTitle:     "testing Stop action stops running instances",
+			action:        "stop",
+			state:         ec2type.InstanceStateNameRunning,
+			expectedCount: InstanceCount{actedUpon: 1},
+		},
+		{
+			testTitle:     "testing Stop action skips stopping instances",
+			action:        "stop",
+			state:         ec2type.InstanceStateNameStopping,
+			expectedCount: InstanceCount{alreadyInDesiredState: 1},
+		},
+		{
+			testTitle:     "testing Stop action skips pending instances",
+			action:        "stop",
+			state:         ec2type.InstanceStateNamePending,
+			expectedCount: InstanceCount{notActionable: 1},
+		},
+		{
+			testTitle:     "testing Start action starts stopped instances",
+			action:        "start",
+			state:         ec2type.InstanceStateNameStopped,
+			expectedCount: InstanceCount{actedUpon: 1},
+		},
+		{
+			testTitle:     "testing Start action skips running instances",
+			action:        "start",
+			state:         ec2type.InstanceStateNameRunning,
+			expectedCount: InstanceCount{alreadyInDesiredState: 1},
+		},
+		{
+			testTitle:     "testing Start action skips stopping instances",
+			action:        "start",
+			state:         ec2type.InstanceStateNameStopping,
+			expectedCount: InstanceCount{notActionable: 1},
+		},
+		{
+			testTitle:     "testing Start action counts untagged running instances as already running",
+			action:        "start",
+			state:         ec2type.InstanceStateNameRunning,
+			untagged:      true,
+			expectedCount: InstanceCount{alreadyInDesiredState: 1},
+		},
+		{
+			testTitle:     "testing Start action leaves untagged stopped instances stopped",
+			action:        "start",
+			state:         ec2type.InstanceStateNameStopped,
+			untagged:      true,
+			expectedCount: InstanceCount{stoppedNotByScheduler: 1},
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.testTitle, func(t *testing.T) {
+			tags := []ec2type.Tag{
+				{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+			}
+			if subtest.untagged {
+				tags = nil
+			}
+			client := &mockIEC2InstancesAPI{
+				DescribeInstancesOutput: &ec2.DescribeInstancesOutput{
+					Reservations: []ec2type.Reservation{
+						{
+							ReservationId: aws.String("r-0123456789abcdef0"),
+							Instances: []ec2type.Instance{
+								{
+									InstanceId: aws.String("i-0123456789abcdef0"),
+									State:      &ec2type.InstanceState{Name: subtest.state},
+									Tags:       tags,
+								},
+							},
+						},
+					},
+				},
+			}
+			actualCount, err := stopStartTestInstancesInMemberAccount(client, &SchedulingRun{Action: subtest.action, Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+			assert.NoError(t, err)
+			assert.Equal(t, subtest.expectedCount, *actualCount)
+			assert.Equal(t, []ec2type.Filter{
+				{Name: aws.String("instance-state-name"), Values: []string{"pending", "running", "stopping", "stopped"}},
+			}, client.DescribeInstancesInputs[0].Filters)
+		})
+	}
+}
+
+func TestListEc2InstancesAcrossPages(t *testing.T) {
+	client := &mockIEC2InstancesAPI{
+		DescribeInstancesPages: []*ec2.DescribeInstancesOutput{
+			{
+				Reservations: []ec2type.Reservation{
+					{
+						ReservationId: aws.String("r-0123456789abcdef0"),
+						Instances: []ec2type.Instance{
+							{InstanceId: aws.String("i-0123456789abcdef0"), State: &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning}},
+						},
+					},
+				},
+			},
+			{
+				Reservations: []ec2type.Reservation{
+					{
+						ReservationId: aws.String("r-0123456789abcdef1"),
+						Instances: []ec2type.Instance{
+							{InstanceId: aws.String("i-0123456789abcdef1"), State: &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning}},
+							{InstanceId: aws.String("i-0123456789abcdef2"), State: &ec2type.InstanceState{Name: ec2type.InstanceStateNameStopped}},
+						},
+					},
+				},
+			},
+		},
+	}
+	actualCount, err := stopStartTestInstancesInMemberAccount(client, &SchedulingRun{Action: "stop", Now: testSchedulingTime, Schedules: testScheduleCatalogue, PageSize: 50})
+	assert.NoError(t, err)
+
+	assert.Equal(t, InstanceCount{actedUpon: 2, alreadyInDesiredState: 1}, *actualCount)
+	assert.Len(t, client.DescribeInstancesInputs, 2)
+	for _, input := range client.DescribeInstancesInputs {
+		assert.Equal(t, int32(50), aws.ToInt32(input.MaxResults))
+	}
+}
+
+func TestStopAndStartInstancesInBatches(t *testing.T) {
+	runningInstances := []ec2type.Instance{}
+	stoppedInstances := []ec2type.Instance{}
+	for n := range 150 {
+		runningInstances = append(runningInstances, ec2type.Instance{
+			InstanceId: aws.String(fmt.Sprintf("i-%017d", n)),
+			State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning},
+		})
+		stoppedInstances = append(stoppedInstances, ec2type.Instance{
+			InstanceId: aws.String(fmt.Sprintf("i-%017d", n)),
+			State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameStopped},
+			Tags: []ec2type.Tag{
+				{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+			},
+		})
+	}
+	batchSizes := func(instanceIds [][]string) []int {
+		sizes := []int{}
+		for _, ids := range instanceIds {
+			sizes = append(sizes, len(ids))
+		}
+		return sizes
+	}
+
+	client := &mockIEC2InstancesAPI{
+		DescribeInstancesOutput: &ec2.DescribeInstancesOutput{
+			Reservations: []ec2type.Reservation{{ReservationId: aws.String("r-0123456789abcdef0"), Instances: runningInstances}},
+		},
+		UnchangedInstanceIds: []string{"i-00000000000000007"},
+	}
+	actualCount, err := stopStartTestInstancesInMemberAccount(client, &SchedulingRun{Action: "stop", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+	assert.NoError(t, err)
+
+	assert.Equal(t, InstanceCount{actedUpon: 149, notActionable: 1}, *actualCount)
+	stopCalls := [][]string{}
+	for _, input := range client.StopInstancesInputs {
+		stopCalls = append(stopCalls, input.InstanceIds)
+	}
+	assert.Equal(t, []int{100, 100, 50, 50}, batchSizes(stopCalls))
+	assert.True(t, aws.ToBool(client.StopInstancesInputs[0].DryRun))
+	assert.False(t, aws.ToBool(client.StopInstancesInputs[1].DryRun))
+	tagCalls := [][]string{}
+	for _, input := range client.CreateTagsInputs {
+		tagCalls = append(tagCalls, input.Resources)
+	}
+	assert.Equal(t, []int{100, 49}, batchSizes(tagCalls))
+	assert.NotContains(t, tagCalls[0], "i-00000000000000007")
+
+	client = &mockIEC2InstancesAPI{
+		DescribeInstancesOutput: &ec2.DescribeInstancesOutput{
+			Reservations: []ec2type.Reservation{{ReservationId: aws.String("r-0123456789abcdef0"), Instances: stoppedInstances}},
+		},
+	}
+	actualCount, err = stopStartTestInstancesInMemberAccount(client, &SchedulingRun{Action: "start", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+	assert.NoError(t, err)
+
+	assert.Equal(t, InstanceCount{actedUpon: 150}, *actualCount)
+	startCalls := [][]string{}
+	for _, input := range client.StartInstancesInputs {
+		startCalls = append(startCalls, input.InstanceIds)
+	}
+	assert.Equal(t, []int{100, 100, 50, 50}, batchSizes(startCalls))
+	untagCalls := [][]string{}
+	for _, input := range client.DeleteTagsInputs {
+		untagCalls = append(untagCalls, input.Resources)
+	}
+	assert.Equal(t, []int{100, 50}, batchSizes(untagCalls))
+}
+
+func TestStopStartInstancesNotReportedByEC2(t *testing.T) {
+	tests := []struct {
+		testTitle     string
+		action        string
+		state         ec2type.InstanceStateName
+		expectedCount InstanceCount
+	}{
+		{
+			testTitle:     "EC2 testing Stop action counts instances not reported as stopping as not actionable",
+			action:        "stop",
+			state:         ec2type.InstanceStateNameRunning,
+			expectedCount: InstanceCount{actedUpon: 1, notActionable: 1},
+		},
+		{
+			testTitle:     "EC2 testing Start action counts instances not reported as starting as not actionable",
+			action:        "start",
+			state:         ec2type.InstanceStateNameStopped,
+			expectedCount: InstanceCount{actedUpon: 1, notActionable: 1},
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.testTitle, func(t *testing.T) {
+			tags := []ec2type.Tag{
+				{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+			}
+			client := &mockIEC2InstancesAPI{
+				DescribeInstancesOutput: &ec2.DescribeInstancesOutput{
+					Reservations: []ec2type.Reservation{
+						{
+							ReservationId: aws.String("r-0123456789abcdef0"),
+							Instances: []ec2type.Instance{
+								{InstanceId: aws.String("i-0123456789abcdef0"), State: &ec2type.InstanceState{Name: subtest.state}, Tags: tags},
+								{InstanceId: aws.String("i-0123456789abcdef1"), State: &ec2type.InstanceState{Name: subtest.state}, Tags: tags},
+							},
+						},
+					},
+				},
+				UnchangedInstanceIds: []string{"i-0123456789abcdef1"},
+			}
+			actualCount, err := stopStartTestInstancesInMemberAccount(client, &SchedulingRun{Action: subtest.action, Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+			assert.NoError(t, err)
+
+			assert.Equal(t, subtest.expectedCount, *actualCount)
+			for _, input := range client.CreateTagsInputs {
+				assert.Equal(t, []string{"i-0123456789abcdef0"}, input.Resources)
+			}
+			for _, input := range client.DeleteTagsInputs {
+				assert.Equal(t, []string{"i-0123456789abcdef0"}, input.Resources)
+			}
+		})
+	}
+}
+
+func TestReconcileInstancesOnBankHoliday(t *testing.T) {
+	tests := []struct {
+		testTitle           string
+		startOnBankHolidays bool
+		expectedStarted     []string
+		expectedCount       InstanceCount
+	}{
+		{
+			testTitle:     "EC2 testing Reconcile action on a bank holiday stops instances but does not start them",
+			expectedCount: InstanceCount{actedUpon: 1, skipped: 1},
+		},
+		{
+			testTitle:           "EC2 testing Reconcile action on a bank holiday starts instances of accounts which opt out",
+			startOnBankHolidays: true,
+			expectedStarted:     []string{"i-0123456789abcdef0"},
+			expectedCount:       InstanceCount{actedUpon: 2},
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.testTitle, func(t *testing.T) {
+			client := &mockIEC2InstancesAPI{
+				DescribeInstancesOutput: &ec2.DescribeInstancesOutput{
+					Reservations: []ec2type.Reservation{
+						{
+							ReservationId: aws.String("r-0123456789abcdef0"),
+							Instances: []ec2type.Instance{
+								// office-hours expects the instance to be running at midday
+								{
+									InstanceId: aws.String("i-0123456789abcdef0"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameStopped},
+									Tags:       []ec2type.Tag{{Key: aws.String("instance-scheduling"), Value: aws.String("office-hours")}},
+								},
+								// night time schedule expects the instance to be stopped at midday
+								{
+									InstanceId: aws.String("i-0123456789abcdef1"),
+									State:      &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning},
+									Tags:       []ec2type.Tag{{Key: aws.String("instance-scheduling"), Value: aws.String("cron(0 19 ? * MON-FRI)/cron(0 7 ? * MON-FRI)")}},
+								},
+							},
+						},
+					},
+				},
+			}
+			run := &SchedulingRun{Action: "reconcile", Now: testSchedulingTime, BankHoliday: "Christmas Day", StartOnBankHolidays: subtest.startOnBankHolidays, Schedules: testScheduleCatalogue}
+			actualCount, err := stopStartTestInstancesInMemberAccount(client, run)
+			assert.NoError(t, err)
+
+			assert.Equal(t, subtest.expectedCount, *actualCount)
+			var started, stopped []string
+			for _, input := range client.StartInstancesInputs {
+				if !aws.ToBool(input.DryRun) {
+					started = append(started, input.InstanceIds...)
+				}
+			}
+			for _, input := range client.StopInstancesInputs {
+				if !aws.ToBool(input.DryRun) {
+					stopped = append(stopped, input.InstanceIds...)
+				}
+			}
+			assert.Equal(t, subtest.expectedStarted, started)
+			assert.Equal(t, []string{"i-0123456789abcdef1"}, stopped)
+		})
+	}
+}
+
+func TestStopInstancesWithoutPermission(t *testing.T) {
+	client := &mockIEC2InstancesAPI{
+		DescribeInstancesOutput: &ec2.DescribeInstancesOutput{
+			Reservations: []ec2type.Reservation{
+				{
+					ReservationId: aws.String("r-0123456789abcdef0"),
+					Instances: []ec2type.Instance{
+						{InstanceId: aws.String("i-0123456789abcdef0"), State: &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning}},
+						{InstanceId: aws.String("i-0123456789abcdef1"), State: &ec2type.InstanceState{Name: ec2type.InstanceStateNameRunning}},
+					},
+				},
+			},
+		},
+		DryRunError: &smithy.GenericAPIError{Code: "UnauthorizedOperation"},
+	}
+	actualCount, err := stopStartTestInstancesInMemberAccount(client, &SchedulingRun{Action: "stop", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+	assert.NoError(t, err)
+
+	assert.Equal(t, InstanceCount{failed: 2}, *actualCount)
+	for _, input := range client.StopInstancesInputs {
+		assert.True(t, aws.ToBool(input.DryRun))
+	}
+	assert.Empty(t, client.CreateTagsInputs)
+}
+
+func TestStopAndStartInstancesWhenOneInstanceFails(t *testing.T) {
+	instances := func(state ec2type.InstanceStateName, tags []ec2type.Tag) []ec2type.Instance {
+		return []ec2type.Instance{
+			{InstanceId: aws.String("i-0123456789abcdef0"), State: &ec2type.InstanceState{Name: state}, Tags: tags},
+			{InstanceId: aws.String("i-0123456789abcdef1"), State: &ec2type.InstanceState{Name: state}, Tags: tags},
+			{InstanceId: aws.String("i-0123456789abcdef2"), State: &ec2type.InstanceState{Name: state}, Tags: tags},
+		}
+	}
+	stoppedBySchedulerTags := []ec2type.Tag{{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")}}
+
+	for _, action := range []string{"stop", "start"} {
+		t.Run(action, func(t *testing.T) {
+			client := &mockIEC2InstancesAPI{FailingInstanceIds: []string{"i-0123456789abcdef1"}}
+			if action == "stop" {
+				client.DescribeInstancesOutput = &ec2.DescribeInstancesOutput{
+					Reservations: []ec2type.Reservation{{ReservationId: aws.String("r-0123456789abcdef0"), Instances: instances(ec2type.InstanceStateNameRunning, nil)}},
+				}
+			} else {
+				client.DescribeInstancesOutput = &ec2.DescribeInstancesOutput{
+					Reservations: []ec2type.Reservation{{ReservationId: aws.String("r-0123456789abcdef0"), Instances: instances(ec2type.InstanceStateNameStopped, stoppedBySchedulerTags)}},
+				}
+			}
+			actualCount, err := stopStartTestInstancesInMemberAccount(client, &SchedulingRun{Action: action, Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+			assert.NoError(t, err)
+
+			assert.Equal(t, InstanceCount{actedUpon: 2, failed: 1}, *actualCount)
+			if action == "stop" {
+				assert.Equal(t, []string{"i-0123456789abcdef0", "i-0123456789abcdef2"}, client.CreateTagsInputs[0].Resources)
+			} else {
+				assert.Equal(t, []string{"i-0123456789abcdef0", "i-0123456789abcdef2"}, client.DeleteTagsInputs[0].Resources)
+			}
+		})
+	}
+}
+
+func TestListEc2InstancesClampsPageSize(t *testing.T) {
+	for pageSize, wantMaxResults := range map[int32]int32{2: 5, 5000: 1000} {
+		client := &mockIEC2InstancesAPI{DescribeInstancesOutput: &ec2.DescribeInstancesOutput{}}
+		_, err := listEc2Instances(client, &SchedulingRun{PageSize: pageSize})
+		assert.NoError(t, err)
+		assert.Equal(t, wantMaxResults, aws.ToInt32(client.DescribeInstancesInputs[0].MaxResults))
+	}
+}
+
+func TestStopStartTestInstancesInMemberAccountWithInvalidAction(t *testing.T) {
+	client := &mockIEC2InstancesAPI{DescribeInstancesOutput: &ec2.DescribeInstancesOutput{}}
+	actualCount, err := stopStartTestInstancesInMemberAccount(client, &SchedulingRun{Action: "hibernate", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+
+	assert.Nil(t, actualCount)
+	assert.EqualError(t, err, "invalid action: [ hibernate ]")
+	assert.Empty(t, client.DescribeInstancesInputs)
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstype "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// ECS services are stopped by saving their desired count in a tag on the service and scaling it to zero, and
+// started by restoring the saved desired count. Daemon services have no desired count and are not scheduled.
+
+const ecsDesiredCountTagKey string = "instance-scheduler:desired-count"
+
+// DescribeServices accepts at most 10 services per call
+const ecsDescribeServicesBatchSize int = 10
+
+type ECSServiceCount struct {
+	ECSActedUpon int
+	ECSSkipped   int
+}
+
+type IECSAPI interface {
+	ListClusters(ctx context.Context, params *ecs.ListClustersInput, optFns ...func(*ecs.Options)) (*ecs.ListClustersOutput, error)
+	ListServices(ctx context.Context, params *ecs.ListServicesInput, optFns ...func(*ecs.Options)) (*ecs.ListServicesOutput, error)
+	DescribeServices(ctx context.Context, params *ecs.DescribeServicesInput, optFns ...func(*ecs.Options)) (*ecs.DescribeServicesOutput, error)
+	UpdateService(ctx context.Context, params *ecs.UpdateServiceInput, optFns ...func(*ecs.Options)) (*ecs.UpdateServiceOutput, error)
+	TagResource(ctx context.Context, params *ecs.TagResourceInput, optFns ...func(*ecs.Options)) (*ecs.TagResourceOutput, error)
+	UntagResource(ctx context.Context, params *ecs.UntagResourceInput, optFns ...func(*ecs.Options)) (*ecs.UntagResourceOutput, error)
+}
+
+func stopStartTestECSServicesInMemberAccount(client IECSAPI, run *SchedulingRun) (*ECSServiceCount, error) {
+	action := run.Action
+	if action == "stop" {
+		return stopECSServices(client, run)
+	}
+	if action == "start" {
+		return startECSServices(client, run)
+	}
+	if action == "test" {
+		return testECSServices(client, run)
+	}
+	if action == "reconcile" {
+		return reconcileECSServices(client, run)
+	}
+	return nil, invalidActionError(action)
+}
+
+// listECSServices returns every service, with its tags, in every cluster in the member account
+func listECSServices(client IECSAPI, run *SchedulingRun) ([]ecstype.Service, error) {
+	services := []ecstype.Service{}
+	clusters := ecs.NewListClustersPaginator(client, &ecs.ListClustersInput{})
+	for clusters.HasMorePages() {
+		clusterPage, err := clusters.NextPage(run.ctx())
+		if err != nil {
+			return nil, err
+		}
+		for _, clusterArn := range clusterPage.ClusterArns {
+			serviceArns := []string{}
+			servicePages := ecs.NewListServicesPaginator(client, &ecs.ListServicesInput{Cluster: aws.String(clusterArn)})
+			for servicePages.HasMorePages() {
+				servicePage, err := servicePages.NextPage(run.ctx())
+				if err != nil {
+					return nil, err
+				}
+				serviceArns = append(serviceArns, servicePage.ServiceArns...)
+			}
+
+			for start := 0; start < len(serviceArns); start += ecsDescribeServicesBatchSize {
+				end := min(start+ecsDescribeServicesBatchSize, len(serviceArns))
+				result, err := client.DescribeServices(run.ctx(), &ecs.DescribeServicesInput{
+					Cluster:  aws.String(clusterArn),
+					Services: serviceArns[start:end],
+					Include:  []ecstype.ServiceField{ecstype.ServiceFieldTags},
+				})
+				if err != nil {
+					return nil, err
+				}
+				services = append(services, result.Services...)
+			}
+		}
+	}
+	return services, nil
+}
+
+func parseECSServiceTags(run *SchedulingRun, resource ecsServiceResource, skippedServices []string) (string, time.Time, *int32, bool, []string) {
+	instanceSchedulingTag, overrideUntil, _, isSkippable, skippedServices := parseResourceTags(run, resource, skippedServices)
+
+	var savedDesiredCount *int32
+	if value, ok := resource.tags()[ecsDesiredCountTagKey]; ok {
+		desiredCount, err := strconv.ParseInt(value, 10, 32)
+		if err == nil && desiredCount >= 0 {
+			savedDesiredCount = aws.Int32(int32(desiredCount))
+		} else {
+			run.Printf("WARN: Ignoring saved ECS service desired count because tag %v has invalid value '%v'\n", ecsDesiredCountTagKey, value)
+		}
+	}
+
+	if !isSkippable && resource.service.SchedulingStrategy == ecstype.SchedulingStrategyDaemon {
+		run.Printf("INFO: Skip ECS service because it is a daemon service without a desired count\n")
+		skippedServices = append(skippedServices, *resource.service.ServiceName)
+		isSkippable = true
+	}
+
+	return instanceSchedulingTag, overrideUntil, savedDesiredCount, isSkippable, skippedServices
+}
+
+func stopECSServices(client IECSAPI, run *SchedulingRun) (*ECSServiceCount, error) {
+	services, err := listECSServices(client, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about ECS services: %w", err)
+	}
+
+	servicesActedUpon := []string{}
+	skippedServices := []string{}
+	for _, service := range services {
+		run.Printf("INFO: ECS service: [ %v ]\n", *service.ServiceName)
+		resource := ecsServiceResource{client: client, service: service}
+		instanceSchedulingTag, overrideUntil, savedDesiredCount, skipService, skippedServicesModified := parseECSServiceTags(run, resource, skippedServices)
+		skippedServices = skippedServicesModified
+
+		if skipService {
+			continue
+		}
+
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		if isOverrideActive(overrideUntil, run.Now) {
+			run.Printf("INFO: Skipped ECS service because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+			skippedServices = append(skippedServices, *service.ServiceName)
+			continue
+		}
+
+		if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+			run.Printf("INFO: Skipped ECS service because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+			skippedServices = append(skippedServices, *service.ServiceName)
+			continue
+		}
+
+		if instanceSchedulingTag == "skip-auto-stop" {
+			run.Printf("INFO: Skipped ECS service because instance-scheduling tag having value 'skip-auto-stop'\n")
+			skippedServices = append(skippedServices, *service.ServiceName)
+			continue
+		}
+
+		if savedDesiredCount != nil || service.DesiredCount == 0 {
+			run.Printf("INFO: Skipped ECS service because it is already scaled to zero\n")
+			skippedServices = append(skippedServices, *service.ServiceName)
+			continue
+		}
+
+		run.Printf("INFO: Stopped ECS service because instance-scheduling tag is absent\n")
+		servicesActedUpon = append(servicesActedUpon, *service.ServiceName)
+		stopECSService(client, run, service)
+	}
+
+	run.Printf("INFO: Stopped %v ECS services: %v\n", len(servicesActedUpon), servicesActedUpon)
+	run.Printf("INFO: Skipped %v ECS services due to instance-scheduling tag or desired count: %v\n", len(skippedServices), skippedServices)
+
+	return &ECSServiceCount{ECSActedUpon: len(servicesActedUpon), ECSSkipped: len(skippedServices)}, nil
+}
+
+func startECSServices(client IECSAPI, run *SchedulingRun) (*ECSServiceCount, error) {
+	services, err := listECSServices(client, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about ECS services: %w", err)
+	}
+
+	servicesActedUpon := []string{}
+	skippedServices := []string{}
+	for _, service := range services {
+		run.Printf("INFO: ECS service: [ %v ]\n", *service.ServiceName)
+		resource := ecsServiceResource{client: client, service: service}
+		instanceSchedulingTag, overrideUntil, savedDesiredCount, skipService, skippedServicesModified := parseECSServiceTags(run, resource, skippedServices)
+		skippedServices = skippedServicesModified
+
+		if skipService {
+			continue
+		}
+
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+			run.Printf("INFO: Skipped ECS service because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+			skippedServices = append(skippedServices, *service.ServiceName)
+			continue
+		}
+
+		if instanceSchedulingTag == "skip-auto-start" {
+			run.Printf("INFO: Skipped ECS service because instance-scheduling tag having value 'skip-auto-start'\n")
+			skippedServices = append(skippedServices, *service.ServiceName)
+			continue
+		}
+
+		if savedDesiredCount == nil {
+			run.Printf("INFO: Skipped ECS service because it was not scaled to zero by the scheduler\n")
+			skippedServices = append(skippedServices, *service.ServiceName)
+			continue
+		}
+
+		run.Printf("INFO: Started ECS service because it was scaled to zero by the scheduler\n")
+		servicesActedUpon = append(servicesActedUpon, *service.ServiceName)
+		startECSService(client, run, service, *savedDesiredCount)
+	}
+
+	run.Printf("INFO: Started %v ECS services: %v\n", len(servicesActedUpon), servicesActedUpon)
+	run.Printf("INFO: Skipped %v ECS services due to instance-scheduling tag or not being stopped by the scheduler: %v\n", len(skippedServices), skippedServices)
+
+	return &ECSServiceCount{ECSActedUpon: len(servicesActedUpon), ECSSkipped: len(skippedServices)}, nil
+}
+
+func testECSServices(client IECSAPI, run *SchedulingRun) (*ECSServiceCount, error) {
+	services, err := listECSServices(client, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about ECS services: %w", err)
+	}
+
+	servicesActedUpon := []string{}
+	skippedServices := []string{}
+	for _, service := range services {
+		run.Printf("INFO: ECS service: [ %v ]\n", *service.ServiceName)
+		resource := ecsServiceResource{client: client, service: service}
+		instanceSchedulingTag, overrideUntil, _, skipService, skippedServicesModified := parseECSServiceTags(run, resource, skippedServices)
+		skippedServices = skippedServicesModified
+
+		if skipService {
+			continue
+		}
+
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		if isOverrideActive(overrideUntil, run.Now) {
+			run.Printf("INFO: Skipped ECS service because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+			skippedServices = append(skippedServices, *service.ServiceName)
+			continue
+		}
+
+		if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+			run.Printf("INFO: Skipped ECS service because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+			skippedServices = append(skippedServices, *service.ServiceName)
+			continue
+		}
+
+		if instanceSchedulingTag == "skip-auto-stop" || instanceSchedulingTag == "skip-auto-start" {
+			run.Printf("INFO: Skipped ECS service because instance-scheduling tag having value 'skip-auto-stop' or 'skip-auto-start'\n")
+			skippedServices = append(skippedServices, *service.ServiceName)
+			continue
+		}
+
+		servicesActedUpon = append(servicesActedUpon, *service.ServiceName)
+		run.Printf("INFO: Successfully tested ECS service %v\n", *service.ServiceName)
+	}
+
+	run.Printf("INFO: Tested %v ECS services: %v\n", len(servicesActedUpon), servicesActedUpon)
+	run.Printf("INFO: Skipped %v ECS services due to instance-scheduling tag: %v\n", len(skippedServices), skippedServices)
+
+	return &ECSServiceCount{ECSActedUpon: len(servicesActedUpon), ECSSkipped: len(skippedServices)}, nil
+}
+
+func reconcileECSServices(client IECSAPI, run *SchedulingRun) (*ECSServiceCount, error) {
+	services, err := listECSServices(client, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about ECS services: %w", err)
+	}
+
+	servicesStarted := []string{}
+	servicesStopped := []string{}
+	skippedServices := []string{}
+	for _, service := range services {
+		run.Printf("INFO: ECS service: [ %v ]\n", *service.ServiceName)
+		resource := ecsServiceResource{client: client, service: service}
+		instanceSchedulingTag, overrideUntil, savedDesiredCount, skipService, skippedServicesModified := parseECSServiceTags(run, resource, skippedServices)
+		skippedServices = skippedServicesModified
+
+		if skipService {
+			continue
+		}
+
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		schedule := run.Schedules.getSchedule(run, instanceSchedulingTag)
+		if schedule == nil {
+			run.Printf("INFO: Skipped ECS service because its instance-scheduling tag does not reference a schedule\n")
+			skippedServices = append(skippedServices, *service.ServiceName)
+			continue
+		}
+
+		desiredState := run.desiredState(schedule)
+
+		if desiredState == scheduleStateRunning && savedDesiredCount != nil {
+			run.Printf("INFO: Starting ECS service because schedule '%v' expects it to be running\n", schedule.Name)
+			servicesStarted = append(servicesStarted, *service.ServiceName)
+			startECSService(client, run, service, *savedDesiredCount)
+			continue
+		}
+
+		if desiredState == scheduleStateStopped && savedDesiredCount == nil && service.DesiredCount > 0 && isOverrideActive(overrideUntil, run.Now) {
+			run.Printf("INFO: Skipped stopping ECS service because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+			skippedServices = append(skippedServices, *service.ServiceName)
+			continue
+		}
+
+		if desiredState == scheduleStateStopped && savedDesiredCount == nil && service.DesiredCount > 0 {
+			run.Printf("INFO: Stopping ECS service because schedule '%v' expects it to be stopped\n", schedule.Name)
+			servicesStopped = append(servicesStopped, *service.ServiceName)
+			stopECSService(client, run, service)
+			continue
+		}
+
+		run.Printf("INFO: Skipped ECS service because schedule '%v' expects it to be '%v'\n", schedule.Name, desiredState)
+		skippedServices = append(skippedServices, *service.ServiceName)
+	}
+
+	run.Printf("INFO: Started %v ECS services: %v\n", len(servicesStarted), servicesStarted)
+	run.Printf("INFO: Stopped %v ECS services: %v\n", len(servicesStopped), servicesStopped)
+	run.Printf("INFO: Skipped %v ECS services due to instance-scheduling tag or schedule: %v\n", len(skippedServices), skippedServices)
+
+	return &ECSServiceCount{ECSActedUpon: len(servicesStarted) + len(servicesStopped), ECSSkipped: len(skippedServices)}, nil
+}
+
+// stopECSService saves the desired count of a service in a tag and then scales it to zero. The service is left
+// alone if its desired count cannot be saved, as it could not be restored afterwards.
+func stopECSService(client IECSAPI, run *SchedulingRun, service ecstype.Service) {
+	resource := ecsServiceResource{client: client, service: service}
+	err := resource.addTags(run, append([]resourceTag{{key: ecsDesiredCountTagKey, value: strconv.Itoa(int(service.DesiredCount))}}, stoppedBySchedulerTags(run)...))
+	if err != nil {
+		run.Printf("ERROR: Could not save the desired count of ECS service %v, so it was not stopped: %v\n", *service.ServiceName, err)
+		return
+	}
+
+	_, err = client.UpdateService(run.ctx(), &ecs.UpdateServiceInput{
+		Cluster:      service.ClusterArn,
+		Service:      service.ServiceName,
+		DesiredCount: aws.Int32(0),
+	})
+	if err == nil {
+		run.Printf("INFO: Successfully stopped ECS service %v\n", *service.ServiceName)
+	} else {
+		run.Printf("ERROR: Could not stop ECS service: %v\n", err)
+	}
+}
+
+// startECSService restores the desired count saved by stopECSService and then removes the tags holding it
+func startECSService(client IECSAPI, run *SchedulingRun, service ecstype.Service, desiredCount int32) {
+	_, err := client.UpdateService(run.ctx(), &ecs.UpdateServiceInput{
+		Cluster:      service.ClusterArn,
+		Service:      service.ServiceName,
+		DesiredCount: aws.Int32(desiredCount),
+	})
+	if err != nil {
+		run.Printf("ERROR: Could not start ECS service: %v\n", err)
+		return
+	}
+	run.Printf("INFO: Successfully started ECS service %v\n", *service.ServiceName)
+
+	resource := ecsServiceResource{client: client, service: service}
+	err = resource.removeTags(run, append([]string{ecsDesiredCountTagKey}, stoppedBySchedulerTagKeys...))
+	if err != nil {
+		run.Printf("ERROR: Could not remove the saved desired count tags from ECS service %v: %v\n", *service.ServiceName, err)
+	}
+}
+
+// ecsServiceResource adapts an ECS service to the tag helpers shared by every service
+type ecsServiceResource struct {
+	client  IECSAPI
+	service ecstype.Service
+}
+
+func (resource ecsServiceResource) kind() string {
+	return "ECS service"
+}
+
+func (resource ecsServiceResource) id() string {
+	return aws.ToString(resource.service.ServiceName)
+}
+
+func (resource ecsServiceResource) tags() map[string]string {
+	tags := map[string]string{}
+	for _, tag := range resource.service.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags
+}
+
+func (resource ecsServiceResource) addTags(run *SchedulingRun, tags []resourceTag) error {
+	ecsTags := []ecstype.Tag{}
+	for _, tag := range tags {
+		ecsTags = append(ecsTags, ecstype.Tag{Key: aws.String(tag.key), Value: aws.String(tag.value)})
+	}
+	_, err := resource.client.TagResource(run.ctx(), &ecs.TagResourceInput{
+		ResourceArn: resource.service.ServiceArn,
+		Tags:        ecsTags,
+	})
+	return err
+}
+
+func (resource ecsServiceResource) removeTags(run *SchedulingRun, keys []string) error {
+	_, err := resource.client.UntagResource(run.ctx(), &ecs.UntagResourceInput{
+		ResourceArn: resource.service.ServiceArn,
+		TagKeys:     keys,
+	})
+	return err
+}
+
+func getECSClientForMemberAccount(session *MemberAccountSession) IECSAPI {
+	return ecs.NewFromConfig(session.Config)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstype "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockIECSAPI struct {
+	Services              map[string][]ecstype.Service
+	DescribeServicesCalls int
+	UpdateServiceInputs   []*ecs.UpdateServiceInput
+	TagResourceInputs     []*ecs.TagResourceInput
+	UntagResourceInputs   []*ecs.UntagResourceInput
+}
+
+func (m *mockIECSAPI) ListClusters(ctx context.Context, params *ecs.ListClustersInput, optFns ...func(*ecs.Options)) (*ecs.ListClustersOutput, error) {
+	output := &ecs.ListClustersOutput{}
+	for clusterArn := range m.Services {
+		output.ClusterArns = append(output.ClusterArns, clusterArn)
+	}
+	return output, nil
+}
+
+func (m *mockIECSAPI) ListServices(ctx context.Context, params *ecs.ListServicesInput, optFns ...func(*ecs.Options)) (*ecs.ListServicesOutput, error) {
+	output := &ecs.ListServicesOutput{}
+	for _, service := range m.Services[*params.Cluster] {
+		output.ServiceArns = append(output.ServiceArns, *service.ServiceArn)
+	}
+	return output, nil
+}
+
+func (m *mockIECSAPI) DescribeServices(ctx context.Context, params *ecs.DescribeServicesInput, optFns ...func(*ecs.Options)) (*ecs.DescribeServicesOutput, error) {
+	m.DescribeServicesCalls++
+	output := &ecs.DescribeServicesOutput{}
+	for _, serviceArn := range params.Services {
+		for _, service := range m.Services[*params.Cluster] {
+			if *service.ServiceArn == serviceArn {
+				output.Services = append(output.Services, service)
+			}
+		}
+	}
+	return output, nil
+}
+
+func (m *mockIECSAPI) UpdateService(ctx context.Context, params *ecs.UpdateServiceInput, optFns ...func(*ecs.Options)) (*ecs.UpdateServiceOutput, error) {
+	m.UpdateServiceInputs = append(m.UpdateServiceInputs, params)
+	return &ecs.UpdateServiceOutput{}, nil
+}
+
+func (m *mockIECSAPI) TagResource(ctx context.Context, params *ecs.TagResourceInput, optFns ...func(*ecs.Options)) (*ecs.TagResourceOutput, error) {
+	m.TagResourceInputs = append(m.TagResourceInputs, params)
+	return &ecs.TagResourceOutput{}, nil
+}
+
+func (m *mockIECSAPI) UntagResource(ctx context.Context, params *ecs.UntagResourceInput, optFns ...func(*ecs.Options)) (*ecs.UntagResourceOutput, error) {
+	m.UntagResourceInputs = append(m.UntagResourceInputs, params)
+	return &ecs.UntagResourceOutput{}, nil
+}
+
+const testECSClusterArn string = "arn:aws:ecs:eu-west-2:123456789012:cluster/test-cluster"
+
+func testECSService(name string, desiredCount int32, tags map[string]string) ecstype.Service {
+	service := ecstype.Service{
+		ServiceName:        aws.String(name),
+		ServiceArn:         aws.String("arn:aws:ecs:eu-west-2:123456789012:service/test-cluster/" + name),
+		ClusterArn:         aws.String(testECSClusterArn),
+		DesiredCount:       desiredCount,
+		SchedulingStrategy: ecstype.SchedulingStrategyReplica,
+	}
+	for key, value := range tags {
+		service.Tags = append(service.Tags, ecstype.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	return service
+}
+
+func TestStopStartTestECSServicesInMemberAccount(t *testing.T) {
+	daemon := testECSService("log-router", 0, nil)
+	daemon.SchedulingStrategy = ecstype.SchedulingStrategyDaemon
+	services := []ecstype.Service{
+		// no instance-scheduling tag and running, acted upon by stop and test
+		testECSService("frontend", 2, nil),
+		// instance-scheduling = skip-scheduling, skipped: 1
+		testECSService("backend", 1, map[string]string{"instance-scheduling": "skip-scheduling"}),
+		// scaled to zero by the scheduler, skipped by stop, acted upon by start and test
+		testECSService("worker", 0, map[string]string{"instance-scheduler:desired-count": "3", "instance-scheduler:stopped-by": "scheduler"}),
+		// scaled to zero by someone else, skipped by stop and start, acted upon by test
+		testECSService("batch", 0, nil),
+		// daemon service without a desired count, skipped: 1
+		daemon,
+		// nights-only expects the service to be stopped at midday and it is running, acted upon by reconcile
+		testECSService("reports", 1, map[string]string{"instance-scheduling": "nights-only"}),
+	}
+
+	tests := []struct {
+		testTitle      string
+		action         string
+		expectedCount  ECSServiceCount
+		expectedUpdate map[string]int32
+	}{
+		{
+			testTitle:      "ECS testing Test action",
+			action:         "test",
+			expectedCount:  ECSServiceCount{ECSActedUpon: 3, ECSSkipped: 3},
+			expectedUpdate: map[string]int32{},
+		},
+		{
+			testTitle:      "ECS testing Stop action",
+			action:         "stop",
+			expectedCount:  ECSServiceCount{ECSActedUpon: 1, ECSSkipped: 5},
+			expectedUpdate: map[string]int32{"frontend": 0},
+		},
+		{
+			testTitle:      "ECS testing Start action",
+			action:         "start",
+			expectedCount:  ECSServiceCount{ECSActedUpon: 1, ECSSkipped: 5},
+			expectedUpdate: map[string]int32{"worker": 3},
+		},
+		{
+			testTitle:      "ECS testing Reconcile action",
+			action:         "reconcile",
+			expectedCount:  ECSServiceCount{ECSActedUpon: 1, ECSSkipped: 5},
+			expectedUpdate: map[string]int32{"reports": 0},
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.testTitle, func(t *testing.T) {
+			client := &mockIECSAPI{Services: map[string][]ecstype.Service{testECSClusterArn: services}}
+			actualCount, err := stopStartTestECSServicesInMemberAccount(client, &SchedulingRun{Action: subtest.action, Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+			assert.NoError(t, err)
+			assert.Equal(t, subtest.expectedCount, *actualCount)
+
+			actualUpdate := map[string]int32{}
+			for _, input := range client.UpdateServiceInputs {
+				actualUpdate[*input.Service] = *input.DesiredCount
+			}
+			assert.Equal(t, subtest.expectedUpdate, actualUpdate)
+		})
+	}
+}
+
+func TestStopAndStartECSService(t *testing.T) {
+	client := &mockIECSAPI{Services: map[string][]ecstype.Service{testECSClusterArn: {testECSService("frontend", 2, nil)}}}
+	stopStartTestECSServicesInMemberAccount(client, &SchedulingRun{Action: "stop", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+
+	assert.Len(t, client.TagResourceInputs, 1)
+	assert.Equal(t, "arn:aws:ecs:eu-west-2:123456789012:service/test-cluster/frontend", *client.TagResourceInputs[0].ResourceArn)
+	savedTags := map[string]string{}
+	for _, tag := range client.TagResourceInputs[0].Tags {
+		savedTags[*tag.Key] = *tag.Value
+	}
+	assert.Equal(t, map[string]string{
+		"instance-scheduler:desired-count": "2",
+		"instance-scheduler:stopped-by":    "scheduler",
+		"instance-scheduler:stopped-at":    "2026-10-14T12:00:00Z",
+	}, savedTags)
+
+	client = &mockIECSAPI{Services: map[string][]ecstype.Service{testECSClusterArn: {testECSService("frontend", 0, savedTags)}}}
+	stopStartTestECSServicesInMemberAccount(client, &SchedulingRun{Action: "start", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+
+	assert.Len(t, client.UpdateServiceInputs, 1)
+	assert.Equal(t, int32(2), *client.UpdateServiceInputs[0].DesiredCount)
+	assert.Equal(t, testECSClusterArn, *client.UpdateServiceInputs[0].Cluster)
+	assert.Len(t, client.UntagResourceInputs, 1)
+	assert.ElementsMatch(t, []string{"instance-scheduler:desired-count", "instance-scheduler:stopped-by", "instance-scheduler:stopped-at"}, client.UntagResourceInputs[0].TagKeys)
+}
+
+func TestListECSServicesInBatches(t *testing.T) {
+	services := []ecstype.Service{}
+	for i := range 23 {
+		services = append(services, testECSService(fmt.Sprintf("service-%v", i), 1, nil))
+	}
+	client := &mockIECSAPI{Services: map[string][]ecstype.Service{testECSClusterArn: services}}
+
+	actualServices, err := listECSServices(client, &SchedulingRun{})
+
+	assert.NoError(t, err)
+	assert.Len(t, actualServices, 23)
+	assert.Equal(t, 3, client.DescribeServicesCalls)
+}
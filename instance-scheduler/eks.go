@@ -0,0 +1,502 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstype "github.com/aws/aws-sdk-go-v2/service/eks/types"
+)
+
+// EKS managed node groups are stopped by saving their scaling configuration in tags on the node group and scaling
+// it to zero nodes, and started by restoring the saved configuration. The Auto Scaling groups behind them are left
+// to EKS. A node group follows the instance-scheduling tag on its cluster unless it carries one of its own.
+
+// eksNodegroupTagKey is added by EKS to the Auto Scaling group behind every managed node group
+const eksNodegroupTagKey string = "eks:nodegroup-name"
+
+type EKSNodegroupCount struct {
+	EKSActedUpon int
+	EKSSkipped   int
+	EKSFailed    int
+}
+
+type IEKSAPI interface {
+	ListClusters(ctx context.Context, params *eks.ListClustersInput, optFns ...func(*eks.Options)) (*eks.ListClustersOutput, error)
+	DescribeCluster(ctx context.Context, params *eks.DescribeClusterInput, optFns ...func(*eks.Options)) (*eks.DescribeClusterOutput, error)
+	ListNodegroups(ctx context.Context, params *eks.ListNodegroupsInput, optFns ...func(*eks.Options)) (*eks.ListNodegroupsOutput, error)
+	DescribeNodegroup(ctx context.Context, params *eks.DescribeNodegroupInput, optFns ...func(*eks.Options)) (*eks.DescribeNodegroupOutput, error)
+	UpdateNodegroupConfig(ctx context.Context, params *eks.UpdateNodegroupConfigInput, optFns ...func(*eks.Options)) (*eks.UpdateNodegroupConfigOutput, error)
+	TagResource(ctx context.Context, params *eks.TagResourceInput, optFns ...func(*eks.Options)) (*eks.TagResourceOutput, error)
+	UntagResource(ctx context.Context, params *eks.UntagResourceInput, optFns ...func(*eks.Options)) (*eks.UntagResourceOutput, error)
+}
+
+// EKSNodegroup is a managed node group together with the cluster it belongs to
+type EKSNodegroup struct {
+	Nodegroup ekstype.Nodegroup
+	Cluster   ekstype.Cluster
+}
+
+func stopStartTestEKSNodegroupsInMemberAccount(client IEKSAPI, run *SchedulingRun) (*EKSNodegroupCount, error) {
+	action := run.Action
+	if action == "stop" {
+		return stopEKSNodegroups(client, run)
+	}
+	if action == "start" {
+		return startEKSNodegroups(client, run)
+	}
+	if action == "test" {
+		return testEKSNodegroups(client, run)
+	}
+	if action == "reconcile" {
+		return reconcileEKSNodegroups(client, run)
+	}
+	return nil, invalidActionError(action)
+}
+
+// listEKSNodegroups returns every managed node group, with its cluster, in the member account
+func listEKSNodegroups(client IEKSAPI, run *SchedulingRun) ([]EKSNodegroup, error) {
+	nodegroups := []EKSNodegroup{}
+	clusters := eks.NewListClustersPaginator(client, &eks.ListClustersInput{})
+	for clusters.HasMorePages() {
+		clusterPage, err := clusters.NextPage(run.ctx())
+		if err != nil {
+			return nil, err
+		}
+		for _, clusterName := range clusterPage.Clusters {
+			cluster, err := client.DescribeCluster(run.ctx(), &eks.DescribeClusterInput{Name: aws.String(clusterName)})
+			if err != nil {
+				return nil, err
+			}
+
+			nodegroupPages := eks.NewListNodegroupsPaginator(client, &eks.ListNodegroupsInput{ClusterName: aws.String(clusterName)})
+			for nodegroupPages.HasMorePages() {
+				nodegroupPage, err := nodegroupPages.NextPage(run.ctx())
+				if err != nil {
+					return nil, err
+				}
+				for _, nodegroupName := range nodegroupPage.Nodegroups {
+					nodegroup, err := client.DescribeNodegroup(run.ctx(), &eks.DescribeNodegroupInput{
+						ClusterName:   aws.String(clusterName),
+						NodegroupName: aws.String(nodegroupName),
+					})
+					if err != nil {
+						return nil, err
+					}
+					nodegroups = append(nodegroups, EKSNodegroup{Nodegroup: *nodegroup.Nodegroup, Cluster: *cluster.Cluster})
+				}
+			}
+		}
+	}
+	return nodegroups, nil
+}
+
+// parseEKSNodegroupTags reads the node group's tags, falling back to its cluster's instance-scheduling and
+// instance-scheduling-override-until tags. The returned resource is the one carrying the override tag.
+func parseEKSNodegroupTags(run *SchedulingRun, client IEKSAPI, nodegroup EKSNodegroup, skippedNodegroups []string) (string, time.Time, eksResource, *AutoScalingGroupCapacity, bool, []string) {
+	nodegroupResource := newEKSNodegroupResource(client, nodegroup)
+	instanceSchedulingTag, hasInstanceSchedulingTag := nodegroup.Nodegroup.Tags["instance-scheduling"]
+	if !hasInstanceSchedulingTag {
+		instanceSchedulingTag = nodegroup.Cluster.Tags["instance-scheduling"]
+	}
+
+	var overrideUntil time.Time
+	overrideResource := nodegroupResource
+	if value, ok := nodegroup.Nodegroup.Tags[overrideUntilTagKey]; ok {
+		overrideUntil = parseOverrideUntilTag(run, value)
+	} else if value, ok := nodegroup.Cluster.Tags[overrideUntilTagKey]; ok {
+		overrideUntil = parseOverrideUntilTag(run, value)
+		overrideResource = newEKSClusterResource(client, nodegroup)
+	}
+
+	savedCapacity := map[string]string{}
+	for _, key := range []string{asgMinSizeTagKey, asgMaxSizeTagKey, asgDesiredCapacityTagKey} {
+		if value, ok := nodegroup.Nodegroup.Tags[key]; ok {
+			savedCapacity[key] = value
+		}
+	}
+
+	isSkippable, skippedNodegroups := isSkipScheduling(run, nodegroupResource, instanceSchedulingTag, skippedNodegroups)
+	if !isSkippable && (nodegroup.Nodegroup.Status != ekstype.NodegroupStatusActive || nodegroup.Nodegroup.ScalingConfig == nil) {
+		run.Printf("INFO: Skip EKS node group because its status is '%v' rather than 'ACTIVE'\n", nodegroup.Nodegroup.Status)
+		skippedNodegroups = append(skippedNodegroups, nodegroupResource.id())
+		isSkippable = true
+	}
+
+	return instanceSchedulingTag, overrideUntil, overrideResource, parseAutoScalingGroupCapacity(run, savedCapacity), isSkippable, skippedNodegroups
+}
+
+func nodegroupName(nodegroup EKSNodegroup) string {
+	return fmt.Sprintf("%v/%v", aws.ToString(nodegroup.Cluster.Name), aws.ToString(nodegroup.Nodegroup.NodegroupName))
+}
+
+// isNodegroupScaledToZero ignores the maximum size, which EKS requires to be at least one
+func isNodegroupScaledToZero(nodegroup EKSNodegroup) bool {
+	scalingConfig := nodegroup.Nodegroup.ScalingConfig
+	return aws.ToInt32(scalingConfig.MinSize) == 0 && aws.ToInt32(scalingConfig.DesiredSize) == 0
+}
+
+func stopEKSNodegroups(client IEKSAPI, run *SchedulingRun) (*EKSNodegroupCount, error) {
+	nodegroups, err := listEKSNodegroups(client, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about EKS node groups: %w", err)
+	}
+
+	nodegroupsActedUpon := []string{}
+	skippedNodegroups := []string{}
+	failedNodegroups := []string{}
+	for _, nodegroup := range nodegroups {
+		name := nodegroupName(nodegroup)
+		run.Printf("INFO: EKS node group: [ %v ]\n", name)
+		instanceSchedulingTag, overrideUntil, overrideResource, savedCapacity, skipNodegroup, skippedNodegroupsModified := parseEKSNodegroupTags(run, client, nodegroup, skippedNodegroups)
+		skippedNodegroups = skippedNodegroupsModified
+
+		if skipNodegroup {
+			continue
+		}
+
+		removeExpiredOverride(run, overrideResource, overrideUntil)
+
+		if isOverrideActive(overrideUntil, run.Now) {
+			run.Printf("INFO: Skipped EKS node group because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+			skippedNodegroups = append(skippedNodegroups, name)
+			continue
+		}
+
+		if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+			run.Printf("INFO: Skipped EKS node group because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+			skippedNodegroups = append(skippedNodegroups, name)
+			continue
+		}
+
+		if instanceSchedulingTag == "skip-auto-stop" {
+			run.Printf("INFO: Skipped EKS node group because instance-scheduling tag having value 'skip-auto-stop'\n")
+			skippedNodegroups = append(skippedNodegroups, name)
+			continue
+		}
+
+		if savedCapacity != nil || isNodegroupScaledToZero(nodegroup) {
+			run.Printf("INFO: Skipped EKS node group because it is already scaled to zero\n")
+			skippedNodegroups = append(skippedNodegroups, name)
+			continue
+		}
+
+		run.Printf("INFO: Stopping EKS node group because instance-scheduling tag is absent\n")
+		if err := stopEKSNodegroup(client, run, nodegroup); err != nil {
+			failedNodegroups = append(failedNodegroups, name)
+			continue
+		}
+		nodegroupsActedUpon = append(nodegroupsActedUpon, name)
+	}
+
+	run.Printf("INFO: Stopped %v EKS node groups: %v\n", len(nodegroupsActedUpon), nodegroupsActedUpon)
+	run.Printf("INFO: Skipped %v EKS node groups due to instance-scheduling tag or capacity: %v\n", len(skippedNodegroups), skippedNodegroups)
+	run.Printf("INFO: Could not stop %v EKS node groups: %v\n", len(failedNodegroups), failedNodegroups)
+
+	return &EKSNodegroupCount{EKSActedUpon: len(nodegroupsActedUpon), EKSSkipped: len(skippedNodegroups), EKSFailed: len(failedNodegroups)}, nil
+}
+
+func startEKSNodegroups(client IEKSAPI, run *SchedulingRun) (*EKSNodegroupCount, error) {
+	nodegroups, err := listEKSNodegroups(client, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about EKS node groups: %w", err)
+	}
+
+	nodegroupsActedUpon := []string{}
+	skippedNodegroups := []string{}
+	failedNodegroups := []string{}
+	for _, nodegroup := range nodegroups {
+		name := nodegroupName(nodegroup)
+		run.Printf("INFO: EKS node group: [ %v ]\n", name)
+		instanceSchedulingTag, overrideUntil, overrideResource, savedCapacity, skipNodegroup, skippedNodegroupsModified := parseEKSNodegroupTags(run, client, nodegroup, skippedNodegroups)
+		skippedNodegroups = skippedNodegroupsModified
+
+		if skipNodegroup {
+			continue
+		}
+
+		removeExpiredOverride(run, overrideResource, overrideUntil)
+
+		if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+			run.Printf("INFO: Skipped EKS node group because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+			skippedNodegroups = append(skippedNodegroups, name)
+			continue
+		}
+
+		if instanceSchedulingTag == "skip-auto-start" {
+			run.Printf("INFO: Skipped EKS node group because instance-scheduling tag having value 'skip-auto-start'\n")
+			skippedNodegroups = append(skippedNodegroups, name)
+			continue
+		}
+
+		if savedCapacity == nil {
+			run.Printf("INFO: Skipped EKS node group because it was not scaled to zero by the scheduler\n")
+			skippedNodegroups = append(skippedNodegroups, name)
+			continue
+		}
+
+		run.Printf("INFO: Starting EKS node group because it was scaled to zero by the scheduler\n")
+		if err := startEKSNodegroup(client, run, nodegroup, savedCapacity); err != nil {
+			failedNodegroups = append(failedNodegroups, name)
+			continue
+		}
+		nodegroupsActedUpon = append(nodegroupsActedUpon, name)
+	}
+
+	run.Printf("INFO: Started %v EKS node groups: %v\n", len(nodegroupsActedUpon), nodegroupsActedUpon)
+	run.Printf("INFO: Skipped %v EKS node groups due to instance-scheduling tag or not being stopped by the scheduler: %v\n", len(skippedNodegroups), skippedNodegroups)
+	run.Printf("INFO: Could not start %v EKS node groups: %v\n", len(failedNodegroups), failedNodegroups)
+
+	return &EKSNodegroupCount{EKSActedUpon: len(nodegroupsActedUpon), EKSSkipped: len(skippedNodegroups), EKSFailed: len(failedNodegroups)}, nil
+}
+
+func testEKSNodegroups(client IEKSAPI, run *SchedulingRun) (*EKSNodegroupCount, error) {
+	nodegroups, err := listEKSNodegroups(client, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about EKS node groups: %w", err)
+	}
+
+	nodegroupsActedUpon := []string{}
+	skippedNodegroups := []string{}
+	for _, nodegroup := range nodegroups {
+		name := nodegroupName(nodegroup)
+		run.Printf("INFO: EKS node group: [ %v ]\n", name)
+		instanceSchedulingTag, overrideUntil, overrideResource, _, skipNodegroup, skippedNodegroupsModified := parseEKSNodegroupTags(run, client, nodegroup, skippedNodegroups)
+		skippedNodegroups = skippedNodegroupsModified
+
+		if skipNodegroup {
+			continue
+		}
+
+		removeExpiredOverride(run, overrideResource, overrideUntil)
+
+		if isOverrideActive(overrideUntil, run.Now) {
+			run.Printf("INFO: Skipped EKS node group because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+			skippedNodegroups = append(skippedNodegroups, name)
+			continue
+		}
+
+		if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+			run.Printf("INFO: Skipped EKS node group because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+			skippedNodegroups = append(skippedNodegroups, name)
+			continue
+		}
+
+		if instanceSchedulingTag == "skip-auto-stop" || instanceSchedulingTag == "skip-auto-start" {
+			run.Printf("INFO: Skipped EKS node group because instance-scheduling tag having value 'skip-auto-stop' or 'skip-auto-start'\n")
+			skippedNodegroups = append(skippedNodegroups, name)
+			continue
+		}
+
+		nodegroupsActedUpon = append(nodegroupsActedUpon, name)
+		run.Printf("INFO: Successfully tested EKS node group %v\n", name)
+	}
+
+	run.Printf("INFO: Tested %v EKS node groups: %v\n", len(nodegroupsActedUpon), nodegroupsActedUpon)
+	run.Printf("INFO: Skipped %v EKS node groups due to instance-scheduling tag: %v\n", len(skippedNodegroups), skippedNodegroups)
+
+	return &EKSNodegroupCount{EKSActedUpon: len(nodegroupsActedUpon), EKSSkipped: len(skippedNodegroups)}, nil
+}
+
+func reconcileEKSNodegroups(client IEKSAPI, run *SchedulingRun) (*EKSNodegroupCount, error) {
+	nodegroups, err := listEKSNodegroups(client, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about EKS node groups: %w", err)
+	}
+
+	nodegroupsStarted := []string{}
+	nodegroupsStopped := []string{}
+	skippedNodegroups := []string{}
+	failedNodegroups := []string{}
+	for _, nodegroup := range nodegroups {
+		name := nodegroupName(nodegroup)
+		run.Printf("INFO: EKS node group: [ %v ]\n", name)
+		instanceSchedulingTag, overrideUntil, overrideResource, savedCapacity, skipNodegroup, skippedNodegroupsModified := parseEKSNodegroupTags(run, client, nodegroup, skippedNodegroups)
+		skippedNodegroups = skippedNodegroupsModified
+
+		if skipNodegroup {
+			continue
+		}
+
+		removeExpiredOverride(run, overrideResource, overrideUntil)
+
+		schedule := run.Schedules.getSchedule(run, instanceSchedulingTag)
+		if schedule == nil {
+			run.Printf("INFO: Skipped EKS node group because its instance-scheduling tag does not reference a schedule\n")
+			skippedNodegroups = append(skippedNodegroups, name)
+			continue
+		}
+
+		desiredState := run.desiredState(schedule)
+
+		if desiredState == scheduleStateRunning && savedCapacity != nil {
+			run.Printf("INFO: Starting EKS node group because schedule '%v' expects it to be running\n", schedule.Name)
+			if err := startEKSNodegroup(client, run, nodegroup, savedCapacity); err != nil {
+				failedNodegroups = append(failedNodegroups, name)
+				continue
+			}
+			nodegroupsStarted = append(nodegroupsStarted, name)
+			continue
+		}
+
+		if desiredState == scheduleStateStopped && savedCapacity == nil && !isNodegroupScaledToZero(nodegroup) && isOverrideActive(overrideUntil, run.Now) {
+			run.Printf("INFO: Skipped stopping EKS node group because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+			skippedNodegroups = append(skippedNodegroups, name)
+			continue
+		}
+
+		if desiredState == scheduleStateStopped && savedCapacity == nil && !isNodegroupScaledToZero(nodegroup) {
+			run.Printf("INFO: Stopping EKS node group because schedule '%v' expects it to be stopped\n", schedule.Name)
+			if err := stopEKSNodegroup(client, run, nodegroup); err != nil {
+				failedNodegroups = append(failedNodegroups, name)
+				continue
+			}
+			nodegroupsStopped = append(nodegroupsStopped, name)
+			continue
+		}
+
+		run.Printf("INFO: Skipped EKS node group because schedule '%v' expects it to be '%v'\n", schedule.Name, desiredState)
+		skippedNodegroups = append(skippedNodegroups, name)
+	}
+
+	run.Printf("INFO: Started %v EKS node groups: %v\n", len(nodegroupsStarted), nodegroupsStarted)
+	run.Printf("INFO: Stopped %v EKS node groups: %v\n", len(nodegroupsStopped), nodegroupsStopped)
+	run.Printf("INFO: Skipped %v EKS node groups due to instance-scheduling tag or schedule: %v\n", len(skippedNodegroups), skippedNodegroups)
+	run.Printf("INFO: Could not start or stop %v EKS node groups: %v\n", len(failedNodegroups), failedNodegroups)
+
+	return &EKSNodegroupCount{EKSActedUpon: len(nodegroupsStarted) + len(nodegroupsStopped), EKSSkipped: len(skippedNodegroups), EKSFailed: len(failedNodegroups)}, nil
+}
+
+// stopEKSNodegroup saves the scaling configuration of a node group in tags and then scales it to zero nodes. The
+// maximum size is kept, as EKS requires it to be at least one. The node group is left alone if its scaling
+// configuration cannot be saved, as it could not be restored afterwards, and the saved scaling configuration is
+// removed again if the node group cannot be scaled to zero, as later runs would take it to be stopped.
+func stopEKSNodegroup(client IEKSAPI, run *SchedulingRun, nodegroup EKSNodegroup) error {
+	name := nodegroupName(nodegroup)
+	scalingConfig := nodegroup.Nodegroup.ScalingConfig
+	savedCapacity := []resourceTag{
+		{key: asgMinSizeTagKey, value: strconv.Itoa(int(aws.ToInt32(scalingConfig.MinSize)))},
+		{key: asgMaxSizeTagKey, value: strconv.Itoa(int(aws.ToInt32(scalingConfig.MaxSize)))},
+		{key: asgDesiredCapacityTagKey, value: strconv.Itoa(int(aws.ToInt32(scalingConfig.DesiredSize)))},
+	}
+	err := newEKSNodegroupResource(client, nodegroup).addTags(run, append(savedCapacity, stoppedBySchedulerTags(run)...))
+	if err != nil {
+		run.Printf("ERROR: Could not save the scaling configuration of EKS node group %v, so it was not stopped: %v\n", name, err)
+		return err
+	}
+
+	_, err = client.UpdateNodegroupConfig(run.ctx(), &eks.UpdateNodegroupConfigInput{
+		ClusterName:   nodegroup.Cluster.Name,
+		NodegroupName: nodegroup.Nodegroup.NodegroupName,
+		ScalingConfig: &ekstype.NodegroupScalingConfig{
+			MinSize:     aws.Int32(0),
+			MaxSize:     scalingConfig.MaxSize,
+			DesiredSize: aws.Int32(0),
+		},
+	})
+	if err != nil {
+		run.Printf("ERROR: Could not stop EKS node group: %v\n", err)
+		removeSavedScalingConfigTags(client, run, nodegroup)
+		return err
+	}
+	run.Printf("INFO: Successfully stopped EKS node group %v\n", name)
+	return nil
+}
+
+// startEKSNodegroup restores the scaling configuration saved by stopEKSNodegroup and then removes the tags holding it
+func startEKSNodegroup(client IEKSAPI, run *SchedulingRun, nodegroup EKSNodegroup, capacity *AutoScalingGroupCapacity) error {
+	name := nodegroupName(nodegroup)
+	_, err := client.UpdateNodegroupConfig(run.ctx(), &eks.UpdateNodegroupConfigInput{
+		ClusterName:   nodegroup.Cluster.Name,
+		NodegroupName: nodegroup.Nodegroup.NodegroupName,
+		ScalingConfig: &ekstype.NodegroupScalingConfig{
+			MinSize:     aws.Int32(capacity.MinSize),
+			MaxSize:     aws.Int32(capacity.MaxSize),
+			DesiredSize: aws.Int32(capacity.DesiredCapacity),
+		},
+	})
+	if err != nil {
+		run.Printf("ERROR: Could not start EKS node group: %v\n", err)
+		return err
+	}
+	run.Printf("INFO: Successfully started EKS node group %v\n", name)
+
+	removeSavedScalingConfigTags(client, run, nodegroup)
+	return nil
+}
+
+// removeSavedScalingConfigTags removes the tags in which stopEKSNodegroup saved the scaling configuration of a node group
+func removeSavedScalingConfigTags(client IEKSAPI, run *SchedulingRun, nodegroup EKSNodegroup) {
+	err := newEKSNodegroupResource(client, nodegroup).removeTags(run, append([]string{asgMinSizeTagKey, asgMaxSizeTagKey, asgDesiredCapacityTagKey}, stoppedBySchedulerTagKeys...))
+	if err != nil {
+		run.Printf("ERROR: Could not remove the saved scaling configuration tags from EKS node group %v: %v\n", nodegroupName(nodegroup), err)
+	}
+}
+
+// eksResource adapts an EKS node group or cluster to the tag helpers shared by every service
+type eksResource struct {
+	client       IEKSAPI
+	resourceKind string
+	identifier   string
+	arn          *string
+	tagMap       map[string]string
+}
+
+func newEKSNodegroupResource(client IEKSAPI, nodegroup EKSNodegroup) eksResource {
+	return eksResource{
+		client:       client,
+		resourceKind: "EKS node group",
+		identifier:   nodegroupName(nodegroup),
+		arn:          nodegroup.Nodegroup.NodegroupArn,
+		tagMap:       nodegroup.Nodegroup.Tags,
+	}
+}
+
+func newEKSClusterResource(client IEKSAPI, nodegroup EKSNodegroup) eksResource {
+	return eksResource{
+		client:       client,
+		resourceKind: "EKS cluster",
+		identifier:   aws.ToString(nodegroup.Cluster.Name),
+		arn:          nodegroup.Cluster.Arn,
+		tagMap:       nodegroup.Cluster.Tags,
+	}
+}
+
+func (resource eksResource) kind() string {
+	return resource.resourceKind
+}
+
+func (resource eksResource) id() string {
+	return resource.identifier
+}
+
+func (resource eksResource) tags() map[string]string {
+	return resource.tagMap
+}
+
+func (resource eksResource) addTags(run *SchedulingRun, tags []resourceTag) error {
+	eksTags := map[string]string{}
+	for _, tag := range tags {
+		eksTags[tag.key] = tag.value
+	}
+	_, err := resource.client.TagResource(run.ctx(), &eks.TagResourceInput{
+		ResourceArn: resource.arn,
+		Tags:        eksTags,
+	})
+	return err
+}
+
+func (resource eksResource) removeTags(run *SchedulingRun, keys []string) error {
+	_, err := resource.client.UntagResource(run.ctx(), &eks.UntagResourceInput{
+		ResourceArn: resource.arn,
+		TagKeys:     keys,
+	})
+	return err
+}
+
+func getEKSClientForMemberAccount(session *MemberAccountSession) IEKSAPI {
+	return eks.NewFromConfig(session.Config)
+}
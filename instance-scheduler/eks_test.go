@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstype "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockIEKSAPI struct {
+	Cluster                     ekstype.Cluster
+	Nodegroups                  []ekstype.Nodegroup
+	UpdateNodegroupConfigInputs []*eks.UpdateNodegroupConfigInput
+	TagResourceInputs           []*eks.TagResourceInput
+	UntagResourceInputs         []*eks.UntagResourceInput
+	UpdateNodegroupConfigError  error
+}
+
+func (m *mockIEKSAPI) ListClusters(ctx context.Context, params *eks.ListClustersInput, optFns ...func(*eks.Options)) (*eks.ListClustersOutput, error) {
+	return &eks.ListClustersOutput{Clusters: []string{*m.Cluster.Name}}, nil
+}
+
+func (m *mockIEKSAPI) DescribeCluster(ctx context.Context, params *eks.DescribeClusterInput, optFns ...func(*eks.Options)) (*eks.DescribeClusterOutput, error) {
+	return &eks.DescribeClusterOutput{Cluster: &m.Cluster}, nil
+}
+
+func (m *mockIEKSAPI) ListNodegroups(ctx context.Context, params *eks.ListNodegroupsInput, optFns ...func(*eks.Options)) (*eks.ListNodegroupsOutput, error) {
+	output := &eks.ListNodegroupsOutput{}
+	for _, nodegroup := range m.Nodegroups {
+		output.Nodegroups = append(output.Nodegroups, *nodegroup.NodegroupName)
+	}
+	return output, nil
+}
+
+func (m *mockIEKSAPI) DescribeNodegroup(ctx context.Context, params *eks.DescribeNodegroupInput, optFns ...func(*eks.Options)) (*eks.DescribeNodegroupOutput, error) {
+	for _, nodegroup := range m.Nodegroups {
+		if *nodegroup.NodegroupName == *params.NodegroupName {
+			return &eks.DescribeNodegroupOutput{Nodegroup: &nodegroup}, nil
+		}
+	}
+	return &eks.DescribeNodegroupOutput{}, nil
+}
+
+func (m *mockIEKSAPI) UpdateNodegroupConfig(ctx context.Context, params *eks.UpdateNodegroupConfigInput, optFns ...func(*eks.Options)) (*eks.UpdateNodegroupConfigOutput, error) {
+	m.UpdateNodegroupConfigInputs = append(m.UpdateNodegroupConfigInputs, params)
+	if m.UpdateNodegroupConfigError != nil {
+		return nil, m.UpdateNodegroupConfigError
+	}
+	return &eks.UpdateNodegroupConfigOutput{}, nil
+}
+
+func (m *mockIEKSAPI) TagResource(ctx context.Context, params *eks.TagResourceInput, optFns ...func(*eks.Options)) (*eks.TagResourceOutput, error) {
+	m.TagResourceInputs = append(m.TagResourceInputs, params)
+	return &eks.TagResourceOutput{}, nil
+}
+
+func (m *mockIEKSAPI) UntagResource(ctx context.Context, params *eks.UntagResourceInput, optFns ...func(*eks.Options)) (*eks.UntagResourceOutput, error) {
+	m.UntagResourceInputs = append(m.UntagResourceInputs, params)
+	return &eks.UntagResourceOutput{}, nil
+}
+
+func testEKSCluster(tags map[string]string) ekstype.Cluster {
+	return ekstype.Cluster{
+		Name: aws.String("test-cluster"),
+		Arn:  aws.String("arn:aws:eks:eu-west-2:123456789012:cluster/test-cluster"),
+		Tags: tags,
+	}
+}
+
+func testEKSNodegroup(name string, minSize int32, maxSize int32, desiredSize int32, tags map[string]string) ekstype.Nodegroup {
+	return ekstype.Nodegroup{
+		NodegroupName: aws.String(name),
+		NodegroupArn:  aws.String("arn:aws:eks:eu-west-2:123456789012:nodegroup/test-cluster/" + name),
+		ClusterName:   aws.String("test-cluster"),
+		Status:        ekstype.NodegroupStatusActive,
+		ScalingConfig: &ekstype.NodegroupScalingConfig{
+			MinSize:     aws.Int32(minSize),
+			MaxSize:     aws.Int32(maxSize),
+			DesiredSize: aws.Int32(desiredSize),
+		},
+		Tags: tags,
+	}
+}
+
+func TestStopStartTestEKSNodegroupsInMemberAccount(t *testing.T) {
+	updating := testEKSNodegroup("upgrading", 1, 2, 1, nil)
+	updating.Status = ekstype.NodegroupStatusUpdating
+	nodegroups := []ekstype.Nodegroup{
+		// no instance-scheduling tag and running, acted upon by stop and test
+		testEKSNodegroup("general", 1, 3, 2, nil),
+		// instance-scheduling = skip-scheduling, skipped: 1
+		testEKSNodegroup("system", 1, 2, 1, map[string]string{"instance-scheduling": "skip-scheduling"}),
+		// scaled to zero by the scheduler, skipped by stop, acted upon by start and test
+		testEKSNodegroup("batch", 0, 4, 0, map[string]string{
+			"instance-scheduler:min-size":         "1",
+			"instance-scheduler:max-size":         "4",
+			"instance-scheduler:desired-capacity": "2",
+			"instance-scheduler:stopped-by":       "scheduler",
+		}),
+		// scaled to zero by someone else, skipped by stop and start, acted upon by test
+		testEKSNodegroup("spare", 0, 1, 0, nil),
+		// being updated by EKS, skipped: 1
+		updating,
+		// nights-only expects the node group to be stopped at midday and it is running, acted upon by reconcile
+		testEKSNodegroup("reports", 1, 1, 1, map[string]string{"instance-scheduling": "nights-only"}),
+	}
+
+	tests := []struct {
+		testTitle      string
+		action         string
+		expectedCount  EKSNodegroupCount
+		expectedUpdate map[string]ekstype.NodegroupScalingConfig
+	}{
+		{
+			testTitle:      "EKS testing Test action",
+			action:         "test",
+			expectedCount:  EKSNodegroupCount{EKSActedUpon: 3, EKSSkipped: 3},
+			expectedUpdate: map[string]ekstype.NodegroupScalingConfig{},
+		},
+		{
+			testTitle:     "EKS testing Stop action",
+			action:        "stop",
+			expectedCount: EKSNodegroupCount{EKSActedUpon: 1, EKSSkipped: 5},
+			expectedUpdate: map[string]ekstype.NodegroupScalingConfig{
+				"general": {MinSize: aws.Int32(0), MaxSize: aws.Int32(3), DesiredSize: aws.Int32(0)},
+			},
+		},
+		{
+			testTitle:     "EKS testing Start action",
+			action:        "start",
+			expectedCount: EKSNodegroupCount{EKSActedUpon: 1, EKSSkipped: 5},
+			expectedUpdate: map[string]ekstype.NodegroupScalingConfig{
+				"batch": {MinSize: aws.Int32(1), MaxSize: aws.Int32(4), DesiredSize: aws.Int32(2)},
+			},
+		},
+		{
+			testTitle:     "EKS testing Reconcile action",
+			action:        "reconcile",
+			expectedCount: EKSNodegroupCount{EKSActedUpon: 1, EKSSkipped: 5},
+			expectedUpdate: map[string]ekstype.NodegroupScalingConfig{
+				"reports": {MinSize: aws.Int32(0), MaxSize: aws.Int32(1), DesiredSize: aws.Int32(0)},
+			},
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.testTitle, func(t *testing.T) {
+			client := &mockIEKSAPI{Cluster: testEKSCluster(nil), Nodegroups: nodegroups}
+			actualCount, err := stopStartTestEKSNodegroupsInMemberAccount(client, &SchedulingRun{Action: subtest.action, Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+			assert.NoError(t, err)
+			assert.Equal(t, subtest.expectedCount, *actualCount)
+
+			actualUpdate := map[string]ekstype.NodegroupScalingConfig{}
+			for _, input := range client.UpdateNodegroupConfigInputs {
+				assert.Equal(t, "test-cluster", *input.ClusterName)
+				actualUpdate[*input.NodegroupName] = *input.ScalingConfig
+			}
+			assert.Equal(t, subtest.expectedUpdate, actualUpdate)
+		})
+	}
+}
+
+func TestEKSNodegroupsFollowClusterTags(t *testing.T) {
+	nodegroups := []ekstype.Nodegroup{
+		// follows the skip-auto-stop tag on the cluster, skipped: 1
+		testEKSNodegroup("general", 1, 3, 2, nil),
+		// its own tag takes precedence over the cluster's, acted upon by stop
+		testEKSNodegroup("batch", 1, 3, 2, map[string]string{"instance-scheduling": "default"}),
+	}
+	client := &mockIEKSAPI{Cluster: testEKSCluster(map[string]string{"instance-scheduling": "skip-auto-stop"}), Nodegroups: nodegroups}
+
+	actualCount, err := stopStartTestEKSNodegroupsInMemberAccount(client, &SchedulingRun{Action: "stop", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+	assert.NoError(t, err)
+
+	assert.Equal(t, EKSNodegroupCount{EKSActedUpon: 1, EKSSkipped: 1}, *actualCount)
+	assert.Len(t, client.UpdateNodegroupConfigInputs, 1)
+	assert.Equal(t, "batch", *client.UpdateNodegroupConfigInputs[0].NodegroupName)
+}
+
+func TestStopAndStartEKSNodegroup(t *testing.T) {
+	client := &mockIEKSAPI{Cluster: testEKSCluster(nil), Nodegroups: []ekstype.Nodegroup{testEKSNodegroup("general", 1, 3, 2, nil)}}
+	stopStartTestEKSNodegroupsInMemberAccount(client, &SchedulingRun{Action: "stop", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+
+	assert.Len(t, client.TagResourceInputs, 1)
+	assert.Equal(t, "arn:aws:eks:eu-west-2:123456789012:nodegroup/test-cluster/general", *client.TagResourceInputs[0].ResourceArn)
+	savedTags := client.TagResourceInputs[0].Tags
+	assert.Equal(t, map[string]string{
+		"instance-scheduler:min-size":         "1",
+		"instance-scheduler:max-size":         "3",
+		"instance-scheduler:desired-capacity": "2",
+		"instance-scheduler:stopped-by":       "scheduler",
+		"instance-scheduler:stopped-at":       "2026-10-14T12:00:00Z",
+	}, savedTags)
+
+	client = &mockIEKSAPI{Cluster: testEKSCluster(nil), Nodegroups: []ekstype.Nodegroup{testEKSNodegroup("general", 0, 3, 0, savedTags)}}
+	stopStartTestEKSNodegroupsInMemberAccount(client, &SchedulingRun{Action: "start", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+
+	assert.Len(t, client.UpdateNodegroupConfigInputs, 1)
+	assert.Equal(t, ekstype.NodegroupScalingConfig{MinSize: aws.Int32(1), MaxSize: aws.Int32(3), DesiredSize: aws.Int32(2)}, *client.UpdateNodegroupConfigInputs[0].ScalingConfig)
+	assert.Len(t, client.UntagResourceInputs, 1)
+	assert.Len(t, client.UntagResourceInputs[0].TagKeys, 5)
+}
+
+func TestStopEKSNodegroupWhenItCannotBeScaledToZero(t *testing.T) {
+	client := &mockIEKSAPI{
+		Cluster:                    testEKSCluster(nil),
+		Nodegroups:                 []ekstype.Nodegroup{testEKSNodegroup("general", 1, 3, 2, nil)},
+		UpdateNodegroupConfigError: errors.New("ResourceInUseException"),
+	}
+	actualCount, err := stopStartTestEKSNodegroupsInMemberAccount(client, &SchedulingRun{Action: "stop", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+	assert.NoError(t, err)
+
+	assert.Equal(t, EKSNodegroupCount{EKSFailed: 1}, *actualCount)
+	assert.Len(t, client.TagResourceInputs, 1)
+	assert.Len(t, client.UntagResourceInputs, 1)
+	assert.Len(t, client.UntagResourceInputs[0].TagKeys, 5)
+}
@@ -116,6 +116,19 @@ func hasInstanceSchedulerSkip(content gjson.Result) bool {
     return false
 }
 
+// hasInstanceSchedulerStartOnBankHolidays checks if the instance_scheduler_start_on_bank_holidays field exists and contains "true"
+func hasInstanceSchedulerStartOnBankHolidays(content gjson.Result) bool {
+    startOnBankHolidays := content.Get("instance_scheduler_start_on_bank_holidays")
+    if startOnBankHolidays.Exists() {
+        for _, value := range startOnBankHolidays.Array() {
+            if value.String() == "true" {
+                return true
+            }
+        }
+    }
+    return false
+}
+
 // extractNames finds all "name" elements in the "environments" array, excluding those with instance_scheduler_skip or production
 func extractNames(content JSONFileContent, envName string) []string {
     var names []string
@@ -148,4 +161,47 @@ func extractNames(content JSONFileContent, envName string) []string {
     })
 
     return names
-}
\ No newline at end of file
+}
+
+// environmentSchedulingOptions holds the scheduling options that the environments of one environments json file set
+type environmentSchedulingOptions struct {
+    // startOnBankHolidays names the environments which have instance_scheduler_start_on_bank_holidays
+    startOnBankHolidays []string
+    // regions maps the name of each environment which has instance_scheduler_regions to its regions
+    regions map[string][]string
+}
+
+// extractSchedulingOptions reads the scheduling options of all "name" elements in the "environments" array
+func extractSchedulingOptions(content JSONFileContent, envName string) environmentSchedulingOptions {
+    options := environmentSchedulingOptions{regions: make(map[string][]string)}
+    jsonData, err := json.Marshal(content)
+    if err != nil {
+        fmt.Println("Failed to marshal JSON content:", err)
+        return options
+    }
+
+    environments := gjson.GetBytes(jsonData, "environments")
+    environments.ForEach(func(_, env gjson.Result) bool {
+        name := env.Get("name").String()
+        if name == "" {
+            return true // continue
+        }
+        if hasInstanceSchedulerStartOnBankHolidays(env) {
+            fmt.Println("extractSchedulingOptions - Found name starting on bank holidays:", envName+"."+name)
+            options.startOnBankHolidays = append(options.startOnBankHolidays, name)
+        }
+        for _, region := range env.Get("instance_scheduler_regions").Array() {
+            if !isValidRegion(region.String()) {
+                fmt.Println("extractSchedulingOptions - Ignoring invalid region for name:", envName+"."+name, region.String())
+                continue
+            }
+            options.regions[name] = append(options.regions[name], region.String())
+        }
+        if len(options.regions[name]) > 0 {
+            fmt.Println("extractSchedulingOptions - Found regions for name:", envName+"."+name, options.regions[name])
+        }
+        return true // continue
+    })
+
+    return options
+}
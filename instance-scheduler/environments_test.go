@@ -170,4 +170,61 @@ func TestExtractNames(t *testing.T) {
 
     // Assert that the returned names match the expected names
     assert.Equal(t, expectedNames, names, "The extracted names should match the expected names")
-}
\ No newline at end of file
+}
+
+// Unit test for hasInstanceSchedulerStartOnBankHolidays
+func TestHasInstanceSchedulerStartOnBankHolidays(t *testing.T) {
+    testCases := []struct {
+        name     string
+        json     string
+        expected bool
+    }{
+        {
+            name:     "Start on bank holidays is true",
+            json:     `{"instance_scheduler_start_on_bank_holidays": ["true"]}`,
+            expected: true,
+        },
+        {
+            name:     "Start on bank holidays is false",
+            json:     `{"instance_scheduler_start_on_bank_holidays": ["false"]}`,
+            expected: false,
+        },
+        {
+            name:     "Start on bank holidays is missing",
+            json:     `{}`,
+            expected: false,
+        },
+    }
+
+    for _, tc := range testCases {
+        t.Run(tc.name, func(t *testing.T) {
+            result := hasInstanceSchedulerStartOnBankHolidays(gjson.Parse(tc.json))
+            assert.Equal(t, tc.expected, result)
+        })
+    }
+}
+
+// Unit test for extractSchedulingOptions
+func TestExtractSchedulingOptions(t *testing.T) {
+
+    mockJSONContent := JSONFileContent{
+        "environments": []interface{}{
+            map[string]interface{}{
+                "name": "development",
+                "instance_scheduler_start_on_bank_holidays": []interface{}{"true"},
+            },
+            map[string]interface{}{
+                "name": "preproduction",
+                "instance_scheduler_regions": []interface{}{"eu-west-2", "London", "eu-west-1"},
+            },
+            map[string]interface{}{
+                "name": "test",
+            },
+        },
+    }
+
+    options := extractSchedulingOptions(mockJSONContent, "env")
+
+    assert.Equal(t, []string{"development"}, options.startOnBankHolidays, "Only environments starting on bank holidays should be extracted")
+    assert.Equal(t, map[string][]string{"preproduction": {"eu-west-2", "eu-west-1"}}, options.regions, "Only environments with their own valid regions should be extracted")
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// Error classes reported for member accounts whose scheduling did not finish. An account lacking the role is a
+// non-member account rather than a failure, and the other classes are listed in their own bucket of the response.
+const (
+	errorClassRoleMissing      = "role_missing"
+	errorClassSCPDenied        = "scp_denied"
+	errorClassThrottled        = "throttled"
+	errorClassAccountSuspended = "account_suspended"
+	errorClassNetwork          = "network"
+	errorClassRegionNotEnabled = "region_not_enabled"
+	errorClassExpiredToken     = "expired_token"
+	errorClassDeadlineExceeded = "deadline_exceeded"
+	errorClassAWS              = "aws_api_error"
+	errorClassUnknown          = "unknown"
+)
+
+var throttlingErrorCodes = []string{
+	"Throttling",
+	"ThrottlingException",
+	"ThrottledException",
+	"RequestThrottled",
+	"RequestThrottledException",
+	"RequestLimitExceeded",
+	"TooManyRequestsException",
+}
+
+var accountSuspendedErrorCodes = []string{
+	"AccountSuspended",
+	"AccountSuspendedException",
+	"Blocked",
+}
+
+// regionNotEnabledErrorCodes are returned for a call to an opt-in region that is not enabled in the account, which
+// does not recognise the credentials of the role assumed in another region
+var regionNotEnabledErrorCodes = []string{
+	"OptInRequired",
+	"RegionDisabledException",
+	"UnrecognizedClientException",
+}
+
+var expiredTokenErrorCodes = []string{
+	"ExpiredToken",
+	"ExpiredTokenException",
+}
+
+// classifyError returns the class of an error from assuming the member account role or calling AWS with it
+func classifyError(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return errorClassDeadlineExceeded
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		code := apiErr.ErrorCode()
+		message := apiErr.ErrorMessage()
+		switch {
+		case slices.Contains(throttlingErrorCodes, code):
+			return errorClassThrottled
+		case slices.Contains(accountSuspendedErrorCodes, code):
+			return errorClassAccountSuspended
+		case slices.Contains(regionNotEnabledErrorCodes, code):
+			return errorClassRegionNotEnabled
+		case slices.Contains(expiredTokenErrorCodes, code):
+			return errorClassExpiredToken
+		case code == "AccessDenied" && strings.Contains(message, "service control policy"):
+			return errorClassSCPDenied
+		case code == "AccessDenied" && strings.Contains(message, "is not authorized to perform: sts:AssumeRole on resource"):
+			return errorClassRoleMissing
+		}
+		return errorClassAWS
+	}
+
+	var requestSendErr *smithyhttp.RequestSendError
+	var netErr net.Error
+	if errors.As(err, &requestSendErr) || errors.As(err, &netErr) {
+		return errorClassNetwork
+	}
+	return errorClassUnknown
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		title string
+		err   error
+		want  string
+	}{
+		{
+			title: "classifies a denied AssumeRole as a missing role",
+			err:   &smithy.GenericAPIError{Code: "AccessDenied", Message: "User: arn:aws:sts::123456789012:assumed-role/instance-scheduler/session is not authorized to perform: sts:AssumeRole on resource: arn:aws:iam::210987654321:role/InstanceSchedulerAccess"},
+			want:  "role_missing",
+		},
+		{
+			title: "classifies an AssumeRole denied by a service control policy",
+			err:   &smithy.GenericAPIError{Code: "AccessDenied", Message: "User: arn:aws:sts::123456789012:assumed-role/instance-scheduler/session is not authorized to perform: sts:AssumeRole on resource: arn:aws:iam::210987654321:role/InstanceSchedulerAccess with an explicit deny in a service control policy"},
+			want:  "scp_denied",
+		},
+		{
+			title: "classifies a throttled call",
+			err:   &smithy.GenericAPIError{Code: "Throttling", Message: "Rate exceeded"},
+			want:  "throttled",
+		},
+		{
+			title: "classifies an EC2 request limit as throttled",
+			err:   &smithy.GenericAPIError{Code: "RequestLimitExceeded", Message: "Request limit exceeded."},
+			want:  "throttled",
+		},
+		{
+			title: "classifies a blocked account as suspended",
+			err:   &smithy.GenericAPIError{Code: "Blocked", Message: "This account is currently blocked and not recognized as a valid account."},
+			want:  "account_suspended",
+		},
+		{
+			title: "does not classify an error as suspended by its message",
+			err:   &smithy.GenericAPIError{Code: "InvalidClientTokenId", Message: "The security token included in the request is invalid because the account is suspended."},
+			want:  "aws_api_error",
+		},
+		{
+			title: "classifies a call to an opt-in region that is not enabled",
+			err:   &smithy.GenericAPIError{Code: "OptInRequired", Message: "You are not subscribed to this service."},
+			want:  "region_not_enabled",
+		},
+		{
+			title: "classifies credentials not recognised in a disabled region",
+			err:   &smithy.GenericAPIError{Code: "UnrecognizedClientException", Message: "The security token included in the request is invalid."},
+			want:  "region_not_enabled",
+		},
+		{
+			title: "classifies STS not activated in a region",
+			err:   &smithy.GenericAPIError{Code: "RegionDisabledException", Message: "STS is not activated in this region for account:123456789012."},
+			want:  "region_not_enabled",
+		},
+		{
+			title: "classifies an expired security token",
+			err:   &smithy.GenericAPIError{Code: "ExpiredToken", Message: "The security token included in the request is expired"},
+			want:  "expired_token",
+		},
+		{
+			title: "classifies a request that could not be sent as a network error",
+			err:   &smithyhttp.RequestSendError{Err: errors.New("dial tcp: lookup sts.eu-west-2.amazonaws.com: no such host")},
+			want:  "network",
+		},
+		{
+			title: "classifies a network timeout as a network error",
+			err:   fmt.Errorf("get credentials: %w", &net.OpError{Op: "dial", Err: errors.New("i/o timeout")}),
+			want:  "network",
+		},
+		{
+			title: "classifies a cancelled request as deadline exceeded",
+			err:   &smithyhttp.RequestSendError{Err: context.DeadlineExceeded},
+			want:  "deadline_exceeded",
+		},
+		{
+			title: "classifies a wrapped AWS error by its code",
+			err:   fmt.Errorf("failed to describe EC2 instances in account test-account-development: %w", &smithy.GenericAPIError{Code: "Throttling"}),
+			want:  "throttled",
+		},
+		{
+			title: "classifies any other AWS error as an AWS API error",
+			err:   &smithy.GenericAPIError{Code: "ValidationError", Message: "1 validation error detected"},
+			want:  "aws_api_error",
+		},
+		{
+			title: "classifies any other error as unknown",
+			err:   errors.New("unexpected"),
+			want:  "unknown",
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.title, func(t *testing.T) {
+			assert.Equal(t, subtest.want, classifyError(subtest.err))
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Non-production resources are not started on UK bank holidays. The calendar is bundled with the lambda in the
+// format published at https://www.gov.uk/bank-holidays.json and should be refreshed from there once a year.
+
+//go:embed bank-holidays.json
+var bankHolidaysJSON []byte
+
+const (
+	bankHolidayDivisionEnglandAndWales string = "england-and-wales"
+	bankHolidayDivisionScotland        string = "scotland"
+	bankHolidayDivisionNorthernIreland string = "northern-ireland"
+)
+
+// Bank holidays are decided on the date in the UK, whatever time zone the lambda runs in
+const bankHolidayTimeZone string = "Europe/London"
+
+type BankHolidayEvent struct {
+	Title   string `json:"title"`
+	Date    string `json:"date"`
+	Notes   string `json:"notes"`
+	Bunting bool   `json:"bunting"`
+}
+
+type BankHolidayDivision struct {
+	Division string             `json:"division"`
+	Events   []BankHolidayEvent `json:"events"`
+}
+
+// BankHolidayCalendar maps each division, e.g. england-and-wales, to its bank holidays
+type BankHolidayCalendar map[string]BankHolidayDivision
+
+func loadBankHolidays() (BankHolidayCalendar, error) {
+	return parseBankHolidays(bankHolidaysJSON)
+}
+
+func parseBankHolidays(data []byte) (BankHolidayCalendar, error) {
+	var calendar BankHolidayCalendar
+	if err := json.Unmarshal(data, &calendar); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal bank holidays: %w", err)
+	}
+	for name, division := range calendar {
+		for _, event := range division.Events {
+			if _, err := time.Parse(time.DateOnly, event.Date); err != nil {
+				return nil, fmt.Errorf("invalid date '%v' for bank holiday '%v' in division %v: %w", event.Date, event.Title, name, err)
+			}
+		}
+	}
+	return calendar, nil
+}
+
+// bankHoliday returns the name of the bank holiday in the division on the UK date of now, or an empty string
+func (calendar BankHolidayCalendar) bankHoliday(division string, now time.Time) (string, error) {
+	events, ok := calendar[division]
+	if !ok {
+		return "", fmt.Errorf("unknown bank holiday division '%v', must be one of '%v' '%v' '%v'", division, bankHolidayDivisionEnglandAndWales, bankHolidayDivisionScotland, bankHolidayDivisionNorthernIreland)
+	}
+	date := now.In(mustLoadLocation(bankHolidayTimeZone)).Format(time.DateOnly)
+	for _, event := range events.Events {
+		if event.Date == date {
+			return event.Title, nil
+		}
+	}
+	return "", nil
+}
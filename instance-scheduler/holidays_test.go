@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadBankHolidays(t *testing.T) {
+	calendar, err := loadBankHolidays()
+	assert.Nil(t, err)
+	assert.Contains(t, calendar, bankHolidayDivisionEnglandAndWales)
+	assert.Contains(t, calendar, bankHolidayDivisionScotland)
+	assert.Contains(t, calendar, bankHolidayDivisionNorthernIreland)
+}
+
+func TestParseBankHolidays(t *testing.T) {
+	_, err := parseBankHolidays([]byte(`{"england-and-wales": {"division": "england-and-wales", "events": [{"title": "Christmas Day", "date": "25/12/2026"}]}}`))
+	assert.NotNil(t, err)
+
+	_, err = parseBankHolidays([]byte(`not json`))
+	assert.NotNil(t, err)
+}
+
+func TestBankHoliday(t *testing.T) {
+	calendar, _ := loadBankHolidays()
+	tests := []struct {
+		title       string
+		division    string
+		now         time.Time
+		want        string
+		expectError bool
+	}{
+		{
+			title:    "returns the bank holiday in England and Wales",
+			division: bankHolidayDivisionEnglandAndWales,
+			now:      time.Date(2026, time.December, 28, 7, 0, 0, 0, time.UTC),
+			want:     "Boxing Day",
+		},
+		{
+			title:    "returns an empty string on a working day",
+			division: bankHolidayDivisionEnglandAndWales,
+			now:      time.Date(2026, time.October, 14, 7, 0, 0, 0, time.UTC),
+			want:     "",
+		},
+		{
+			title:    "uses the UK date rather than the UTC date",
+			division: bankHolidayDivisionEnglandAndWales,
+			now:      time.Date(2026, time.August, 30, 23, 30, 0, 0, time.UTC), // 00:30 BST on 31 August
+			want:     "Summer bank holiday",
+		},
+		{
+			title:    "returns bank holidays which only apply in Scotland",
+			division: bankHolidayDivisionScotland,
+			now:      time.Date(2026, time.November, 30, 7, 0, 0, 0, time.UTC),
+			want:     "St Andrew’s Day",
+		},
+		{
+			title:    "does not return bank holidays of other divisions",
+			division: bankHolidayDivisionEnglandAndWales,
+			now:      time.Date(2026, time.November, 30, 7, 0, 0, 0, time.UTC),
+			want:     "",
+		},
+		{
+			title:    "returns bank holidays which only apply in Northern Ireland",
+			division: bankHolidayDivisionNorthernIreland,
+			now:      time.Date(2026, time.March, 17, 7, 0, 0, 0, time.UTC),
+			want:     "St Patrick’s Day",
+		},
+		{
+			title:       "returns an error for an unknown division",
+			division:    "wales",
+			now:         time.Date(2026, time.December, 25, 7, 0, 0, 0, time.UTC),
+			want:        "",
+			expectError: true,
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.title, func(t *testing.T) {
+			got, err := calendar.bankHoliday(subtest.division, subtest.now)
+			assert.Equal(t, subtest.want, got)
+			if subtest.expectError {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
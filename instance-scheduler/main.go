@@ -1,8 +1,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -15,41 +22,288 @@ type InstanceSchedulingRequest struct {
 	Action string `json:"action"`
 }
 
+// SchedulingRun holds what every member account needs to know about the current invocation
+type SchedulingRun struct {
+	Action                  string
+	Now                     time.Time
+	BankHoliday             string
+	StartOnBankHolidays     bool
+	Schedules               ScheduleCatalogue
+	RemoveExpiredOverrides  bool
+	DeleteIdleSageMakerApps bool
+	PageSize                int32
+	Regions                 []string
+	MemberAccountRole       MemberAccountRole
+	Logger                  *log.Logger
+	Context                 context.Context
+}
+
+// ctx returns the context of the AWS calls made for the run, which is cancelled shortly before the Lambda times out
+func (run *SchedulingRun) ctx() context.Context {
+	if run.Context == nil {
+		return context.Background()
+	}
+	return run.Context
+}
+
+// logger returns the logger for the member account being scheduled, which prefixes each line with the account name,
+// or the standard logger outside of one
+func (run *SchedulingRun) logger() *log.Logger {
+	if run.Logger == nil {
+		return log.Default()
+	}
+	return run.Logger
+}
+
+// isStartSkippedForBankHoliday reports whether resources stay stopped because today is a bank holiday and the member
+// account has not opted out with StartOnBankHolidays
+func (run *SchedulingRun) isStartSkippedForBankHoliday() bool {
+	return run.BankHoliday != "" && !run.StartOnBankHolidays
+}
+
+// desiredState returns the state the schedule expects at the time of the run. A schedule expecting resources to be
+// running does not start them on a bank holiday, so they are left as they are, while stopping them is still allowed.
+func (run *SchedulingRun) desiredState(schedule *Schedule) string {
+	desiredState := schedule.desiredState(run.Now)
+	if desiredState == scheduleStateRunning && run.isStartSkippedForBankHoliday() {
+		run.Printf("INFO: Schedule '%v' expects it to be running, but it is not started on a bank holiday: %v\n", schedule.Name, run.BankHoliday)
+		return ""
+	}
+	return desiredState
+}
+
+func (run *SchedulingRun) Printf(format string, v ...any) {
+	run.logger().Output(2, fmt.Sprintf(format, v...))
+}
+
+func (run *SchedulingRun) Println(v ...any) {
+	run.logger().Output(2, fmt.Sprintln(v...))
+}
+
+func (run *SchedulingRun) Print(v ...any) {
+	run.logger().Output(2, fmt.Sprint(v...))
+}
+
 type InstanceSchedulingResponse struct {
-	Action                string   `json:"action"`
-	MemberAccountNames    []string `json:"member_account_names"`
-	NonMemberAccountNames []string `json:"non_member_account_names"`
-	ActedUpon             int      `json:"acted_upon"`
-	Skipped               int      `json:"skipped"`
-	SkippedAutoScaled     int      `json:"skipped_auto_scaled"`
-	RDSActedUpon          int      `json:"rds_acted_upon"`
-	RDSSkipped            int      `json:"rds_skipped"`
+	Action                           string          `json:"action"`
+	MemberAccountNames               []string        `json:"member_account_names"`
+	NonMemberAccountNames            []string        `json:"non_member_account_names"`
+	CompletedAccountNames            []string        `json:"completed_account_names"`
+	PendingAccountNames              []string        `json:"pending_account_names"`
+	FailedAccounts                   []FailedAccount `json:"failed_accounts"`
+	SCPDeniedAccountNames            []string        `json:"scp_denied_account_names"`
+	ThrottledAccountNames            []string        `json:"throttled_account_names"`
+	SuspendedAccountNames            []string        `json:"suspended_account_names"`
+	NetworkErrorAccountNames         []string        `json:"network_error_account_names"`
+	ActedUpon                        int             `json:"acted_upon"`
+	Skipped                          int             `json:"skipped"`
+	SkippedAutoScaled                int             `json:"skipped_auto_scaled"`
+	RDSActedUpon                     int             `json:"rds_acted_upon"`
+	RDSSkipped                       int             `json:"rds_skipped"`
+	StoppedNotByScheduler            int             `json:"stopped_not_by_scheduler"`
+	Hibernated                       int             `json:"hibernated"`
+	AlreadyInDesiredState            int             `json:"already_in_desired_state"`
+	NotActionable                    int             `json:"not_actionable"`
+	Failed                           int             `json:"failed"`
+	RDSStoppedNotByScheduler         int             `json:"rds_stopped_not_by_scheduler"`
+	RDSAutoRestartedRestopped        int             `json:"rds_auto_restarted_restopped"`
+	RDSAlreadyInDesiredState         int             `json:"rds_already_in_desired_state"`
+	RDSNotActionable                 int             `json:"rds_not_actionable"`
+	RDSFailed                        int             `json:"rds_failed"`
+	RDSClustersActedUpon             int             `json:"rds_clusters_acted_upon"`
+	RDSClustersSkipped               int             `json:"rds_clusters_skipped"`
+	DocDBClustersActedUpon           int             `json:"docdb_clusters_acted_upon"`
+	DocDBClustersSkipped             int             `json:"docdb_clusters_skipped"`
+	NeptuneClustersActedUpon         int             `json:"neptune_clusters_acted_upon"`
+	NeptuneClustersSkipped           int             `json:"neptune_clusters_skipped"`
+	DBClustersAlreadyInDesiredState  int             `json:"db_clusters_already_in_desired_state"`
+	DBClustersStoppedNotByScheduler  int             `json:"db_clusters_stopped_not_by_scheduler"`
+	DBClustersNotActionable          int             `json:"db_clusters_not_actionable"`
+	DBClustersAutoRestartedRestopped int             `json:"db_clusters_auto_restarted_restopped"`
+	DBClustersFailed                 int             `json:"db_clusters_failed"`
+	ASGActedUpon                     int             `json:"asg_acted_upon"`
+	ASGSkipped                       int             `json:"asg_skipped"`
+	ASGFailed                        int             `json:"asg_failed"`
+	ECSActedUpon                     int             `json:"ecs_acted_upon"`
+	ECSSkipped                       int             `json:"ecs_skipped"`
+	EKSActedUpon                     int             `json:"eks_acted_upon"`
+	EKSSkipped                       int             `json:"eks_skipped"`
+	EKSFailed                        int             `json:"eks_failed"`
+	RedshiftActedUpon                int             `json:"redshift_acted_upon"`
+	RedshiftSkipped                  int             `json:"redshift_skipped"`
+	RedshiftAlreadyInDesiredState    int             `json:"redshift_already_in_desired_state"`
+	RedshiftNotActionable            int             `json:"redshift_not_actionable"`
+	SageMakerActedUpon               int             `json:"sagemaker_acted_upon"`
+	SageMakerSkipped                 int             `json:"sagemaker_skipped"`
+	SageMakerAppsDeleted             int             `json:"sagemaker_apps_deleted"`
+	SageMakerFailed                  int             `json:"sagemaker_failed"`
+	SageMakerStoppedNotByScheduler   int             `json:"sagemaker_stopped_not_by_scheduler"`
+	SkippedHoliday                   []string        `json:"skipped_holiday"`
+	InvalidSchedules                 []string        `json:"invalid_schedules,omitempty"`
+	Regions                          RegionResponses `json:"regions"`
+}
+
+// RegionResponses holds the response for each region by its name
+type RegionResponses map[string]*RegionResponse
+
+// RegionResponse sums the resources acted upon and skipped in one region across the member accounts scheduled in it,
+// and lists the accounts which could not be scheduled in the region
+type RegionResponse struct {
+	AccountNames   []string        `json:"account_names"`
+	ActedUpon      int             `json:"acted_upon"`
+	Skipped        int             `json:"skipped"`
+	FailedAccounts []FailedAccount `json:"failed_accounts,omitempty"`
+}
+
+// FailedAccount is a member account whose scheduling did not finish, with the class of the error that stopped it
+type FailedAccount struct {
+	AccountName string `json:"account_name"`
+	ErrorClass  string `json:"error_class"`
+	Message     string `json:"message"`
+}
+
+// newFailedAccount classifies the error that stopped the scheduling of the account
+func newFailedAccount(accName string, err error) FailedAccount {
+	return FailedAccount{AccountName: accName, ErrorClass: classifyError(err), Message: err.Error()}
+}
+
+// addFailedAccount lists the account as failed, and in the bucket for the class of its error when it has one
+func (response *InstanceSchedulingResponse) addFailedAccount(accName string, err error) {
+	failedAccount := newFailedAccount(accName, err)
+	response.FailedAccounts = append(response.FailedAccounts, failedAccount)
+	switch failedAccount.ErrorClass {
+	case errorClassSCPDenied:
+		response.SCPDeniedAccountNames = append(response.SCPDeniedAccountNames, accName)
+	case errorClassThrottled:
+		response.ThrottledAccountNames = append(response.ThrottledAccountNames, accName)
+	case errorClassAccountSuspended:
+		response.SuspendedAccountNames = append(response.SuspendedAccountNames, accName)
+	case errorClassNetwork:
+		response.NetworkErrorAccountNames = append(response.NetworkErrorAccountNames, accName)
+	}
+}
+
+// add merges the response for one member account into the response for the run
+func (response *InstanceSchedulingResponse) add(accountResponse *InstanceSchedulingResponse) {
+	response.MemberAccountNames = append(response.MemberAccountNames, accountResponse.MemberAccountNames...)
+	response.NonMemberAccountNames = append(response.NonMemberAccountNames, accountResponse.NonMemberAccountNames...)
+	response.CompletedAccountNames = append(response.CompletedAccountNames, accountResponse.CompletedAccountNames...)
+	response.PendingAccountNames = append(response.PendingAccountNames, accountResponse.PendingAccountNames...)
+	response.FailedAccounts = append(response.FailedAccounts, accountResponse.FailedAccounts...)
+	response.SCPDeniedAccountNames = append(response.SCPDeniedAccountNames, accountResponse.SCPDeniedAccountNames...)
+	response.ThrottledAccountNames = append(response.ThrottledAccountNames, accountResponse.ThrottledAccountNames...)
+	response.SuspendedAccountNames = append(response.SuspendedAccountNames, accountResponse.SuspendedAccountNames...)
+	response.NetworkErrorAccountNames = append(response.NetworkErrorAccountNames, accountResponse.NetworkErrorAccountNames...)
+	response.SkippedHoliday = append(response.SkippedHoliday, accountResponse.SkippedHoliday...)
+	response.ActedUpon += accountResponse.ActedUpon
+	response.Skipped += accountResponse.Skipped
+	response.SkippedAutoScaled += accountResponse.SkippedAutoScaled
+	response.StoppedNotByScheduler += accountResponse.StoppedNotByScheduler
+	response.Hibernated += accountResponse.Hibernated
+	response.AlreadyInDesiredState += accountResponse.AlreadyInDesiredState
+	response.NotActionable += accountResponse.NotActionable
+	response.Failed += accountResponse.Failed
+	response.RDSActedUpon += accountResponse.RDSActedUpon
+	response.RDSSkipped += accountResponse.RDSSkipped
+	response.RDSStoppedNotByScheduler += accountResponse.RDSStoppedNotByScheduler
+	response.RDSAutoRestartedRestopped += accountResponse.RDSAutoRestartedRestopped
+	response.RDSAlreadyInDesiredState += accountResponse.RDSAlreadyInDesiredState
+	response.RDSNotActionable += accountResponse.RDSNotActionable
+	response.RDSFailed += accountResponse.RDSFailed
+	response.RDSClustersActedUpon += accountResponse.RDSClustersActedUpon
+	response.RDSClustersSkipped += accountResponse.RDSClustersSkipped
+	response.DocDBClustersActedUpon += accountResponse.DocDBClustersActedUpon
+	response.DocDBClustersSkipped += accountResponse.DocDBClustersSkipped
+	response.NeptuneClustersActedUpon += accountResponse.NeptuneClustersActedUpon
+	response.NeptuneClustersSkipped += accountResponse.NeptuneClustersSkipped
+	response.DBClustersAlreadyInDesiredState += accountResponse.DBClustersAlreadyInDesiredState
+	response.DBClustersStoppedNotByScheduler += accountResponse.DBClustersStoppedNotByScheduler
+	response.DBClustersNotActionable += accountResponse.DBClustersNotActionable
+	response.DBClustersAutoRestartedRestopped += accountResponse.DBClustersAutoRestartedRestopped
+	response.DBClustersFailed += accountResponse.DBClustersFailed
+	response.ASGActedUpon += accountResponse.ASGActedUpon
+	response.ASGSkipped += accountResponse.ASGSkipped
+	response.ASGFailed += accountResponse.ASGFailed
+	response.ECSActedUpon += accountResponse.ECSActedUpon
+	response.ECSSkipped += accountResponse.ECSSkipped
+	response.EKSActedUpon += accountResponse.EKSActedUpon
+	response.EKSSkipped += accountResponse.EKSSkipped
+	response.EKSFailed += accountResponse.EKSFailed
+	response.RedshiftActedUpon += accountResponse.RedshiftActedUpon
+	response.RedshiftSkipped += accountResponse.RedshiftSkipped
+	response.RedshiftAlreadyInDesiredState += accountResponse.RedshiftAlreadyInDesiredState
+	response.RedshiftNotActionable += accountResponse.RedshiftNotActionable
+	response.SageMakerActedUpon += accountResponse.SageMakerActedUpon
+	response.SageMakerSkipped += accountResponse.SageMakerSkipped
+	response.SageMakerAppsDeleted += accountResponse.SageMakerAppsDeleted
+	response.SageMakerFailed += accountResponse.SageMakerFailed
+	response.SageMakerStoppedNotByScheduler += accountResponse.SageMakerStoppedNotByScheduler
+	for region, accountRegionResponse := range accountResponse.Regions {
+		if response.Regions == nil {
+			response.Regions = RegionResponses{}
+		}
+		regionResponse, ok := response.Regions[region]
+		if !ok {
+			regionResponse = &RegionResponse{AccountNames: []string{}}
+			response.Regions[region] = regionResponse
+		}
+		regionResponse.AccountNames = append(regionResponse.AccountNames, accountRegionResponse.AccountNames...)
+		regionResponse.ActedUpon += accountRegionResponse.ActedUpon
+		regionResponse.Skipped += accountRegionResponse.Skipped
+		regionResponse.FailedAccounts = append(regionResponse.FailedAccounts, accountRegionResponse.FailedAccounts...)
+	}
 }
 
 type InstanceScheduler struct {
-	LoadDefaultConfig                        func() (aws.Config, error)
-	CreateSSMClient                          func(aws.Config) ISSMGetParameter
-	GetParameter                             func(client ISSMGetParameter, parameterName string) string
-	CreateSecretManagerClient                func(cfg aws.Config) ISecretManagerGetSecretValue
-	GetSecret                                func(client ISecretManagerGetSecretValue, secretId string) string
-	GetEc2ClientForMemberAccount             func(cfg aws.Config, accountName string, accountId string) IEC2InstancesAPI
-	GetRDSClientForMemberAccount             func(cfg aws.Config, accountName string, accountId string) IRDSInstancesAPI
-	StopStartTestInstancesInMemberAccount    func(client IEC2InstancesAPI, action string) *InstanceCount
-	StopStartTestRDSInstancesInMemberAccount func(RDSClient IRDSInstancesAPI, action string) *RDSInstanceCount
-}
-
-func (instanceScheduler *InstanceScheduler) handler(request InstanceSchedulingRequest) (events.APIGatewayProxyResponse, error) {
+	Now                                           func() time.Time
+	LoadDefaultConfig                             func(ctx context.Context) (aws.Config, error)
+	LoadBankHolidays                              func() (BankHolidayCalendar, error)
+	CreateSSMClient                               func(aws.Config) ISSMGetParameter
+	GetParameter                                  func(ctx context.Context, client ISSMGetParameter, parameterName string) (string, error)
+	LoadScheduleDocument                          func(ctx context.Context, client ISSMGetParameter) (string, error)
+	CreateSecretManagerClient                     func(cfg aws.Config) ISecretManagerGetSecretValue
+	GetSecret                                     func(ctx context.Context, client ISecretManagerGetSecretValue, secretId string) (string, error)
+	GetNonProductionAccounts                      func(environments string) (map[string]NonProductionAccount, error)
+	CreateMemberAccountSession                    func(run *SchedulingRun, cfg aws.Config, accountName string, accountId string) (*MemberAccountSession, error)
+	GetEc2ClientForMemberAccount                  func(session *MemberAccountSession) IEC2InstancesAPI
+	GetRDSClientForMemberAccount                  func(session *MemberAccountSession) IRDSInstancesAPI
+	GetRDSClusterClientForMemberAccount           func(session *MemberAccountSession) IRDSClustersAPI
+	GetAutoScalingClientForMemberAccount          func(session *MemberAccountSession) IAutoScalingAPI
+	StopStartTestInstancesInMemberAccount         func(client IEC2InstancesAPI, run *SchedulingRun) (*InstanceCount, error)
+	StopStartTestRDSInstancesInMemberAccount      func(RDSClient IRDSInstancesAPI, run *SchedulingRun) (*RDSInstanceCount, error)
+	StopStartTestRDSClustersInMemberAccount       func(RDSClient IRDSClustersAPI, run *SchedulingRun) (*RDSClusterCount, error)
+	StopStartTestAutoScalingGroupsInMemberAccount func(client IAutoScalingAPI, run *SchedulingRun) (*AutoScalingGroupCount, error)
+	GetECSClientForMemberAccount                  func(session *MemberAccountSession) IECSAPI
+	StopStartTestECSServicesInMemberAccount       func(client IECSAPI, run *SchedulingRun) (*ECSServiceCount, error)
+	GetEKSClientForMemberAccount                  func(session *MemberAccountSession) IEKSAPI
+	StopStartTestEKSNodegroupsInMemberAccount     func(client IEKSAPI, run *SchedulingRun) (*EKSNodegroupCount, error)
+	GetRedshiftClientForMemberAccount             func(session *MemberAccountSession) IRedshiftAPI
+	StopStartTestRedshiftClustersInMemberAccount  func(client IRedshiftAPI, run *SchedulingRun) (*RedshiftClusterCount, error)
+	GetSageMakerClientForMemberAccount            func(session *MemberAccountSession) ISageMakerAPI
+	StopStartTestSageMakerInMemberAccount         func(client ISageMakerAPI, run *SchedulingRun) (*SageMakerCount, error)
+}
+
+func (instanceScheduler *InstanceScheduler) handler(ctx context.Context, request InstanceSchedulingRequest) (events.APIGatewayProxyResponse, error) {
 	log.Printf("INFO: Starting Instance Scheduling...")
 
 	instanceSchedulingResponse := &InstanceSchedulingResponse{
-		Action:                request.Action,
-		MemberAccountNames:    []string{},
-		NonMemberAccountNames: []string{},
-		ActedUpon:             0,
-		Skipped:               0,
-		SkippedAutoScaled:     0,
-		RDSActedUpon:          0,
-		RDSSkipped:            0,
+		Action:                   request.Action,
+		MemberAccountNames:       []string{},
+		NonMemberAccountNames:    []string{},
+		CompletedAccountNames:    []string{},
+		PendingAccountNames:      []string{},
+		FailedAccounts:           []FailedAccount{},
+		SCPDeniedAccountNames:    []string{},
+		ThrottledAccountNames:    []string{},
+		SuspendedAccountNames:    []string{},
+		NetworkErrorAccountNames: []string{},
+		ActedUpon:                0,
+		Skipped:                  0,
+		SkippedAutoScaled:        0,
+		RDSActedUpon:             0,
+		RDSSkipped:               0,
+		SkippedHoliday:           []string{},
+		Regions:                  RegionResponses{},
 	}
 
 	action, err := parseAction(request.Action)
@@ -61,7 +315,7 @@ func (instanceScheduler *InstanceScheduler) handler(request InstanceSchedulingRe
 		}, err
 	}
 
-	cfg, err := instanceScheduler.LoadDefaultConfig()
+	cfg, err := instanceScheduler.LoadDefaultConfig(ctx)
 	if err != nil {
 		body, _ := json.Marshal(instanceSchedulingResponse)
 		return events.APIGatewayProxyResponse{
@@ -70,58 +324,403 @@ func (instanceScheduler *InstanceScheduler) handler(request InstanceSchedulingRe
 		}, err
 	}
 
+	run := &SchedulingRun{
+		Action:                  action,
+		Now:                     instanceScheduler.Now(),
+		RemoveExpiredOverrides:  getEnv("INSTANCE_SCHEDULING_REMOVE_EXPIRED_OVERRIDES", "false") == "true",
+		DeleteIdleSageMakerApps: getEnv("INSTANCE_SCHEDULING_DELETE_IDLE_SAGEMAKER_APPS", "false") == "true",
+		PageSize:                parsePageSize(getEnv("INSTANCE_SCHEDULING_PAGE_SIZE", "")),
+		MemberAccountRole:       parseMemberAccountRole(action),
+		Regions:                 parseRegions(getEnv("INSTANCE_SCHEDULING_REGIONS", "")),
+		Context:                 ctx,
+	}
+	deadlineMargin := parseDeadlineMargin(getEnv("INSTANCE_SCHEDULING_DEADLINE_MARGIN", ""))
+	if deadline, ok := ctx.Deadline(); ok {
+		// Leave time to return the response when the AWS calls of an account still in progress are cancelled
+		var cancel context.CancelFunc
+		run.Context, cancel = context.WithDeadline(ctx, deadline.Add(-responseTimeReserve))
+		defer cancel()
+	}
+	log.Printf("INFO: Scheduling time is %v\n", run.Now.Format(time.RFC3339))
+	log.Printf("INFO: Scheduling regions %v unless overridden for an account\n", run.Regions)
+	log.Printf("INFO: Assuming role %v%v in member accounts with session name %v\n", run.MemberAccountRole.Path, run.MemberAccountRole.Name, run.MemberAccountRole.SessionName)
+
+	bankHolidays, err := instanceScheduler.LoadBankHolidays()
+	if err != nil {
+		body, _ := json.Marshal(instanceSchedulingResponse)
+		return events.APIGatewayProxyResponse{
+			Body:       string(body),
+			StatusCode: 500,
+		}, err
+	}
+
+	bankHolidayDivision := getEnv("INSTANCE_SCHEDULING_BANK_HOLIDAY_DIVISION", bankHolidayDivisionEnglandAndWales)
+	run.BankHoliday, err = bankHolidays.bankHoliday(bankHolidayDivision, run.Now)
+	if err != nil {
+		body, _ := json.Marshal(instanceSchedulingResponse)
+		return events.APIGatewayProxyResponse{
+			Body:       string(body),
+			StatusCode: 500,
+		}, err
+	}
+	if run.BankHoliday != "" {
+		log.Printf("INFO: Today is a bank holiday in %v: %v\n", bankHolidayDivision, run.BankHoliday)
+	}
+
 	// skipAccounts := instanceScheduler.GetEnv("INSTANCE_SCHEDULING_SKIP_ACCOUNTS")
 	// log.Printf("INSTANCE_SCHEDULING_SKIP_ACCOUNTS=%v\n", skipAccounts)
 
 	ssmClient := instanceScheduler.CreateSSMClient(cfg)
-	secretId := instanceScheduler.GetParameter(ssmClient, "environment_management_arn")
 
-	secretsManagerClient := instanceScheduler.CreateSecretManagerClient(cfg)
-	environments := instanceScheduler.GetSecret(secretsManagerClient, secretId)
-
-	accounts := getNonProductionAccounts(environments)
-	for accName, accId := range accounts {
-		ec2Client := instanceScheduler.GetEc2ClientForMemberAccount(cfg, accName, accId)
-		rdsClient := instanceScheduler.GetRDSClientForMemberAccount(cfg, accName, accId)
-
-		if ec2Client == nil || rdsClient == nil {
-			instanceSchedulingResponse.NonMemberAccountNames = append(instanceSchedulingResponse.NonMemberAccountNames, accName)
-		} else {
-			instanceSchedulingResponse.MemberAccountNames = append(instanceSchedulingResponse.MemberAccountNames, accName)
-			log.Printf("INFO: Instance scheduling for member account: accountName=%v\n", accName)
-
-			count := instanceScheduler.StopStartTestInstancesInMemberAccount(ec2Client, action)
-			instanceSchedulingResponse.ActedUpon += count.actedUpon
-			instanceSchedulingResponse.Skipped += count.skipped
-			instanceSchedulingResponse.SkippedAutoScaled += count.skippedAutoScaled
-
-			rdsCount := instanceScheduler.StopStartTestRDSInstancesInMemberAccount(rdsClient, action)
-			instanceSchedulingResponse.RDSActedUpon += rdsCount.RDSActedUpon
-			instanceSchedulingResponse.RDSSkipped += rdsCount.RDSSkipped
+	scheduleDocument, err := instanceScheduler.LoadScheduleDocument(run.ctx(), ssmClient)
+	if err != nil {
+		body, _ := json.Marshal(instanceSchedulingResponse)
+		return events.APIGatewayProxyResponse{
+			Body:       string(body),
+			StatusCode: 500,
+		}, err
+	}
+
+	run.Schedules, err = parseScheduleCatalogue(scheduleDocument)
+	if err != nil {
+		log.Printf("%v\n", err)
+		var invalidScheduleCatalogueError *InvalidScheduleCatalogueError
+		if errors.As(err, &invalidScheduleCatalogueError) {
+			instanceSchedulingResponse.InvalidSchedules = invalidScheduleCatalogueError.InvalidSchedules
 		}
+		// The invalid schedules are reported in the body, which the lambda runtime drops when an error is returned
+		body, _ := json.Marshal(instanceSchedulingResponse)
+		return events.APIGatewayProxyResponse{
+			Body:       string(body),
+			StatusCode: 400,
+		}, nil
+	}
+	log.Printf("INFO: Loaded %v schedules\n", len(run.Schedules))
+
+	secretId, err := instanceScheduler.GetParameter(run.ctx(), ssmClient, "environment_management_arn")
+	if err != nil {
+		body, _ := json.Marshal(instanceSchedulingResponse)
+		return events.APIGatewayProxyResponse{
+			Body:       string(body),
+			StatusCode: 500,
+		}, err
+	}
+
+	secretsManagerClient := instanceScheduler.CreateSecretManagerClient(cfg)
+	environments, err := instanceScheduler.GetSecret(run.ctx(), secretsManagerClient, secretId)
+	if err != nil {
+		body, _ := json.Marshal(instanceSchedulingResponse)
+		return events.APIGatewayProxyResponse{
+			Body:       string(body),
+			StatusCode: 500,
+		}, err
+	}
+
+	accounts, err := instanceScheduler.GetNonProductionAccounts(environments)
+	if err != nil {
+		body, _ := json.Marshal(instanceSchedulingResponse)
+		return events.APIGatewayProxyResponse{
+			Body:       string(body),
+			StatusCode: 500,
+		}, err
+	}
+	concurrency := parseConcurrency(getEnv("INSTANCE_SCHEDULING_CONCURRENCY", ""))
+	log.Printf("INFO: Scheduling %v accounts, %v at a time\n", len(accounts), concurrency)
+
+	accountNames := make(chan string)
+	var responseMutex sync.Mutex
+	var workers sync.WaitGroup
+	for range concurrency {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for accName := range accountNames {
+				var accountResponse *InstanceSchedulingResponse
+				if hasTimeRemaining(ctx, deadlineMargin) {
+					accountResponse = instanceScheduler.scheduleMemberAccount(cfg, run, accName, accounts[accName])
+				} else {
+					accountResponse = &InstanceSchedulingResponse{PendingAccountNames: []string{accName}}
+				}
+				responseMutex.Lock()
+				instanceSchedulingResponse.add(accountResponse)
+				responseMutex.Unlock()
+			}
+		}()
+	}
+	for accName := range accounts {
+		accountNames <- accName
+	}
+	close(accountNames)
+	workers.Wait()
+
+	slices.Sort(instanceSchedulingResponse.MemberAccountNames)
+	slices.Sort(instanceSchedulingResponse.NonMemberAccountNames)
+	slices.Sort(instanceSchedulingResponse.CompletedAccountNames)
+	slices.Sort(instanceSchedulingResponse.PendingAccountNames)
+	slices.SortFunc(instanceSchedulingResponse.FailedAccounts, func(a, b FailedAccount) int {
+		return strings.Compare(a.AccountName, b.AccountName)
+	})
+	slices.Sort(instanceSchedulingResponse.SCPDeniedAccountNames)
+	slices.Sort(instanceSchedulingResponse.ThrottledAccountNames)
+	slices.Sort(instanceSchedulingResponse.SuspendedAccountNames)
+	slices.Sort(instanceSchedulingResponse.NetworkErrorAccountNames)
+	slices.Sort(instanceSchedulingResponse.SkippedHoliday)
+	for _, regionResponse := range instanceSchedulingResponse.Regions {
+		slices.Sort(regionResponse.AccountNames)
+		slices.SortFunc(regionResponse.FailedAccounts, func(a, b FailedAccount) int {
+			return strings.Compare(a.AccountName, b.AccountName)
+		})
 	}
 
 	log.Printf("INFO: Instance scheduling for %v member accounts: %v\n", len(instanceSchedulingResponse.MemberAccountNames), instanceSchedulingResponse.MemberAccountNames)
-	log.Printf("INFO: Ignored %v non-member accounts lacking InstanceSchedulerAccess role: %v\n", len(instanceSchedulingResponse.NonMemberAccountNames), instanceSchedulingResponse.NonMemberAccountNames)
+	log.Printf("INFO: Ignored %v non-member accounts lacking %v role: %v\n", len(instanceSchedulingResponse.NonMemberAccountNames), run.MemberAccountRole.Name, instanceSchedulingResponse.NonMemberAccountNames)
+	statusCode := 200
+	if len(instanceSchedulingResponse.PendingAccountNames) > 0 {
+		log.Printf("WARN: Ran out of time before the Lambda deadline, pending accounts: %v\n", instanceSchedulingResponse.PendingAccountNames)
+		statusCode = 207
+	}
+	if len(instanceSchedulingResponse.FailedAccounts) > 0 {
+		log.Printf("WARN: Scheduling failed for %v member accounts: %v\n", len(instanceSchedulingResponse.FailedAccounts), instanceSchedulingResponse.FailedAccounts)
+		statusCode = 207
+	}
 
 	body, _ := json.Marshal(instanceSchedulingResponse)
 	return events.APIGatewayProxyResponse{
 		Body:       string(body),
-		StatusCode: 200,
+		StatusCode: statusCode,
 	}, nil
 }
 
+// responseTimeReserve is kept back from the Lambda deadline to return the response after cancelling the AWS calls
+const responseTimeReserve = 2 * time.Second
+
+// hasTimeRemaining reports whether more than margin remains before the deadline of ctx, if it has one
+func hasTimeRemaining(ctx context.Context, margin time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > margin
+}
+
+// scheduleMemberAccount schedules the resources in one account and returns its share of the response. Its log lines
+// are prefixed with the account name, as accounts are scheduled concurrently. The account is listed as failed when
+// its clients cannot be created or the run's context is done before it finishes, e.g. when it was still being
+// scheduled at the Lambda deadline.
+func (instanceScheduler *InstanceScheduler) scheduleMemberAccount(cfg aws.Config, schedulingRun *SchedulingRun, accName string, account NonProductionAccount) *InstanceSchedulingResponse {
+	accountRun := *schedulingRun
+	accountRun.Logger = log.New(log.Writer(), fmt.Sprintf("[%v] ", accName), log.Flags()|log.Lmsgprefix)
+	run := &accountRun
+
+	accountResponse, err := instanceScheduler.scheduleMemberAccountResources(cfg, run, accName, account)
+	if err == nil {
+		err = run.ctx().Err()
+	}
+	if err != nil {
+		run.Printf("ERROR: Scheduling of member account %v did not finish: %v\n", accName, err)
+		accountResponse.addFailedAccount(accName, err)
+	} else {
+		accountResponse.CompletedAccountNames = []string{accName}
+	}
+	return accountResponse
+}
+
+func (instanceScheduler *InstanceScheduler) scheduleMemberAccountResources(cfg aws.Config, run *SchedulingRun, accName string, account NonProductionAccount) (*InstanceSchedulingResponse, error) {
+	accountResponse := &InstanceSchedulingResponse{}
+
+	session, err := instanceScheduler.CreateMemberAccountSession(run, cfg, accName, account.Id)
+	if err != nil {
+		return accountResponse, err
+	}
+	if session == nil {
+		accountResponse.NonMemberAccountNames = []string{accName}
+		return accountResponse, nil
+	}
+
+	accountResponse.MemberAccountNames = []string{accName}
+	run.StartOnBankHolidays = account.StartOnBankHolidays
+	if run.Action == "start" && run.isStartSkippedForBankHoliday() {
+		run.Printf("INFO: Skipped starting member account %v because today is a bank holiday: %v\n", accName, run.BankHoliday)
+		accountResponse.SkippedHoliday = []string{accName}
+		return accountResponse, nil
+	}
+
+	regions := account.Regions
+	if len(regions) == 0 {
+		regions = run.Regions
+	}
+	run.Printf("INFO: Instance scheduling for member account: accountName=%v, regions=%v\n", accName, regions)
+
+	// every region is scheduled even when another one fails, and the account fails with the errors of the regions
+	// that could not be scheduled, unless they are opt-in regions the account has not enabled
+	var regionErrors []error
+	for _, region := range regions {
+		if run.ctx().Err() != nil {
+			break
+		}
+		regionRun := *run
+		regionRun.Logger = log.New(log.Writer(), fmt.Sprintf("[%v %v] ", accName, region), log.Flags()|log.Lmsgprefix)
+		regionResponse, err := instanceScheduler.scheduleMemberAccountRegion(session.inRegion(region), &regionRun)
+		accountResponse.add(regionResponse)
+		if err == nil {
+			continue
+		}
+		if classifyError(err) == errorClassRegionNotEnabled {
+			regionRun.Printf("WARN: Skipped region %v because it is not enabled in member account %v: %v\n", region, accName, err)
+			continue
+		}
+		regionRun.Printf("ERROR: Could not schedule region %v of member account %v: %v\n", region, accName, err)
+		regionErrors = append(regionErrors, err)
+	}
+	return accountResponse, errors.Join(regionErrors...)
+}
+
+// scheduleMemberAccountRegion schedules the resources in the region of the session and returns its share of the
+// response, with a summary for the region which lists the account as failed when the region could not be scheduled
+func (instanceScheduler *InstanceScheduler) scheduleMemberAccountRegion(session *MemberAccountSession, run *SchedulingRun) (*InstanceSchedulingResponse, error) {
+	accountResponse := &InstanceSchedulingResponse{}
+	err := instanceScheduler.scheduleMemberAccountRegionResources(session, run, accountResponse)
+
+	regionResponse := &RegionResponse{
+		AccountNames: []string{session.AccountName},
+		ActedUpon: accountResponse.ActedUpon + accountResponse.RDSActedUpon + accountResponse.RDSClustersActedUpon +
+			accountResponse.DocDBClustersActedUpon + accountResponse.NeptuneClustersActedUpon + accountResponse.ASGActedUpon +
+			accountResponse.ECSActedUpon + accountResponse.EKSActedUpon + accountResponse.RedshiftActedUpon +
+			accountResponse.SageMakerActedUpon,
+		Skipped: accountResponse.Skipped + accountResponse.RDSSkipped + accountResponse.RDSClustersSkipped +
+			accountResponse.DocDBClustersSkipped + accountResponse.NeptuneClustersSkipped + accountResponse.ASGSkipped +
+			accountResponse.ECSSkipped + accountResponse.EKSSkipped + accountResponse.RedshiftSkipped +
+			accountResponse.SageMakerSkipped,
+	}
+	if err != nil {
+		regionResponse.FailedAccounts = []FailedAccount{newFailedAccount(session.AccountName, err)}
+	}
+	accountResponse.Regions = RegionResponses{session.Config.Region: regionResponse}
+	return accountResponse, err
+}
+
+// scheduleMemberAccountRegionResources schedules the resources in the region of the session into accountResponse. It
+// stops at the first resource type that cannot be scheduled.
+func (instanceScheduler *InstanceScheduler) scheduleMemberAccountRegionResources(session *MemberAccountSession, run *SchedulingRun, accountResponse *InstanceSchedulingResponse) error {
+	ec2Client := instanceScheduler.GetEc2ClientForMemberAccount(session)
+	count, err := instanceScheduler.StopStartTestInstancesInMemberAccount(ec2Client, run)
+	if err != nil {
+		return err
+	}
+	accountResponse.ActedUpon = count.actedUpon
+	accountResponse.Skipped = count.skipped
+	accountResponse.SkippedAutoScaled = count.skippedAutoScaled
+	accountResponse.StoppedNotByScheduler = count.stoppedNotByScheduler
+	accountResponse.Hibernated = count.hibernated
+	accountResponse.AlreadyInDesiredState = count.alreadyInDesiredState
+	accountResponse.NotActionable = count.notActionable
+	accountResponse.Failed = count.failed
+
+	rdsClient := instanceScheduler.GetRDSClientForMemberAccount(session)
+	rdsCount, err := instanceScheduler.StopStartTestRDSInstancesInMemberAccount(rdsClient, run)
+	if err != nil {
+		return err
+	}
+	accountResponse.RDSActedUpon = rdsCount.RDSActedUpon
+	accountResponse.RDSSkipped = rdsCount.RDSSkipped
+	accountResponse.RDSStoppedNotByScheduler = rdsCount.RDSStoppedNotByScheduler
+	accountResponse.RDSAutoRestartedRestopped = rdsCount.RDSAutoRestartedRestopped
+	accountResponse.RDSAlreadyInDesiredState = rdsCount.RDSAlreadyInDesiredState
+	accountResponse.RDSNotActionable = rdsCount.RDSNotActionable
+	accountResponse.RDSFailed = rdsCount.RDSFailed
+
+	rdsClusterClient := instanceScheduler.GetRDSClusterClientForMemberAccount(session)
+	rdsClusterCount, err := instanceScheduler.StopStartTestRDSClustersInMemberAccount(rdsClusterClient, run)
+	if err != nil {
+		return err
+	}
+	accountResponse.RDSClustersActedUpon = rdsClusterCount.RDSClustersActedUpon
+	accountResponse.RDSClustersSkipped = rdsClusterCount.RDSClustersSkipped
+	accountResponse.DocDBClustersActedUpon = rdsClusterCount.DocDBClustersActedUpon
+	accountResponse.DocDBClustersSkipped = rdsClusterCount.DocDBClustersSkipped
+	accountResponse.NeptuneClustersActedUpon = rdsClusterCount.NeptuneClustersActedUpon
+	accountResponse.NeptuneClustersSkipped = rdsClusterCount.NeptuneClustersSkipped
+	accountResponse.DBClustersAlreadyInDesiredState = rdsClusterCount.DBClustersAlreadyInDesiredState
+	accountResponse.DBClustersStoppedNotByScheduler = rdsClusterCount.DBClustersStoppedNotByScheduler
+	accountResponse.DBClustersNotActionable = rdsClusterCount.DBClustersNotActionable
+	accountResponse.DBClustersAutoRestartedRestopped = rdsClusterCount.DBClustersAutoRestartedRestopped
+	accountResponse.DBClustersFailed = rdsClusterCount.DBClustersFailed
+
+	asgClient := instanceScheduler.GetAutoScalingClientForMemberAccount(session)
+	asgCount, err := instanceScheduler.StopStartTestAutoScalingGroupsInMemberAccount(asgClient, run)
+	if err != nil {
+		return err
+	}
+	accountResponse.ASGActedUpon = asgCount.ASGActedUpon
+	accountResponse.ASGSkipped = asgCount.ASGSkipped
+	accountResponse.ASGFailed = asgCount.ASGFailed
+
+	ecsClient := instanceScheduler.GetECSClientForMemberAccount(session)
+	ecsCount, err := instanceScheduler.StopStartTestECSServicesInMemberAccount(ecsClient, run)
+	if err != nil {
+		return err
+	}
+	accountResponse.ECSActedUpon = ecsCount.ECSActedUpon
+	accountResponse.ECSSkipped = ecsCount.ECSSkipped
+
+	eksClient := instanceScheduler.GetEKSClientForMemberAccount(session)
+	eksCount, err := instanceScheduler.StopStartTestEKSNodegroupsInMemberAccount(eksClient, run)
+	if err != nil {
+		return err
+	}
+	accountResponse.EKSActedUpon = eksCount.EKSActedUpon
+	accountResponse.EKSSkipped = eksCount.EKSSkipped
+	accountResponse.EKSFailed = eksCount.EKSFailed
+
+	redshiftClient := instanceScheduler.GetRedshiftClientForMemberAccount(session)
+	redshiftCount, err := instanceScheduler.StopStartTestRedshiftClustersInMemberAccount(redshiftClient, run)
+	if err != nil {
+		return err
+	}
+	accountResponse.RedshiftActedUpon = redshiftCount.RedshiftActedUpon
+	accountResponse.RedshiftSkipped = redshiftCount.RedshiftSkipped
+	accountResponse.RedshiftAlreadyInDesiredState = redshiftCount.RedshiftAlreadyInDesiredState
+	accountResponse.RedshiftNotActionable = redshiftCount.RedshiftNotActionable
+
+	sagemakerClient := instanceScheduler.GetSageMakerClientForMemberAccount(session)
+	sagemakerCount, err := instanceScheduler.StopStartTestSageMakerInMemberAccount(sagemakerClient, run)
+	if err != nil {
+		return err
+	}
+	accountResponse.SageMakerActedUpon = sagemakerCount.SageMakerActedUpon
+	accountResponse.SageMakerSkipped = sagemakerCount.SageMakerSkipped
+	accountResponse.SageMakerAppsDeleted = sagemakerCount.SageMakerAppsDeleted
+	accountResponse.SageMakerFailed = sagemakerCount.SageMakerFailed
+	accountResponse.SageMakerStoppedNotByScheduler = sagemakerCount.SageMakerStoppedNotByScheduler
+
+	return nil
+}
+
 func main() {
 	InstanceScheduler := InstanceScheduler{
-		LoadDefaultConfig:                        LoadDefaultConfig,
-		CreateSSMClient:                          CreateSSMClient,
-		GetParameter:                             getParameter,
-		CreateSecretManagerClient:                CreateSecretManagerClient,
-		GetSecret:                                getSecret,
-		GetEc2ClientForMemberAccount:             getEc2ClientForMemberAccount,
-		GetRDSClientForMemberAccount:             getRDSClientForMemberAccount,
-		StopStartTestInstancesInMemberAccount:    stopStartTestInstancesInMemberAccount,
-		StopStartTestRDSInstancesInMemberAccount: StopStartTestRDSInstancesInMemberAccount,
+		Now:                                           time.Now,
+		LoadDefaultConfig:                             LoadDefaultConfig,
+		LoadBankHolidays:                              loadBankHolidays,
+		CreateSSMClient:                               CreateSSMClient,
+		GetParameter:                                  getParameter,
+		LoadScheduleDocument:                          loadScheduleDocument,
+		CreateSecretManagerClient:                     CreateSecretManagerClient,
+		GetSecret:                                     getSecret,
+		GetNonProductionAccounts:                      getNonProductionAccounts,
+		CreateMemberAccountSession:                    createMemberAccountSession,
+		GetEc2ClientForMemberAccount:                  getEc2ClientForMemberAccount,
+		GetRDSClientForMemberAccount:                  getRDSClientForMemberAccount,
+		StopStartTestInstancesInMemberAccount:         stopStartTestInstancesInMemberAccount,
+		StopStartTestRDSInstancesInMemberAccount:      StopStartTestRDSInstancesInMemberAccount,
+		GetRDSClusterClientForMemberAccount:           getRDSClusterClientForMemberAccount,
+		StopStartTestRDSClustersInMemberAccount:       StopStartTestRDSClustersInMemberAccount,
+		GetAutoScalingClientForMemberAccount:          getAutoScalingClientForMemberAccount,
+		StopStartTestAutoScalingGroupsInMemberAccount: stopStartTestAutoScalingGroupsInMemberAccount,
+		GetECSClientForMemberAccount:                  getECSClientForMemberAccount,
+		StopStartTestECSServicesInMemberAccount:       stopStartTestECSServicesInMemberAccount,
+		GetEKSClientForMemberAccount:                  getEKSClientForMemberAccount,
+		StopStartTestEKSNodegroupsInMemberAccount:     stopStartTestEKSNodegroupsInMemberAccount,
+		GetRedshiftClientForMemberAccount:             getRedshiftClientForMemberAccount,
+		StopStartTestRedshiftClustersInMemberAccount:  stopStartTestRedshiftClustersInMemberAccount,
+		GetSageMakerClientForMemberAccount:            getSageMakerClientForMemberAccount,
+		StopStartTestSageMakerInMemberAccount:         stopStartTestSageMakerInMemberAccount,
 	}
 	lambda.Start(InstanceScheduler.handler)
 }
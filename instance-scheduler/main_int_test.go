@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -13,17 +15,34 @@ func TestHandler(t *testing.T) {
 	t.Run("Test request", func(t *testing.T) {
 
 		instanceScheduler := InstanceScheduler{
-			LoadDefaultConfig:                        LoadDefaultConfig,
-			CreateSSMClient:                          CreateSSMClient,
-			GetParameter:                             getParameter,
-			CreateSecretManagerClient:                CreateSecretManagerClient,
-			GetSecret:                                getSecret,
-			GetEc2ClientForMemberAccount:             getEc2ClientForMemberAccount,
-			GetRDSClientForMemberAccount:             getRDSClientForMemberAccount,
-			StopStartTestInstancesInMemberAccount:    stopStartTestInstancesInMemberAccount,
-			StopStartTestRDSInstancesInMemberAccount: StopStartTestRDSInstancesInMemberAccount,
+			Now:                                           time.Now,
+			LoadDefaultConfig:                             LoadDefaultConfig,
+			LoadBankHolidays:                              loadBankHolidays,
+			CreateSSMClient:                               CreateSSMClient,
+			GetParameter:                                  getParameter,
+			LoadScheduleDocument:                          loadScheduleDocument,
+			CreateSecretManagerClient:                     CreateSecretManagerClient,
+			GetSecret:                                     getSecret,
+			GetNonProductionAccounts:                      getNonProductionAccounts,
+			CreateMemberAccountSession:                    createMemberAccountSession,
+			GetEc2ClientForMemberAccount:                  getEc2ClientForMemberAccount,
+			GetRDSClientForMemberAccount:                  getRDSClientForMemberAccount,
+			StopStartTestInstancesInMemberAccount:         stopStartTestInstancesInMemberAccount,
+			StopStartTestRDSInstancesInMemberAccount:      StopStartTestRDSInstancesInMemberAccount,
+			GetRDSClusterClientForMemberAccount:           getRDSClusterClientForMemberAccount,
+			StopStartTestRDSClustersInMemberAccount:       StopStartTestRDSClustersInMemberAccount,
+			GetAutoScalingClientForMemberAccount:          getAutoScalingClientForMemberAccount,
+			StopStartTestAutoScalingGroupsInMemberAccount: stopStartTestAutoScalingGroupsInMemberAccount,
+			GetECSClientForMemberAccount:                  getECSClientForMemberAccount,
+			StopStartTestECSServicesInMemberAccount:       stopStartTestECSServicesInMemberAccount,
+			GetEKSClientForMemberAccount:                  getEKSClientForMemberAccount,
+			StopStartTestEKSNodegroupsInMemberAccount:     stopStartTestEKSNodegroupsInMemberAccount,
+			GetRedshiftClientForMemberAccount:             getRedshiftClientForMemberAccount,
+			StopStartTestRedshiftClustersInMemberAccount:  stopStartTestRedshiftClustersInMemberAccount,
+			GetSageMakerClientForMemberAccount:            getSageMakerClientForMemberAccount,
+			StopStartTestSageMakerInMemberAccount:         stopStartTestSageMakerInMemberAccount,
 		}
-		result, err := instanceScheduler.handler(InstanceSchedulingRequest{Action: "Test"})
+		result, err := instanceScheduler.handler(context.Background(), InstanceSchedulingRequest{Action: "Test"})
 		if err != nil {
 			t.Fatalf("Failed to run lambda's handler: %v", err)
 		}
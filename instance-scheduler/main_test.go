@@ -4,19 +4,29 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
-func mockLoadDefaultConfigWithError() (aws.Config, error) {
+func mockNow() time.Time {
+	return time.Date(2026, time.October, 14, 12, 0, 0, 0, time.UTC)
+}
+
+func mockLoadDefaultConfigWithError(ctx context.Context) (aws.Config, error) {
 	return aws.Config{}, errors.New("Mock Error!")
 }
 
-func mockLoadDefaultConfig() (aws.Config, error) {
+func mockLoadDefaultConfig(ctx context.Context) (aws.Config, error) {
 	return aws.Config{}, nil
 }
 
@@ -32,15 +42,28 @@ func mockCreateSSMClient(aws.Config) ISSMGetParameter {
 	return new(MockISSMGetParameter)
 }
 
-func mockHandlerGetParameter(client ISSMGetParameter, parameterName string) string {
-	return "test parameter"
+func mockHandlerGetParameter(ctx context.Context, client ISSMGetParameter, parameterName string) (string, error) {
+	return "test parameter", nil
+}
+
+func mockLoadScheduleDocument(ctx context.Context, client ISSMGetParameter) (string, error) {
+	return defaultScheduleDocument, nil
+}
+
+func mockLoadBankHolidays() (BankHolidayCalendar, error) {
+	christmasDay := []BankHolidayEvent{{Title: "Christmas Day", Date: "2026-12-25"}}
+	return BankHolidayCalendar{
+		bankHolidayDivisionEnglandAndWales: {Division: bankHolidayDivisionEnglandAndWales, Events: christmasDay},
+		bankHolidayDivisionScotland:        {Division: bankHolidayDivisionScotland, Events: christmasDay},
+		bankHolidayDivisionNorthernIreland: {Division: bankHolidayDivisionNorthernIreland, Events: christmasDay},
+	}, nil
 }
 
 func mockCreateSecretManagerClient(cfg aws.Config) ISecretManagerGetSecretValue {
 	return nil
 }
 
-func mockGetSecret(client ISecretManagerGetSecretValue, secretId string) string {
+func mockGetSecret(ctx context.Context, client ISecretManagerGetSecretValue, secretId string) (string, error) {
 	return `{
 		"account_ids": {
 			"test-account-development": "1",
@@ -48,7 +71,22 @@ func mockGetSecret(client ISecretManagerGetSecretValue, secretId string) string
 			"test-account-test": "3",
 			"test-account-production": "4"
 		}
-	}`
+	}`, nil
+}
+
+func mockGetNonProductionAccounts(environments string) (map[string]NonProductionAccount, error) {
+	return map[string]NonProductionAccount{
+		"test-account-development": {Id: "1"},
+		"test-account-test":        {Id: "3", StartOnBankHolidays: true},
+	}, nil
+}
+
+func mockCreateMemberAccountSession(run *SchedulingRun, cfg aws.Config, accountName string, accountId string) (*MemberAccountSession, error) {
+	return &MemberAccountSession{AccountName: accountName, AccountId: accountId, Config: cfg}, nil
+}
+
+func mockCreateMemberAccountSessionNonMember(run *SchedulingRun, cfg aws.Config, accountName string, accountId string) (*MemberAccountSession, error) {
+	return nil, nil
 }
 
 type MockGetEc2ClientForMemberAccount struct {
@@ -56,47 +94,140 @@ type MockGetEc2ClientForMemberAccount struct {
 	IEC2InstancesAPI
 }
 
-func mockGetEc2ClientForMemberAccount(cfg aws.Config, accountName string, accountId string) IEC2InstancesAPI {
+func mockGetEc2ClientForMemberAccount(session *MemberAccountSession) IEC2InstancesAPI {
 	return new(MockGetEc2ClientForMemberAccount)
 }
 
-func mockGetEc2ClientForMemberAccountError(cfg aws.Config, accountName string, accountId string) IEC2InstancesAPI {
-	return nil
-}
-
 type MockGetRDSClientForMemberAccount struct {
 	mock.Mock
 	IRDSInstancesAPI
 }
 
-func mockGetRdsClientForMemberAccount(cfg aws.Config, accountName string, accountId string) IRDSInstancesAPI {
+func mockGetRdsClientForMemberAccount(session *MemberAccountSession) IRDSInstancesAPI {
 	return new(MockGetRDSClientForMemberAccount)
 }
 
-func mockGetRdsClientForMemberAccountError(cfg aws.Config, accountName string, accountId string) IRDSInstancesAPI {
-	return nil
-}
-
-func mockStopStartTestInstancesInMemberAccount(client IEC2InstancesAPI, action string) *InstanceCount {
+func mockStopStartTestInstancesInMemberAccount(client IEC2InstancesAPI, run *SchedulingRun) (*InstanceCount, error) {
 	return &InstanceCount{
 		actedUpon:         1,
 		skipped:           1,
 		skippedAutoScaled: 1,
-	}
+	}, nil
 }
 
-func mockStopStartTestRDSInstancesInMemberAccount(RDSClient IRDSInstancesAPI, action string) *RDSInstanceCount {
+func mockStopStartTestRDSInstancesInMemberAccount(RDSClient IRDSInstancesAPI, run *SchedulingRun) (*RDSInstanceCount, error) {
 	return &RDSInstanceCount{
 		RDSActedUpon: 1,
 		RDSSkipped:   1,
-	}
+	}, nil
+}
+
+type MockGetRDSClusterClientForMemberAccount struct {
+	mock.Mock
+	IRDSClustersAPI
+}
+
+func mockGetRDSClusterClientForMemberAccount(session *MemberAccountSession) IRDSClustersAPI {
+	return new(MockGetRDSClusterClientForMemberAccount)
+}
+
+func mockStopStartTestRDSClustersInMemberAccount(RDSClient IRDSClustersAPI, run *SchedulingRun) (*RDSClusterCount, error) {
+	return &RDSClusterCount{
+		RDSClustersActedUpon:     1,
+		RDSClustersSkipped:       1,
+		DocDBClustersActedUpon:   1,
+		DocDBClustersSkipped:     1,
+		NeptuneClustersActedUpon: 1,
+		NeptuneClustersSkipped:   1,
+	}, nil
+}
+
+type MockGetAutoScalingClientForMemberAccount struct {
+	mock.Mock
+	IAutoScalingAPI
+}
+
+func mockGetAutoScalingClientForMemberAccount(session *MemberAccountSession) IAutoScalingAPI {
+	return new(MockGetAutoScalingClientForMemberAccount)
+}
+
+func mockStopStartTestAutoScalingGroupsInMemberAccount(client IAutoScalingAPI, run *SchedulingRun) (*AutoScalingGroupCount, error) {
+	return &AutoScalingGroupCount{
+		ASGActedUpon: 1,
+		ASGSkipped:   1,
+	}, nil
+}
+
+type MockGetECSClientForMemberAccount struct {
+	mock.Mock
+	IECSAPI
+}
+
+func mockGetECSClientForMemberAccount(session *MemberAccountSession) IECSAPI {
+	return new(MockGetECSClientForMemberAccount)
+}
+
+func mockStopStartTestECSServicesInMemberAccount(client IECSAPI, run *SchedulingRun) (*ECSServiceCount, error) {
+	return &ECSServiceCount{
+		ECSActedUpon: 1,
+		ECSSkipped:   1,
+	}, nil
+}
+
+type MockGetEKSClientForMemberAccount struct {
+	mock.Mock
+	IEKSAPI
+}
+
+func mockGetEKSClientForMemberAccount(session *MemberAccountSession) IEKSAPI {
+	return new(MockGetEKSClientForMemberAccount)
+}
+
+func mockStopStartTestEKSNodegroupsInMemberAccount(client IEKSAPI, run *SchedulingRun) (*EKSNodegroupCount, error) {
+	return &EKSNodegroupCount{
+		EKSActedUpon: 1,
+		EKSSkipped:   1,
+	}, nil
+}
+
+type MockGetRedshiftClientForMemberAccount struct {
+	mock.Mock
+	IRedshiftAPI
+}
+
+func mockGetRedshiftClientForMemberAccount(session *MemberAccountSession) IRedshiftAPI {
+	return new(MockGetRedshiftClientForMemberAccount)
+}
+
+func mockStopStartTestRedshiftClustersInMemberAccount(client IRedshiftAPI, run *SchedulingRun) (*RedshiftClusterCount, error) {
+	return &RedshiftClusterCount{
+		RedshiftActedUpon: 1,
+		RedshiftSkipped:   1,
+	}, nil
+}
+
+type MockGetSageMakerClientForMemberAccount struct {
+	mock.Mock
+	ISageMakerAPI
+}
+
+func mockGetSageMakerClientForMemberAccount(session *MemberAccountSession) ISageMakerAPI {
+	return new(MockGetSageMakerClientForMemberAccount)
+}
+
+func mockStopStartTestSageMakerInMemberAccount(client ISageMakerAPI, run *SchedulingRun) (*SageMakerCount, error) {
+	return &SageMakerCount{
+		SageMakerActedUpon:   1,
+		SageMakerSkipped:     1,
+		SageMakerAppsDeleted: 1,
+	}, nil
 }
 
 func TestHandlerUnit(t *testing.T) {
 	t.Run("returns 400 error status and empty response when `InstanceSchedulingRequest.Action` is invalid", func(t *testing.T) {
 		instanceScheduler := InstanceScheduler{LoadDefaultConfig: mockLoadDefaultConfig}
 
-		response, err := instanceScheduler.handler(InstanceSchedulingRequest{Action: "Invalid Action! 😱"})
+		response, err := instanceScheduler.handler(context.Background(), InstanceSchedulingRequest{Action: "Invalid Action! 😱"})
 
 		responseBody := InstanceSchedulingResponse{}
 		json.Unmarshal([]byte(response.Body), &responseBody)
@@ -114,7 +245,7 @@ func TestHandlerUnit(t *testing.T) {
 	t.Run("returns 500 error status and empty response when default config cannot load", func(t *testing.T) {
 		instanceScheduler := InstanceScheduler{LoadDefaultConfig: mockLoadDefaultConfigWithError}
 
-		response, err := instanceScheduler.handler(InstanceSchedulingRequest{Action: "test"})
+		response, err := instanceScheduler.handler(context.Background(), InstanceSchedulingRequest{Action: "test"})
 
 		responseBody := InstanceSchedulingResponse{}
 		json.Unmarshal([]byte(response.Body), &responseBody)
@@ -131,18 +262,24 @@ func TestHandlerUnit(t *testing.T) {
 
 	t.Run("returns 200 status and empty response when no non-production accounts found", func(t *testing.T) {
 		instanceScheduler := InstanceScheduler{
+			Now:                       mockNow,
 			LoadDefaultConfig:         mockLoadDefaultConfig,
 			CreateSSMClient:           mockCreateSSMClient,
 			GetParameter:              mockHandlerGetParameter,
+			LoadScheduleDocument:      mockLoadScheduleDocument,
+			LoadBankHolidays:          mockLoadBankHolidays,
 			CreateSecretManagerClient: mockCreateSecretManagerClient,
-			GetSecret: func(client ISecretManagerGetSecretValue, secretId string) string {
+			GetSecret: func(ctx context.Context, client ISecretManagerGetSecretValue, secretId string) (string, error) {
 				return `{
 					"account_ids": {}
-				}`
+				}`, nil
+			},
+			GetNonProductionAccounts: func(environments string) (map[string]NonProductionAccount, error) {
+				return map[string]NonProductionAccount{}, nil
 			},
 		}
 
-		response, err := instanceScheduler.handler(InstanceSchedulingRequest{Action: "test"})
+		response, err := instanceScheduler.handler(context.Background(), InstanceSchedulingRequest{Action: "test"})
 
 		responseBody := InstanceSchedulingResponse{}
 		json.Unmarshal([]byte(response.Body), &responseBody)
@@ -159,22 +296,26 @@ func TestHandlerUnit(t *testing.T) {
 
 	t.Run("returns 200 status and returns full response and counts number of non-member accounts", func(t *testing.T) {
 		instanceScheduler := InstanceScheduler{
-			LoadDefaultConfig:            mockLoadDefaultConfig,
-			CreateSSMClient:              mockCreateSSMClient,
-			GetParameter:                 mockHandlerGetParameter,
-			CreateSecretManagerClient:    mockCreateSecretManagerClient,
-			GetSecret:                    mockGetSecret,
-			GetEc2ClientForMemberAccount: mockGetEc2ClientForMemberAccountError,
-			GetRDSClientForMemberAccount: mockGetRdsClientForMemberAccountError,
+			Now:                        mockNow,
+			LoadDefaultConfig:          mockLoadDefaultConfig,
+			CreateSSMClient:            mockCreateSSMClient,
+			GetParameter:               mockHandlerGetParameter,
+			LoadScheduleDocument:       mockLoadScheduleDocument,
+			CreateSecretManagerClient:  mockCreateSecretManagerClient,
+			LoadBankHolidays:           mockLoadBankHolidays,
+			GetSecret:                  mockGetSecret,
+			GetNonProductionAccounts:   mockGetNonProductionAccounts,
+			CreateMemberAccountSession: mockCreateMemberAccountSessionNonMember,
 		}
 
-		response, err := instanceScheduler.handler(InstanceSchedulingRequest{Action: "test"})
+		response, err := instanceScheduler.handler(context.Background(), InstanceSchedulingRequest{Action: "test"})
 
 		responseBody := InstanceSchedulingResponse{}
 		json.Unmarshal([]byte(response.Body), &responseBody)
 		assert.Equal(t, response.StatusCode, 200)
 		assert.Equal(t, responseBody.Action, "test")
 		assert.ElementsMatch(t, responseBody.MemberAccountNames, []string{})
+		assert.Equal(t, []string{"test-account-development", "test-account-test"}, responseBody.NonMemberAccountNames)
 		assert.Equal(t, responseBody.ActedUpon, 0)
 		assert.Equal(t, responseBody.Skipped, 0)
 		assert.Equal(t, responseBody.SkippedAutoScaled, 0)
@@ -183,5 +324,590 @@ func TestHandlerUnit(t *testing.T) {
 		assert.Nil(t, err)
 	})
 
+	t.Run("returns 200 status and skips starting member accounts on a bank holiday unless they opt out", func(t *testing.T) {
+		instanceScheduler := InstanceScheduler{
+			Now: func() time.Time {
+				return time.Date(2026, time.December, 25, 7, 0, 0, 0, time.UTC)
+			},
+			LoadDefaultConfig:                             mockLoadDefaultConfig,
+			LoadBankHolidays:                              mockLoadBankHolidays,
+			CreateSSMClient:                               mockCreateSSMClient,
+			GetParameter:                                  mockHandlerGetParameter,
+			LoadScheduleDocument:                          mockLoadScheduleDocument,
+			CreateSecretManagerClient:                     mockCreateSecretManagerClient,
+			GetSecret:                                     mockGetSecret,
+			GetNonProductionAccounts:                      mockGetNonProductionAccounts,
+			CreateMemberAccountSession:                    mockCreateMemberAccountSession,
+			GetEc2ClientForMemberAccount:                  mockGetEc2ClientForMemberAccount,
+			GetRDSClientForMemberAccount:                  mockGetRdsClientForMemberAccount,
+			StopStartTestInstancesInMemberAccount:         mockStopStartTestInstancesInMemberAccount,
+			StopStartTestRDSInstancesInMemberAccount:      mockStopStartTestRDSInstancesInMemberAccount,
+			GetRDSClusterClientForMemberAccount:           mockGetRDSClusterClientForMemberAccount,
+			StopStartTestRDSClustersInMemberAccount:       mockStopStartTestRDSClustersInMemberAccount,
+			GetAutoScalingClientForMemberAccount:          mockGetAutoScalingClientForMemberAccount,
+			StopStartTestAutoScalingGroupsInMemberAccount: mockStopStartTestAutoScalingGroupsInMemberAccount,
+			GetECSClientForMemberAccount:                  mockGetECSClientForMemberAccount,
+			StopStartTestECSServicesInMemberAccount:       mockStopStartTestECSServicesInMemberAccount,
+			GetEKSClientForMemberAccount:                  mockGetEKSClientForMemberAccount,
+			StopStartTestEKSNodegroupsInMemberAccount:     mockStopStartTestEKSNodegroupsInMemberAccount,
+			GetRedshiftClientForMemberAccount:             mockGetRedshiftClientForMemberAccount,
+			StopStartTestRedshiftClustersInMemberAccount:  mockStopStartTestRedshiftClustersInMemberAccount,
+			GetSageMakerClientForMemberAccount:            mockGetSageMakerClientForMemberAccount,
+			StopStartTestSageMakerInMemberAccount:         mockStopStartTestSageMakerInMemberAccount,
+		}
+
+		response, err := instanceScheduler.handler(context.Background(), InstanceSchedulingRequest{Action: "start"})
+
+		responseBody := InstanceSchedulingResponse{}
+		json.Unmarshal([]byte(response.Body), &responseBody)
+		assert.Equal(t, response.StatusCode, 200)
+		assert.ElementsMatch(t, responseBody.MemberAccountNames, []string{"test-account-development", "test-account-test"})
+		assert.Equal(t, responseBody.SkippedHoliday, []string{"test-account-development"})
+		assert.Equal(t, responseBody.ActedUpon, 1)
+		assert.Equal(t, responseBody.RDSActedUpon, 1)
+		assert.Equal(t, responseBody.RDSClustersActedUpon, 1)
+		assert.Equal(t, responseBody.DocDBClustersActedUpon, 1)
+		assert.Equal(t, responseBody.NeptuneClustersActedUpon, 1)
+		assert.Equal(t, responseBody.ASGActedUpon, 1)
+		assert.Equal(t, responseBody.ECSActedUpon, 1)
+		assert.Equal(t, responseBody.EKSActedUpon, 1)
+		assert.Equal(t, responseBody.RedshiftActedUpon, 1)
+		assert.Equal(t, responseBody.SageMakerActedUpon, 1)
+		assert.Nil(t, err)
+	})
+
+	t.Run("returns 200 status and lists every account as completed when there is time to schedule them", func(t *testing.T) {
+		instanceScheduler := InstanceScheduler{
+			Now:                                           mockNow,
+			LoadDefaultConfig:                             mockLoadDefaultConfig,
+			LoadBankHolidays:                              mockLoadBankHolidays,
+			CreateSSMClient:                               mockCreateSSMClient,
+			GetParameter:                                  mockHandlerGetParameter,
+			LoadScheduleDocument:                          mockLoadScheduleDocument,
+			CreateSecretManagerClient:                     mockCreateSecretManagerClient,
+			GetSecret:                                     mockGetSecret,
+			GetNonProductionAccounts:                      mockGetNonProductionAccounts,
+			CreateMemberAccountSession:                    mockCreateMemberAccountSession,
+			GetEc2ClientForMemberAccount:                  mockGetEc2ClientForMemberAccount,
+			GetRDSClientForMemberAccount:                  mockGetRdsClientForMemberAccount,
+			StopStartTestInstancesInMemberAccount:         mockStopStartTestInstancesInMemberAccount,
+			StopStartTestRDSInstancesInMemberAccount:      mockStopStartTestRDSInstancesInMemberAccount,
+			GetRDSClusterClientForMemberAccount:           mockGetRDSClusterClientForMemberAccount,
+			StopStartTestRDSClustersInMemberAccount:       mockStopStartTestRDSClustersInMemberAccount,
+			GetAutoScalingClientForMemberAccount:          mockGetAutoScalingClientForMemberAccount,
+			StopStartTestAutoScalingGroupsInMemberAccount: mockStopStartTestAutoScalingGroupsInMemberAccount,
+			GetECSClientForMemberAccount:                  mockGetECSClientForMemberAccount,
+			StopStartTestECSServicesInMemberAccount:       mockStopStartTestECSServicesInMemberAccount,
+			GetEKSClientForMemberAccount:                  mockGetEKSClientForMemberAccount,
+			StopStartTestEKSNodegroupsInMemberAccount:     mockStopStartTestEKSNodegroupsInMemberAccount,
+			GetRedshiftClientForMemberAccount:             mockGetRedshiftClientForMemberAccount,
+			StopStartTestRedshiftClustersInMemberAccount:  mockStopStartTestRedshiftClustersInMemberAccount,
+			GetSageMakerClientForMemberAccount:            mockGetSageMakerClientForMemberAccount,
+			StopStartTestSageMakerInMemberAccount:         mockStopStartTestSageMakerInMemberAccount,
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		response, err := instanceScheduler.handler(ctx, InstanceSchedulingRequest{Action: "stop"})
+
+		responseBody := InstanceSchedulingResponse{}
+		json.Unmarshal([]byte(response.Body), &responseBody)
+		assert.Equal(t, response.StatusCode, 200)
+		assert.Equal(t, []string{"test-account-development", "test-account-test"}, responseBody.CompletedAccountNames)
+		assert.Equal(t, []string{}, responseBody.PendingAccountNames)
+		assert.Equal(t, []FailedAccount{}, responseBody.FailedAccounts)
+		assert.Equal(t, responseBody.ActedUpon, 2)
+		assert.Nil(t, err)
+	})
+
+	t.Run("returns 200 status and schedules every account in each region, or in its own regions", func(t *testing.T) {
+		t.Setenv("INSTANCE_SCHEDULING_REGIONS", "eu-west-2, eu-west-1")
+		var regionsMutex sync.Mutex
+		regions := map[string][]string{}
+		instanceScheduler := InstanceScheduler{
+			Now:                       mockNow,
+			LoadDefaultConfig:         mockLoadDefaultConfig,
+			LoadBankHolidays:          mockLoadBankHolidays,
+			CreateSSMClient:           mockCreateSSMClient,
+			GetParameter:              mockHandlerGetParameter,
+			LoadScheduleDocument:      mockLoadScheduleDocument,
+			CreateSecretManagerClient: mockCreateSecretManagerClient,
+			GetSecret:                 mockGetSecret,
+			GetNonProductionAccounts: func(environments string) (map[string]NonProductionAccount, error) {
+				return map[string]NonProductionAccount{
+					"test-account-development": {Id: "1"},
+					"test-account-test":        {Id: "3", Regions: []string{"us-east-1"}},
+				}, nil
+			},
+			CreateMemberAccountSession:   mockCreateMemberAccountSession,
+			GetRDSClientForMemberAccount: mockGetRdsClientForMemberAccount,
+			GetEc2ClientForMemberAccount: func(session *MemberAccountSession) IEC2InstancesAPI {
+				regionsMutex.Lock()
+				defer regionsMutex.Unlock()
+				regions[session.AccountName] = append(regions[session.AccountName], session.Config.Region)
+				return new(MockGetEc2ClientForMemberAccount)
+			},
+			StopStartTestInstancesInMemberAccount:         mockStopStartTestInstancesInMemberAccount,
+			StopStartTestRDSInstancesInMemberAccount:      mockStopStartTestRDSInstancesInMemberAccount,
+			GetRDSClusterClientForMemberAccount:           mockGetRDSClusterClientForMemberAccount,
+			StopStartTestRDSClustersInMemberAccount:       mockStopStartTestRDSClustersInMemberAccount,
+			GetAutoScalingClientForMemberAccount:          mockGetAutoScalingClientForMemberAccount,
+			StopStartTestAutoScalingGroupsInMemberAccount: mockStopStartTestAutoScalingGroupsInMemberAccount,
+			GetECSClientForMemberAccount:                  mockGetECSClientForMemberAccount,
+			StopStartTestECSServicesInMemberAccount:       mockStopStartTestECSServicesInMemberAccount,
+			GetEKSClientForMemberAccount:                  mockGetEKSClientForMemberAccount,
+			StopStartTestEKSNodegroupsInMemberAccount:     mockStopStartTestEKSNodegroupsInMemberAccount,
+			GetRedshiftClientForMemberAccount:             mockGetRedshiftClientForMemberAccount,
+			StopStartTestRedshiftClustersInMemberAccount:  mockStopStartTestRedshiftClustersInMemberAccount,
+			GetSageMakerClientForMemberAccount:            mockGetSageMakerClientForMemberAccount,
+			StopStartTestSageMakerInMemberAccount:         mockStopStartTestSageMakerInMemberAccount,
+		}
+		response, err := instanceScheduler.handler(context.Background(), InstanceSchedulingRequest{Action: "stop"})
+
+		responseBody := InstanceSchedulingResponse{}
+		json.Unmarshal([]byte(response.Body), &responseBody)
+		assert.Equal(t, response.StatusCode, 200)
+		assert.Equal(t, map[string][]string{
+			"test-account-development": {"eu-west-2", "eu-west-1"},
+			"test-account-test":        {"us-east-1"},
+		}, regions)
+		assert.Equal(t, responseBody.ActedUpon, 3)
+		assert.Equal(t, responseBody.RDSActedUpon, 3)
+		assert.Equal(t, RegionResponses{
+			"eu-west-2": {AccountNames: []string{"test-account-development"}, ActedUpon: 10, Skipped: 10},
+			"eu-west-1": {AccountNames: []string{"test-account-development"}, ActedUpon: 10, Skipped: 10},
+			"us-east-1": {AccountNames: []string{"test-account-test"}, ActedUpon: 10, Skipped: 10},
+		}, responseBody.Regions)
+		assert.Nil(t, err)
+	})
+
+	t.Run("returns 207 status and lists accounts as pending when too little time remains to start them", func(t *testing.T) {
+		instanceScheduler := InstanceScheduler{
+			Now:                                           mockNow,
+			LoadDefaultConfig:                             mockLoadDefaultConfig,
+			LoadBankHolidays:                              mockLoadBankHolidays,
+			CreateSSMClient:                               mockCreateSSMClient,
+			GetParameter:                                  mockHandlerGetParameter,
+			LoadScheduleDocument:                          mockLoadScheduleDocument,
+			CreateSecretManagerClient:                     mockCreateSecretManagerClient,
+			GetSecret:                                     mockGetSecret,
+			GetNonProductionAccounts:                      mockGetNonProductionAccounts,
+			CreateMemberAccountSession:                    mockCreateMemberAccountSession,
+			GetEc2ClientForMemberAccount:                  mockGetEc2ClientForMemberAccount,
+			GetRDSClientForMemberAccount:                  mockGetRdsClientForMemberAccount,
+			StopStartTestInstancesInMemberAccount:         mockStopStartTestInstancesInMemberAccount,
+			StopStartTestRDSInstancesInMemberAccount:      mockStopStartTestRDSInstancesInMemberAccount,
+			GetRDSClusterClientForMemberAccount:           mockGetRDSClusterClientForMemberAccount,
+			StopStartTestRDSClustersInMemberAccount:       mockStopStartTestRDSClustersInMemberAccount,
+			GetAutoScalingClientForMemberAccount:          mockGetAutoScalingClientForMemberAccount,
+			StopStartTestAutoScalingGroupsInMemberAccount: mockStopStartTestAutoScalingGroupsInMemberAccount,
+			GetECSClientForMemberAccount:                  mockGetECSClientForMemberAccount,
+			StopStartTestECSServicesInMemberAccount:       mockStopStartTestECSServicesInMemberAccount,
+			GetEKSClientForMemberAccount:                  mockGetEKSClientForMemberAccount,
+			StopStartTestEKSNodegroupsInMemberAccount:     mockStopStartTestEKSNodegroupsInMemberAccount,
+			GetRedshiftClientForMemberAccount:             mockGetRedshiftClientForMemberAccount,
+			StopStartTestRedshiftClustersInMemberAccount:  mockStopStartTestRedshiftClustersInMemberAccount,
+			GetSageMakerClientForMemberAccount:            mockGetSageMakerClientForMemberAccount,
+			StopStartTestSageMakerInMemberAccount:         mockStopStartTestSageMakerInMemberAccount,
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		response, err := instanceScheduler.handler(ctx, InstanceSchedulingRequest{Action: "stop"})
+
+		responseBody := InstanceSchedulingResponse{}
+		json.Unmarshal([]byte(response.Body), &responseBody)
+		assert.Equal(t, response.StatusCode, 207)
+		assert.Equal(t, []string{}, responseBody.CompletedAccountNames)
+		assert.Equal(t, []string{"test-account-development", "test-account-test"}, responseBody.PendingAccountNames)
+		assert.Equal(t, []FailedAccount{}, responseBody.FailedAccounts)
+		assert.Equal(t, []string{}, responseBody.MemberAccountNames)
+		assert.Equal(t, responseBody.ActedUpon, 0)
+		assert.Nil(t, err)
+	})
+
+	t.Run("returns 207 status and lists accounts as failed when the deadline is reached while scheduling them", func(t *testing.T) {
+		t.Setenv("INSTANCE_SCHEDULING_DEADLINE_MARGIN", "0s")
+		instanceScheduler := InstanceScheduler{
+			Now:                          mockNow,
+			LoadDefaultConfig:            mockLoadDefaultConfig,
+			LoadBankHolidays:             mockLoadBankHolidays,
+			CreateSSMClient:              mockCreateSSMClient,
+			GetParameter:                 mockHandlerGetParameter,
+			LoadScheduleDocument:         mockLoadScheduleDocument,
+			CreateSecretManagerClient:    mockCreateSecretManagerClient,
+			GetSecret:                    mockGetSecret,
+			GetNonProductionAccounts:     mockGetNonProductionAccounts,
+			CreateMemberAccountSession:   mockCreateMemberAccountSession,
+			GetEc2ClientForMemberAccount: mockGetEc2ClientForMemberAccount,
+			GetRDSClientForMemberAccount: mockGetRdsClientForMemberAccount,
+			StopStartTestInstancesInMemberAccount: func(client IEC2InstancesAPI, run *SchedulingRun) (*InstanceCount, error) {
+				<-run.ctx().Done()
+				return &InstanceCount{}, nil
+			},
+			StopStartTestRDSInstancesInMemberAccount:      mockStopStartTestRDSInstancesInMemberAccount,
+			GetRDSClusterClientForMemberAccount:           mockGetRDSClusterClientForMemberAccount,
+			StopStartTestRDSClustersInMemberAccount:       mockStopStartTestRDSClustersInMemberAccount,
+			GetAutoScalingClientForMemberAccount:          mockGetAutoScalingClientForMemberAccount,
+			StopStartTestAutoScalingGroupsInMemberAccount: mockStopStartTestAutoScalingGroupsInMemberAccount,
+			GetECSClientForMemberAccount:                  mockGetECSClientForMemberAccount,
+			StopStartTestECSServicesInMemberAccount:       mockStopStartTestECSServicesInMemberAccount,
+			GetEKSClientForMemberAccount:                  mockGetEKSClientForMemberAccount,
+			StopStartTestEKSNodegroupsInMemberAccount:     mockStopStartTestEKSNodegroupsInMemberAccount,
+			GetRedshiftClientForMemberAccount:             mockGetRedshiftClientForMemberAccount,
+			StopStartTestRedshiftClustersInMemberAccount:  mockStopStartTestRedshiftClustersInMemberAccount,
+			GetSageMakerClientForMemberAccount:            mockGetSageMakerClientForMemberAccount,
+			StopStartTestSageMakerInMemberAccount:         mockStopStartTestSageMakerInMemberAccount,
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), responseTimeReserve+500*time.Millisecond)
+		defer cancel()
+
+		response, err := instanceScheduler.handler(ctx, InstanceSchedulingRequest{Action: "stop"})
+
+		responseBody := InstanceSchedulingResponse{}
+		json.Unmarshal([]byte(response.Body), &responseBody)
+		assert.Equal(t, response.StatusCode, 207)
+		assert.Equal(t, []string{}, responseBody.CompletedAccountNames)
+		assert.Equal(t, []string{}, responseBody.PendingAccountNames)
+		assert.Equal(t, []FailedAccount{
+			{AccountName: "test-account-development", ErrorClass: "deadline_exceeded", Message: "context deadline exceeded"},
+			{AccountName: "test-account-test", ErrorClass: "deadline_exceeded", Message: "context deadline exceeded"},
+		}, responseBody.FailedAccounts)
+		assert.NoError(t, ctx.Err())
+		assert.Nil(t, err)
+	})
+
+	t.Run("returns 207 status and lists an account as failed when its clients cannot be created", func(t *testing.T) {
+		instanceScheduler := InstanceScheduler{
+			Now:                       mockNow,
+			LoadDefaultConfig:         mockLoadDefaultConfig,
+			LoadBankHolidays:          mockLoadBankHolidays,
+			CreateSSMClient:           mockCreateSSMClient,
+			GetParameter:              mockHandlerGetParameter,
+			LoadScheduleDocument:      mockLoadScheduleDocument,
+			CreateSecretManagerClient: mockCreateSecretManagerClient,
+			GetSecret:                 mockGetSecret,
+			GetNonProductionAccounts:  mockGetNonProductionAccounts,
+			CreateMemberAccountSession: func(run *SchedulingRun, cfg aws.Config, accountName string, accountId string) (*MemberAccountSession, error) {
+				if accountName == "test-account-test" {
+					return nil, &smithy.GenericAPIError{Code: "RequestExpired", Message: "Request has expired."}
+				}
+				return mockCreateMemberAccountSession(run, cfg, accountName, accountId)
+			},
+			GetEc2ClientForMemberAccount:                  mockGetEc2ClientForMemberAccount,
+			GetRDSClientForMemberAccount:                  mockGetRdsClientForMemberAccount,
+			StopStartTestInstancesInMemberAccount:         mockStopStartTestInstancesInMemberAccount,
+			StopStartTestRDSInstancesInMemberAccount:      mockStopStartTestRDSInstancesInMemberAccount,
+			GetRDSClusterClientForMemberAccount:           mockGetRDSClusterClientForMemberAccount,
+			StopStartTestRDSClustersInMemberAccount:       mockStopStartTestRDSClustersInMemberAccount,
+			GetAutoScalingClientForMemberAccount:          mockGetAutoScalingClientForMemberAccount,
+			StopStartTestAutoScalingGroupsInMemberAccount: mockStopStartTestAutoScalingGroupsInMemberAccount,
+			GetECSClientForMemberAccount:                  mockGetECSClientForMemberAccount,
+			StopStartTestECSServicesInMemberAccount:       mockStopStartTestECSServicesInMemberAccount,
+			GetEKSClientForMemberAccount:                  mockGetEKSClientForMemberAccount,
+			StopStartTestEKSNodegroupsInMemberAccount:     mockStopStartTestEKSNodegroupsInMemberAccount,
+			GetRedshiftClientForMemberAccount:             mockGetRedshiftClientForMemberAccount,
+			StopStartTestRedshiftClustersInMemberAccount:  mockStopStartTestRedshiftClustersInMemberAccount,
+			GetSageMakerClientForMemberAccount:            mockGetSageMakerClientForMemberAccount,
+			StopStartTestSageMakerInMemberAccount:         mockStopStartTestSageMakerInMemberAccount,
+		}
+		response, err := instanceScheduler.handler(context.Background(), InstanceSchedulingRequest{Action: "stop"})
+
+		responseBody := InstanceSchedulingResponse{}
+		json.Unmarshal([]byte(response.Body), &responseBody)
+		assert.Equal(t, response.StatusCode, 207)
+		assert.Equal(t, []string{"test-account-development"}, responseBody.CompletedAccountNames)
+		assert.Equal(t, []string{"test-account-development"}, responseBody.MemberAccountNames)
+		assert.Equal(t, []FailedAccount{
+			{AccountName: "test-account-test", ErrorClass: "aws_api_error", Message: "api error RequestExpired: Request has expired."},
+		}, responseBody.FailedAccounts)
+		assert.Equal(t, responseBody.ActedUpon, 1)
+		assert.Nil(t, err)
+	})
+
+	t.Run("returns 207 status and lists an account as failed when one of its resource types cannot be scheduled", func(t *testing.T) {
+		instanceScheduler := InstanceScheduler{
+			Now:                                           mockNow,
+			LoadDefaultConfig:                             mockLoadDefaultConfig,
+			LoadBankHolidays:                              mockLoadBankHolidays,
+			CreateSSMClient:                               mockCreateSSMClient,
+			GetParameter:                                  mockHandlerGetParameter,
+			LoadScheduleDocument:                          mockLoadScheduleDocument,
+			CreateSecretManagerClient:                     mockCreateSecretManagerClient,
+			GetSecret:                                     mockGetSecret,
+			GetNonProductionAccounts:                      mockGetNonProductionAccounts,
+			CreateMemberAccountSession:                    mockCreateMemberAccountSession,
+			GetEc2ClientForMemberAccount:                  mockGetEc2ClientForMemberAccount,
+			GetRDSClientForMemberAccount:                  mockGetRdsClientForMemberAccount,
+			StopStartTestInstancesInMemberAccount:         mockStopStartTestInstancesInMemberAccount,
+			StopStartTestRDSInstancesInMemberAccount:      mockStopStartTestRDSInstancesInMemberAccount,
+			GetRDSClusterClientForMemberAccount:           mockGetRDSClusterClientForMemberAccount,
+			StopStartTestRDSClustersInMemberAccount:       mockStopStartTestRDSClustersInMemberAccount,
+			GetAutoScalingClientForMemberAccount:          mockGetAutoScalingClientForMemberAccount,
+			StopStartTestAutoScalingGroupsInMemberAccount: mockStopStartTestAutoScalingGroupsInMemberAccount,
+			GetECSClientForMemberAccount:                  mockGetECSClientForMemberAccount,
+			StopStartTestECSServicesInMemberAccount:       mockStopStartTestECSServicesInMemberAccount,
+			GetEKSClientForMemberAccount:                  mockGetEKSClientForMemberAccount,
+			StopStartTestEKSNodegroupsInMemberAccount: func(client IEKSAPI, run *SchedulingRun) (*EKSNodegroupCount, error) {
+				if run.Logger.Prefix() == "[test-account-test eu-west-2] " {
+					return nil, invalidActionError("hibernate")
+				}
+				return mockStopStartTestEKSNodegroupsInMemberAccount(client, run)
+			},
+			GetRedshiftClientForMemberAccount:            mockGetRedshiftClientForMemberAccount,
+			StopStartTestRedshiftClustersInMemberAccount: mockStopStartTestRedshiftClustersInMemberAccount,
+			GetSageMakerClientForMemberAccount:           mockGetSageMakerClientForMemberAccount,
+			StopStartTestSageMakerInMemberAccount:        mockStopStartTestSageMakerInMemberAccount,
+		}
+		response, err := instanceScheduler.handler(context.Background(), InstanceSchedulingRequest{Action: "stop"})
+
+		responseBody := InstanceSchedulingResponse{}
+		json.Unmarshal([]byte(response.Body), &responseBody)
+		assert.Equal(t, response.StatusCode, 207)
+		assert.Equal(t, []string{"test-account-development"}, responseBody.CompletedAccountNames)
+		assert.Equal(t, []FailedAccount{
+			{AccountName: "test-account-test", ErrorClass: "unknown", Message: "invalid action: [ hibernate ]"},
+		}, responseBody.FailedAccounts)
+		assert.Equal(t, responseBody.EKSActedUpon, 1)
+		assert.Nil(t, err)
+	})
+
+	t.Run("returns 207 status and lists an account as failed when its resources cannot be listed", func(t *testing.T) {
+		instanceScheduler := InstanceScheduler{
+			Now:                        mockNow,
+			LoadDefaultConfig:          mockLoadDefaultConfig,
+			LoadBankHolidays:           mockLoadBankHolidays,
+			CreateSSMClient:            mockCreateSSMClient,
+			GetParameter:               mockHandlerGetParameter,
+			LoadScheduleDocument:       mockLoadScheduleDocument,
+			CreateSecretManagerClient:  mockCreateSecretManagerClient,
+			GetSecret:                  mockGetSecret,
+			GetNonProductionAccounts:   mockGetNonProductionAccounts,
+			CreateMemberAccountSession: mockCreateMemberAccountSession,
+			GetEc2ClientForMemberAccount: func(session *MemberAccountSession) IEC2InstancesAPI {
+				if session.AccountName == "test-account-test" {
+					return &mockIEC2InstancesAPI{DescribeInstancesError: errors.New("DescribeInstances failed")}
+				}
+				return &mockIEC2InstancesAPI{DescribeInstancesOutput: &ec2.DescribeInstancesOutput{}}
+			},
+			StopStartTestInstancesInMemberAccount:         stopStartTestInstancesInMemberAccount,
+			GetRDSClientForMemberAccount:                  mockGetRdsClientForMemberAccount,
+			StopStartTestRDSInstancesInMemberAccount:      mockStopStartTestRDSInstancesInMemberAccount,
+			GetRDSClusterClientForMemberAccount:           mockGetRDSClusterClientForMemberAccount,
+			StopStartTestRDSClustersInMemberAccount:       mockStopStartTestRDSClustersInMemberAccount,
+			GetAutoScalingClientForMemberAccount:          mockGetAutoScalingClientForMemberAccount,
+			StopStartTestAutoScalingGroupsInMemberAccount: mockStopStartTestAutoScalingGroupsInMemberAccount,
+			GetECSClientForMemberAccount:                  mockGetECSClientForMemberAccount,
+			StopStartTestECSServicesInMemberAccount:       mockStopStartTestECSServicesInMemberAccount,
+			GetEKSClientForMemberAccount:                  mockGetEKSClientForMemberAccount,
+			StopStartTestEKSNodegroupsInMemberAccount:     mockStopStartTestEKSNodegroupsInMemberAccount,
+			GetRedshiftClientForMemberAccount:             mockGetRedshiftClientForMemberAccount,
+			StopStartTestRedshiftClustersInMemberAccount:  mockStopStartTestRedshiftClustersInMemberAccount,
+			GetSageMakerClientForMemberAccount:            mockGetSageMakerClientForMemberAccount,
+			StopStartTestSageMakerInMemberAccount:         mockStopStartTestSageMakerInMemberAccount,
+		}
+		response, err := instanceScheduler.handler(context.Background(), InstanceSchedulingRequest{Action: "stop"})
+
+		responseBody := InstanceSchedulingResponse{}
+		json.Unmarshal([]byte(response.Body), &responseBody)
+		assert.Equal(t, response.StatusCode, 207)
+		assert.Equal(t, []string{"test-account-development"}, responseBody.CompletedAccountNames)
+		assert.Equal(t, []FailedAccount{
+			{AccountName: "test-account-test", ErrorClass: "unknown", Message: "could not retrieve information about Amazon EC2 instances: DescribeInstances failed"},
+		}, responseBody.FailedAccounts)
+		assert.Nil(t, err)
+	})
+
+	t.Run("returns 207 status and schedules every region when one fails, listing the failures by region", func(t *testing.T) {
+		t.Setenv("INSTANCE_SCHEDULING_REGIONS", "eu-west-1,eu-west-2,ap-east-1")
+		instanceScheduler := InstanceScheduler{
+			Now:                       mockNow,
+			LoadDefaultConfig:         mockLoadDefaultConfig,
+			LoadBankHolidays:          mockLoadBankHolidays,
+			CreateSSMClient:           mockCreateSSMClient,
+			GetParameter:              mockHandlerGetParameter,
+			LoadScheduleDocument:      mockLoadScheduleDocument,
+			CreateSecretManagerClient: mockCreateSecretManagerClient,
+			GetSecret:                 mockGetSecret,
+			GetNonProductionAccounts: func(environments string) (map[string]NonProductionAccount, error) {
+				return map[string]NonProductionAccount{
+					"test-account-development": {Id: "1"},
+					"test-account-test":        {Id: "3", Regions: []string{"ap-east-1"}},
+				}, nil
+			},
+			CreateMemberAccountSession: mockCreateMemberAccountSession,
+			GetEc2ClientForMemberAccount: func(session *MemberAccountSession) IEC2InstancesAPI {
+				switch session.Config.Region {
+				case "eu-west-1":
+					return &mockIEC2InstancesAPI{DescribeInstancesError: errors.New("DescribeInstances failed")}
+				case "ap-east-1":
+					return &mockIEC2InstancesAPI{DescribeInstancesError: &smithy.GenericAPIError{Code: "OptInRequired", Message: "You are not subscribed to this service."}}
+				}
+				return &mockIEC2InstancesAPI{DescribeInstancesOutput: &ec2.DescribeInstancesOutput{}}
+			},
+			StopStartTestInstancesInMemberAccount:         stopStartTestInstancesInMemberAccount,
+			GetRDSClientForMemberAccount:                  mockGetRdsClientForMemberAccount,
+			StopStartTestRDSInstancesInMemberAccount:      mockStopStartTestRDSInstancesInMemberAccount,
+			GetRDSClusterClientForMemberAccount:           mockGetRDSClusterClientForMemberAccount,
+			StopStartTestRDSClustersInMemberAccount:       mockStopStartTestRDSClustersInMemberAccount,
+			GetAutoScalingClientForMemberAccount:          mockGetAutoScalingClientForMemberAccount,
+			StopStartTestAutoScalingGroupsInMemberAccount: mockStopStartTestAutoScalingGroupsInMemberAccount,
+			GetECSClientForMemberAccount:                  mockGetECSClientForMemberAccount,
+			StopStartTestECSServicesInMemberAccount:       mockStopStartTestECSServicesInMemberAccount,
+			GetEKSClientForMemberAccount:                  mockGetEKSClientForMemberAccount,
+			StopStartTestEKSNodegroupsInMemberAccount:     mockStopStartTestEKSNodegroupsInMemberAccount,
+			GetRedshiftClientForMemberAccount:             mockGetRedshiftClientForMemberAccount,
+			StopStartTestRedshiftClustersInMemberAccount:  mockStopStartTestRedshiftClustersInMemberAccount,
+			GetSageMakerClientForMemberAccount:            mockGetSageMakerClientForMemberAccount,
+			StopStartTestSageMakerInMemberAccount:         mockStopStartTestSageMakerInMemberAccount,
+		}
+		response, err := instanceScheduler.handler(context.Background(), InstanceSchedulingRequest{Action: "stop"})
+
+		responseBody := InstanceSchedulingResponse{}
+		json.Unmarshal([]byte(response.Body), &responseBody)
+		assert.Equal(t, response.StatusCode, 207)
+		// an opt-in region the account has not enabled does not fail the account
+		assert.Equal(t, []string{"test-account-test"}, responseBody.CompletedAccountNames)
+		assert.Equal(t, []FailedAccount{
+			{AccountName: "test-account-development", ErrorClass: "unknown", Message: "could not retrieve information about Amazon EC2 instances: DescribeInstances failed"},
+		}, responseBody.FailedAccounts)
+		assert.Equal(t, []FailedAccount{
+			{AccountName: "test-account-development", ErrorClass: "unknown", Message: "could not retrieve information about Amazon EC2 instances: DescribeInstances failed"},
+		}, responseBody.Regions["eu-west-1"].FailedAccounts)
+		assert.Equal(t, []string{"test-account-development"}, responseBody.Regions["eu-west-2"].AccountNames)
+		assert.Empty(t, responseBody.Regions["eu-west-2"].FailedAccounts)
+		assert.Len(t, responseBody.Regions["ap-east-1"].FailedAccounts, 2)
+		assert.Equal(t, "region_not_enabled", responseBody.Regions["ap-east-1"].FailedAccounts[0].ErrorClass)
+		assert.Nil(t, err)
+	})
+
+	t.Run("returns 500 error status when the environments secret cannot be read", func(t *testing.T) {
+		instanceScheduler := InstanceScheduler{
+			Now:                       mockNow,
+			LoadDefaultConfig:         mockLoadDefaultConfig,
+			LoadBankHolidays:          mockLoadBankHolidays,
+			CreateSSMClient:           mockCreateSSMClient,
+			GetParameter:              mockHandlerGetParameter,
+			LoadScheduleDocument:      mockLoadScheduleDocument,
+			CreateSecretManagerClient: mockCreateSecretManagerClient,
+			GetSecret: func(ctx context.Context, client ISecretManagerGetSecretValue, secretId string) (string, error) {
+				return "", errors.New("failed to get secret test parameter: AccessDeniedException")
+			},
+		}
+
+		response, err := instanceScheduler.handler(context.Background(), InstanceSchedulingRequest{Action: "stop"})
+
+		assert.Equal(t, response.StatusCode, 500)
+		assert.ErrorContains(t, err, "AccessDeniedException")
+	})
+
+	t.Run("returns 500 error status when the bank holiday division is unknown", func(t *testing.T) {
+		t.Setenv("INSTANCE_SCHEDULING_BANK_HOLIDAY_DIVISION", "wales")
+		instanceScheduler := InstanceScheduler{
+			Now:               mockNow,
+			LoadDefaultConfig: mockLoadDefaultConfig,
+			LoadBankHolidays:  mockLoadBankHolidays,
+		}
+
+		response, err := instanceScheduler.handler(context.Background(), InstanceSchedulingRequest{Action: "start"})
+
+		assert.Equal(t, response.StatusCode, 500)
+		assert.NotNil(t, err)
+	})
+	t.Run("returns 400 error status and lists invalid schedules when the schedule catalogue is invalid", func(t *testing.T) {
+		instanceScheduler := InstanceScheduler{
+			Now:               mockNow,
+			LoadDefaultConfig: mockLoadDefaultConfig,
+			LoadBankHolidays:  mockLoadBankHolidays,
+			CreateSSMClient:   mockCreateSSMClient,
+			LoadScheduleDocument: func(ctx context.Context, client ISSMGetParameter) (string, error) {
+				return "schedules:\n  office-hours:\n    start: cron(0 7 ? * MON-FRI)\n  broken:\n    stop: cron(0 19)\n", nil
+			},
+		}
+
+		response, err := instanceScheduler.handler(context.Background(), InstanceSchedulingRequest{Action: "reconcile"})
+
+		responseBody := InstanceSchedulingResponse{}
+		json.Unmarshal([]byte(response.Body), &responseBody)
+		assert.Equal(t, response.StatusCode, 400)
+		assert.Len(t, responseBody.InvalidSchedules, 1)
+		assert.Contains(t, responseBody.InvalidSchedules[0], "broken:")
+		assert.Nil(t, err)
+	})
+}
+
+func TestInstanceSchedulingResponseAdd(t *testing.T) {
+	response := &InstanceSchedulingResponse{
+		MemberAccountNames:    []string{"test-account-development"},
+		NonMemberAccountNames: []string{},
+		SkippedHoliday:        []string{},
+		ActedUpon:             1,
+		RDSActedUpon:          2,
+	}
+	response.add(&InstanceSchedulingResponse{MemberAccountNames: []string{"test-account-test"}, ActedUpon: 3, SageMakerAppsDeleted: 1})
+	response.add(&InstanceSchedulingResponse{NonMemberAccountNames: []string{"test-account-preproduction"}, CompletedAccountNames: []string{"test-account-preproduction"}})
+	response.add(&InstanceSchedulingResponse{PendingAccountNames: []string{"test-account-staging"}})
+	response.add(&InstanceSchedulingResponse{Regions: RegionResponses{"eu-west-1": {AccountNames: []string{"test-account-test"}, ActedUpon: 2, Skipped: 1}}})
+	response.add(&InstanceSchedulingResponse{Regions: RegionResponses{"eu-west-1": {AccountNames: []string{"test-account-development"}, ActedUpon: 1}}})
+	response.add(&InstanceSchedulingResponse{FailedAccounts: []FailedAccount{{AccountName: "test-account-sandbox", ErrorClass: "unknown"}}})
+
+	assert.Equal(t, []string{"test-account-development", "test-account-test"}, response.MemberAccountNames)
+	assert.Equal(t, []string{"test-account-preproduction"}, response.NonMemberAccountNames)
+	assert.Equal(t, []string{"test-account-preproduction"}, response.CompletedAccountNames)
+	assert.Equal(t, []string{"test-account-staging"}, response.PendingAccountNames)
+	assert.Equal(t, RegionResponses{"eu-west-1": {AccountNames: []string{"test-account-test", "test-account-development"}, ActedUpon: 3, Skipped: 1}}, response.Regions)
+	assert.Equal(t, []FailedAccount{{AccountName: "test-account-sandbox", ErrorClass: "unknown"}}, response.FailedAccounts)
+	assert.Equal(t, 4, response.ActedUpon)
+	assert.Equal(t, 2, response.RDSActedUpon)
+	assert.Equal(t, 1, response.SageMakerAppsDeleted)
 }
 
+func TestNewFailedAccount(t *testing.T) {
+	tests := []struct {
+		title string
+		err   error
+		want  string
+	}{
+		{
+			title: "classifies a cancelled context as deadline exceeded",
+			err:   fmt.Errorf("failed to describe EC2 instances in account test-account-development: %w", context.DeadlineExceeded),
+			want:  "deadline_exceeded",
+		},
+		{
+			title: "classifies an error returned by an AWS API",
+			err:   fmt.Errorf("failed to describe RDS instances in account test-account-development: %w", &smithy.GenericAPIError{Code: "InternalFailure"}),
+			want:  "aws_api_error",
+		},
+		{
+			title: "classifies any other error as unknown",
+			err:   errors.New("unexpected"),
+			want:  "unknown",
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.title, func(t *testing.T) {
+			failedAccount := newFailedAccount("test-account-development", subtest.err)
+			assert.Equal(t, "test-account-development", failedAccount.AccountName)
+			assert.Equal(t, subtest.want, failedAccount.ErrorClass)
+			assert.Equal(t, subtest.err.Error(), failedAccount.Message)
+		})
+	}
+}
+
+func TestAddFailedAccount(t *testing.T) {
+	response := &InstanceSchedulingResponse{}
+
+	response.addFailedAccount("test-account-development", &smithy.GenericAPIError{Code: "AccessDenied", Message: "is not authorized to perform: sts:AssumeRole on resource: arn:aws:iam::1:role/InstanceSchedulerAccess with an explicit deny in a service control policy"})
+	response.addFailedAccount("test-account-test", &smithy.GenericAPIError{Code: "Throttling"})
+	response.addFailedAccount("test-account-preproduction", &smithy.GenericAPIError{Code: "Blocked"})
+	response.addFailedAccount("test-account-staging", &smithyhttp.RequestSendError{Err: errors.New("connection reset by peer")})
+	response.addFailedAccount("test-account-sandbox", errors.New("unexpected"))
+
+	assert.Len(t, response.FailedAccounts, 5)
+	assert.Equal(t, []string{"test-account-development"}, response.SCPDeniedAccountNames)
+	assert.Equal(t, []string{"test-account-test"}, response.ThrottledAccountNames)
+	assert.Equal(t, []string{"test-account-preproduction"}, response.SuspendedAccountNames)
+	assert.Equal(t, []string{"test-account-staging"}, response.NetworkErrorAccountNames)
+	assert.Equal(t, "unknown", response.FailedAccounts[4].ErrorClass)
+}
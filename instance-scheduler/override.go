@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Engineers can keep a resource running past its normal stop with a tag such as
+// instance-scheduling-override-until=2026-10-20T22:00Z. The resource is skipped when stopping until that time,
+// after which it is scheduled as normal again. Expired tags are removed when INSTANCE_SCHEDULING_REMOVE_EXPIRED_OVERRIDES=true.
+
+const overrideUntilTagKey string = "instance-scheduling-override-until"
+
+// Accepted formats, with or without seconds
+var overrideUntilLayouts = []string{"2006-01-02T15:04Z07:00", time.RFC3339}
+
+func parseOverrideUntil(value string) (time.Time, error) {
+	for _, layout := range overrideUntilLayouts {
+		if overrideUntil, err := time.Parse(layout, value); err == nil {
+			return overrideUntil, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("'%v' is not a timestamp such as 2026-10-20T22:00Z", value)
+}
+
+// parseOverrideUntilTag returns the time in the instance-scheduling-override-until tag, ignoring invalid values
+func parseOverrideUntilTag(run *SchedulingRun, value string) time.Time {
+	overrideUntil, err := parseOverrideUntil(value)
+	if err != nil {
+		run.Printf("WARN: Ignoring invalid %v tag: %v\n", overrideUntilTagKey, err)
+		return time.Time{}
+	}
+	return overrideUntil
+}
+
+// isOverrideActive reports whether an instance-scheduling-override-until tag keeps the resource running at now
+func isOverrideActive(overrideUntil time.Time, now time.Time) bool {
+	return now.Before(overrideUntil)
+}
+
+// isOverrideExpired reports whether a resource carries an instance-scheduling-override-until tag which has expired
+func isOverrideExpired(overrideUntil time.Time, now time.Time) bool {
+	return !overrideUntil.IsZero() && !now.Before(overrideUntil)
+}
+
+// removeExpiredOverride logs an expired instance-scheduling-override-until tag and removes it when configured to
+func removeExpiredOverride(run *SchedulingRun, resource taggedResource, overrideUntil time.Time) {
+	if !isOverrideExpired(overrideUntil, run.Now) {
+		return
+	}
+	run.Printf("INFO: %v tag on %v %v expired at %v\n", overrideUntilTagKey, resource.kind(), resource.id(), overrideUntil.Format(time.RFC3339))
+	if !run.RemoveExpiredOverrides || run.Action == "test" {
+		return
+	}
+
+	err := resource.removeTags(run, []string{overrideUntilTagKey})
+	if err == nil {
+		run.Printf("INFO: Removed expired %v tag from %v %v\n", overrideUntilTagKey, resource.kind(), resource.id())
+	} else {
+		run.Printf("ERROR: Could not remove expired %v tag from %v %v: %v\n", overrideUntilTagKey, resource.kind(), resource.id(), err)
+	}
+}
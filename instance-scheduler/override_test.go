@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOverrideUntil(t *testing.T) {
+	tests := []struct {
+		testTitle     string
+		value         string
+		expected      time.Time
+		expectedError bool
+	}{
+		{
+			testTitle: "timestamp without seconds",
+			value:     "2026-10-20T22:00Z",
+			expected:  time.Date(2026, time.October, 20, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			testTitle: "RFC 3339 timestamp with offset",
+			value:     "2026-10-20T22:00:00+01:00",
+			expected:  time.Date(2026, time.October, 20, 21, 0, 0, 0, time.UTC),
+		},
+		{
+			testTitle:     "date without time",
+			value:         "2026-10-20",
+			expectedError: true,
+		},
+		{
+			testTitle:     "empty value",
+			value:         "",
+			expectedError: true,
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.testTitle, func(t *testing.T) {
+			actual, err := parseOverrideUntil(subtest.value)
+			if subtest.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, subtest.expected.Equal(actual), "want %v, got %v", subtest.expected, actual)
+		})
+	}
+}
+
+func TestOverrideUntilState(t *testing.T) {
+	overrideUntil := time.Date(2026, time.October, 20, 22, 0, 0, 0, time.UTC)
+	tests := []struct {
+		testTitle       string
+		overrideUntil   time.Time
+		now             time.Time
+		expectedActive  bool
+		expectedExpired bool
+	}{
+		{
+			testTitle: "no override",
+			now:       overrideUntil,
+		},
+		{
+			testTitle:      "before the override ends",
+			overrideUntil:  overrideUntil,
+			now:            overrideUntil.Add(-time.Minute),
+			expectedActive: true,
+		},
+		{
+			testTitle:       "when the override ends",
+			overrideUntil:   overrideUntil,
+			now:             overrideUntil,
+			expectedExpired: true,
+		},
+		{
+			testTitle:       "after the override ends",
+			overrideUntil:   overrideUntil,
+			now:             overrideUntil.Add(time.Hour),
+			expectedExpired: true,
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.testTitle, func(t *testing.T) {
+			assert.Equal(t, subtest.expectedActive, isOverrideActive(subtest.overrideUntil, subtest.now))
+			assert.Equal(t, subtest.expectedExpired, isOverrideExpired(subtest.overrideUntil, subtest.now))
+		})
+	}
+}
@@ -3,211 +3,550 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
-	"strings"
+	"slices"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/rds"
 	rdstype "github.com/aws/aws-sdk-go-v2/service/rds/types"
-	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
+// rdsAutoStartAfter is how long AWS keeps an RDS instance stopped before starting it again automatically
+const rdsAutoStartAfter = 7 * 24 * time.Hour
+
+// rdsMinPageSize and rdsMaxPageSize are the MaxRecords accepted by DescribeDBInstances and DescribeDBClusters
+const (
+	rdsMinPageSize int32 = 20
+	rdsMaxPageSize int32 = 100
+)
+
+// rdsAutoStartEventCategory is the category of RDS-EVENT-0154 and RDS-EVENT-0153, which RDS records when it starts a
+// DB instance or cluster that has been stopped for longer than it allows. DescribeEvents does not return event IDs, so
+// these events are recognised by their category and by being recorded once rdsAutoStartAfter has passed.
+const rdsAutoStartEventCategory = "notification"
+
 type RDSInstanceCount struct {
-	RDSActedUpon int
-	RDSSkipped   int
+	RDSActedUpon              int
+	RDSSkipped                int
+	RDSStoppedNotByScheduler  int
+	RDSAutoRestartedRestopped int
+	RDSAlreadyInDesiredState  int
+	RDSNotActionable          int
+	RDSFailed                 int
 }
 
 type IRDSInstancesAPI interface {
 	StopDBInstance(ctx context.Context, params *rds.StopDBInstanceInput, optFns ...func(*rds.Options)) (*rds.StopDBInstanceOutput, error)
 	StartDBInstance(ctx context.Context, params *rds.StartDBInstanceInput, optFns ...func(*rds.Options)) (*rds.StartDBInstanceOutput, error)
 	DescribeDBInstances(ctx context.Context, params *rds.DescribeDBInstancesInput, optFns ...func(*rds.Options)) (*rds.DescribeDBInstancesOutput, error)
+	AddTagsToResource(ctx context.Context, params *rds.AddTagsToResourceInput, optFns ...func(*rds.Options)) (*rds.AddTagsToResourceOutput, error)
+	RemoveTagsFromResource(ctx context.Context, params *rds.RemoveTagsFromResourceInput, optFns ...func(*rds.Options)) (*rds.RemoveTagsFromResourceOutput, error)
+	DescribeEvents(ctx context.Context, params *rds.DescribeEventsInput, optFns ...func(*rds.Options)) (*rds.DescribeEventsOutput, error)
 }
 
-func StopStartTestRDSInstancesInMemberAccount(RDSClient IRDSInstancesAPI, action string) *RDSInstanceCount {
+// IRDSEventsAPI is the part of the RDS API which lists the events of DB instances and clusters
+type IRDSEventsAPI interface {
+	DescribeEvents(ctx context.Context, params *rds.DescribeEventsInput, optFns ...func(*rds.Options)) (*rds.DescribeEventsOutput, error)
+}
+
+func StopStartTestRDSInstancesInMemberAccount(RDSClient IRDSInstancesAPI, run *SchedulingRun) (*RDSInstanceCount, error) {
+	action := run.Action
 	if action == "stop" {
-		return stopRDSInstances(RDSClient)
+		return stopRDSInstances(RDSClient, run)
 	}
 
 	if action == "start" {
-		return startRDSInstances(RDSClient)
+		return startRDSInstances(RDSClient, run)
 	}
 
 	if action == "test" {
-		return testRDSInstances(RDSClient)
+		return testRDSInstances(RDSClient, run)
+	}
+
+	if action == "reconcile" {
+		return reconcileRDSInstances(RDSClient, run)
 	}
 
-	log.Fatalf("Invalid action: [ %v ]", action)
-	return nil
+	return nil, invalidActionError(action)
 }
 
-func parseRDSInstanceTags(instance rdstype.DBInstance, RDSskippedInstances []string) (string, bool, []string) {
-	var instanceSchedulingTag string
-	isSkipSchedulingTag := false
-	for _, tag := range instance.TagList {
-		if *tag.Key == "instance-scheduling" {
-			instanceSchedulingTag = *tag.Value
-		}
-		if *tag.Key == "instance-scheduling" && *tag.Value == "skip-scheduling" {
-			log.Printf("INFO: Skip instance because instance-scheduling tag having value 'skip-scheduling'\n")
-			RDSskippedInstances = append(RDSskippedInstances, *instance.DBInstanceIdentifier)
-			isSkipSchedulingTag = true
-		}
+// listRDSInstances returns every DB instance in the member account, requesting the run's page size per page, within
+// the range RDS accepts, unless it is zero
+func listRDSInstances(RDSClient IRDSInstancesAPI, run *SchedulingRun) ([]rdstype.DBInstance, error) {
+	input := &rds.DescribeDBInstancesInput{}
+	if run.PageSize > 0 {
+		input.MaxRecords = aws.Int32(clampPageSize(run.PageSize, rdsMinPageSize, rdsMaxPageSize))
 	}
 
-	return instanceSchedulingTag, isSkipSchedulingTag, RDSskippedInstances
+	RDSInstances := []rdstype.DBInstance{}
+	pages := rds.NewDescribeDBInstancesPaginator(RDSClient, input)
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(run.ctx())
+		if err != nil {
+			return nil, err
+		}
+		RDSInstances = append(RDSInstances, page.DBInstances...)
+	}
+	return RDSInstances, nil
 }
 
-func startRDSInstance(client IRDSInstancesAPI, dbInstanceIdentifier string) {
+func startRDSInstance(client IRDSInstancesAPI, run *SchedulingRun, dbInstanceIdentifier string) error {
 	input := &rds.StartDBInstanceInput{
 		DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
 	}
 
-	_, err := client.StartDBInstance(context.TODO(), input)
+	_, err := client.StartDBInstance(run.ctx(), input)
 	if err == nil {
-		log.Printf("INFO: Successfully started RDS instance with Identifier %v\n", dbInstanceIdentifier)
+		run.Printf("INFO: Successfully started RDS instance with Identifier %v\n", dbInstanceIdentifier)
 	} else {
-		log.Printf("ERROR: Could not start RDS instance: %v\n", err)
+		run.Printf("ERROR: Could not start RDS instance: %v\n", err)
 	}
+	return err
 }
 
-func stopRDSInstance(client IRDSInstancesAPI, dbInstanceIdentifier string) {
+func stopRDSInstance(client IRDSInstancesAPI, run *SchedulingRun, dbInstanceIdentifier string) error {
 	input := &rds.StopDBInstanceInput{
 		DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
 	}
 
-	_, err := client.StopDBInstance(context.TODO(), input)
+	_, err := client.StopDBInstance(run.ctx(), input)
 	if err == nil {
-		log.Printf("INFO: Successfully stopped RDS instance with Identifier %v\n", dbInstanceIdentifier)
+		run.Printf("INFO: Successfully stopped RDS instance with Identifier %v\n", dbInstanceIdentifier)
 	} else {
-		log.Printf("ERROR: Could not stop RDS instance: %v\n", err)
+		run.Printf("ERROR: Could not stop RDS instance: %v\n", err)
 	}
+	return err
 }
 
-func stopRDSInstances(RDSClient IRDSInstancesAPI) *RDSInstanceCount {
-	result, err := RDSClient.DescribeDBInstances(context.TODO(), &rds.DescribeDBInstancesInput{})
+func stopRDSInstances(RDSClient IRDSInstancesAPI, run *SchedulingRun) (*RDSInstanceCount, error) {
+	RDSInstances, err := listRDSInstances(RDSClient, run)
 	if err != nil {
-		log.Print("ERROR: Could not retrieve information about Amazon RDS instances in member account:\n", err)
-		return &RDSInstanceCount{RDSActedUpon: 0, RDSSkipped: 0}
+		return nil, fmt.Errorf("could not retrieve information about Amazon RDS instances: %w", err)
 	}
 
 	instancesActedUpon := []string{}
 	skippedInstances := []string{}
-
-	for _, RDSInstance := range result.DBInstances {
-		log.Printf("INFO: RDS Instance Identifier: [ %v ]\n", *RDSInstance.DBInstanceIdentifier)
-		instanceSchedulingTag, skipInstance, skippedInstancesModified := parseRDSInstanceTags(RDSInstance, skippedInstances)
+	failedInstances := []string{}
+	alreadyStoppedInstances := []string{}
+	notActionableInstances := []string{}
+
+	for _, RDSInstance := range RDSInstances {
+		run.Printf("INFO: RDS Instance Identifier: [ %v ]\n", *RDSInstance.DBInstanceIdentifier)
+		resource := newRDSInstanceResource(RDSClient, RDSInstance)
+		if RDSInstance.DBClusterIdentifier != nil {
+			run.Printf("INFO: Skipped RDS instance because it is a member of DB cluster %v, which is scheduled as a whole\n", *RDSInstance.DBClusterIdentifier)
+			continue
+		}
+		instanceSchedulingTag, overrideUntil, _, skipInstance, skippedInstancesModified := parseResourceTags(run, resource, skippedInstances)
 		skippedInstances = skippedInstancesModified
 
 		if skipInstance {
 			continue
 		}
 
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		if isOverrideActive(overrideUntil, run.Now) {
+			skippedInstances = append(skippedInstances, *RDSInstance.DBInstanceIdentifier)
+			run.Printf("INFO: Skipped RDS instance because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+			continue
+		}
+
+		if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+			skippedInstances = append(skippedInstances, *RDSInstance.DBInstanceIdentifier)
+			run.Printf("INFO: Skipped RDS instance because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+			continue
+		}
+
 		if instanceSchedulingTag == "skip-auto-stop" {
 			skippedInstances = append(skippedInstances, *RDSInstance.DBInstanceIdentifier)
-			log.Printf("INFO: Skipped RDS instance because instance-scheduling tag having value 'skip-auto-stop'\n")
+			run.Printf("INFO: Skipped RDS instance because instance-scheduling tag having value 'skip-auto-stop'\n")
 			continue
 		}
 
+		instanceStatus := aws.ToString(RDSInstance.DBInstanceStatus)
+		if instanceStatus == "stopped" || instanceStatus == "stopping" {
+			alreadyStoppedInstances = append(alreadyStoppedInstances, *RDSInstance.DBInstanceIdentifier)
+			run.Printf("INFO: Skipped RDS instance because it is already %v\n", instanceStatus)
+			continue
+		}
+		if instanceStatus != "available" {
+			notActionableInstances = append(notActionableInstances, *RDSInstance.DBInstanceIdentifier)
+			run.Printf("INFO: Skipped RDS instance because it cannot be stopped while %v\n", instanceStatus)
+			continue
+		}
+
+		run.Printf("INFO: Stopping RDS instance because instance-scheduling tag is absent\n")
+		if stopRDSInstance(RDSClient, run, *RDSInstance.DBInstanceIdentifier) != nil {
+			failedInstances = append(failedInstances, *RDSInstance.DBInstanceIdentifier)
+			continue
+		}
+		tagResourceStoppedByScheduler(run, resource)
 		instancesActedUpon = append(instancesActedUpon, *RDSInstance.DBInstanceIdentifier)
-		stopRDSInstance(RDSClient, *RDSInstance.DBInstanceIdentifier)
-		log.Printf("INFO: Stopped RDS instance because instance-scheduling tag is absent\n")
 	}
 
-	log.Printf("INFO: Stopped %v instances: %v\n", len(instancesActedUpon), instancesActedUpon)
-	log.Printf("INFO: Skipped %v instances due to instance-scheduling tag: %v\n", len(skippedInstances), skippedInstances)
+	run.Printf("INFO: Stopped %v instances: %v\n", len(instancesActedUpon), instancesActedUpon)
+	run.Printf("INFO: Skipped %v instances due to instance-scheduling tag: %v\n", len(skippedInstances), skippedInstances)
+	run.Printf("INFO: Skipped %v RDS instances which were already stopped: %v\n", len(alreadyStoppedInstances), alreadyStoppedInstances)
+	run.Printf("INFO: Skipped %v RDS instances which could not be stopped in their current status: %v\n", len(notActionableInstances), notActionableInstances)
+	run.Printf("INFO: Could not stop %v RDS instances: %v\n", len(failedInstances), failedInstances)
 
-	return &RDSInstanceCount{RDSActedUpon: len(instancesActedUpon), RDSSkipped: len(skippedInstances)}
+	return &RDSInstanceCount{RDSActedUpon: len(instancesActedUpon), RDSSkipped: len(skippedInstances), RDSAlreadyInDesiredState: len(alreadyStoppedInstances), RDSNotActionable: len(notActionableInstances), RDSFailed: len(failedInstances)}, nil
 }
 
-func startRDSInstances(RDSClient IRDSInstancesAPI) *RDSInstanceCount {
-	result, err := RDSClient.DescribeDBInstances(context.TODO(), &rds.DescribeDBInstancesInput{})
+func startRDSInstances(RDSClient IRDSInstancesAPI, run *SchedulingRun) (*RDSInstanceCount, error) {
+	RDSInstances, err := listRDSInstances(RDSClient, run)
 	if err != nil {
-		log.Print("ERROR: Could not retrieve information about Amazon RDS instances in member account:\n", err)
-		return &RDSInstanceCount{RDSActedUpon: 0, RDSSkipped: 0}
+		return nil, fmt.Errorf("could not retrieve information about Amazon RDS instances: %w", err)
 	}
 
 	instancesActedUpon := []string{}
 	skippedInstances := []string{}
-
-	for _, RDSInstance := range result.DBInstances {
-		log.Printf("INFO: RDS Instance Identifier: [ %v ]\n", *RDSInstance.DBInstanceIdentifier)
-		instanceSchedulingTag, skipInstance, skippedInstancesModified := parseRDSInstanceTags(RDSInstance, skippedInstances)
+	failedInstances := []string{}
+	stoppedNotBySchedulerInstances := []string{}
+	alreadyRunningInstances := []string{}
+	notActionableInstances := []string{}
+
+	for _, RDSInstance := range RDSInstances {
+		run.Printf("INFO: RDS Instance Identifier: [ %v ]\n", *RDSInstance.DBInstanceIdentifier)
+		resource := newRDSInstanceResource(RDSClient, RDSInstance)
+		if RDSInstance.DBClusterIdentifier != nil {
+			run.Printf("INFO: Skipped RDS instance because it is a member of DB cluster %v, which is scheduled as a whole\n", *RDSInstance.DBClusterIdentifier)
+			continue
+		}
+		instanceSchedulingTag, overrideUntil, stoppedByScheduler, skipInstance, skippedInstancesModified := parseResourceTags(run, resource, skippedInstances)
 		skippedInstances = skippedInstancesModified
 
 		if skipInstance {
 			continue
 		}
 
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+			skippedInstances = append(skippedInstances, *RDSInstance.DBInstanceIdentifier)
+			run.Printf("INFO: Skipped RDS instance because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+			continue
+		}
+
 		if instanceSchedulingTag == "skip-auto-start" {
 			skippedInstances = append(skippedInstances, *RDSInstance.DBInstanceIdentifier)
-			log.Printf("INFO: Skipped RDS instance because instance-scheduling tag having value 'skip-auto-start'\n")
+			run.Printf("INFO: Skipped RDS instance because instance-scheduling tag having value 'skip-auto-start'\n")
+			continue
+		}
+
+		instanceStatus := aws.ToString(RDSInstance.DBInstanceStatus)
+		if instanceStatus == "available" || instanceStatus == "starting" {
+			alreadyRunningInstances = append(alreadyRunningInstances, *RDSInstance.DBInstanceIdentifier)
+			run.Printf("INFO: Skipped RDS instance because it is already %v\n", instanceStatus)
+			if stoppedByScheduler {
+				untagResourceStoppedByScheduler(run, resource)
+			}
+			continue
+		}
+		if instanceStatus != "stopped" {
+			notActionableInstances = append(notActionableInstances, *RDSInstance.DBInstanceIdentifier)
+			run.Printf("INFO: Skipped RDS instance because it cannot be started while %v\n", instanceStatus)
 			continue
 		}
 
+		if !stoppedByScheduler {
+			stoppedNotBySchedulerInstances = append(stoppedNotBySchedulerInstances, *RDSInstance.DBInstanceIdentifier)
+			run.Printf("INFO: Skipped RDS instance because it was not stopped by the scheduler\n")
+			continue
+		}
+
+		run.Printf("INFO: Starting RDS instance because it was stopped by the scheduler\n")
+		if startRDSInstance(RDSClient, run, *RDSInstance.DBInstanceIdentifier) != nil {
+			failedInstances = append(failedInstances, *RDSInstance.DBInstanceIdentifier)
+			continue
+		}
+		untagResourceStoppedByScheduler(run, resource)
 		instancesActedUpon = append(instancesActedUpon, *RDSInstance.DBInstanceIdentifier)
-		startRDSInstance(RDSClient, *RDSInstance.DBInstanceIdentifier)
-		log.Printf("INFO: Started RDS instance because instance-scheduling tag is absent\n")
 	}
 
-	log.Printf("INFO: Started %v RDS instances: %v\n", len(instancesActedUpon), instancesActedUpon)
-	log.Printf("INFO: Skipped %v RDS instances due to instance-scheduling tag: %v\n", len(skippedInstances), skippedInstances)
+	run.Printf("INFO: Started %v RDS instances: %v\n", len(instancesActedUpon), instancesActedUpon)
+	run.Printf("INFO: Skipped %v RDS instances due to instance-scheduling tag or not being stopped by the scheduler: %v\n", len(skippedInstances), skippedInstances)
+	run.Printf("INFO: Found %v stopped RDS instances which were not stopped by the scheduler: %v\n", len(stoppedNotBySchedulerInstances), stoppedNotBySchedulerInstances)
+	run.Printf("INFO: Skipped %v RDS instances which were already running: %v\n", len(alreadyRunningInstances), alreadyRunningInstances)
+	run.Printf("INFO: Skipped %v RDS instances which could not be started in their current status: %v\n", len(notActionableInstances), notActionableInstances)
+	run.Printf("INFO: Could not start %v RDS instances: %v\n", len(failedInstances), failedInstances)
 
-	return &RDSInstanceCount{RDSActedUpon: len(instancesActedUpon), RDSSkipped: len(skippedInstances)}
+	return &RDSInstanceCount{RDSActedUpon: len(instancesActedUpon), RDSSkipped: len(skippedInstances), RDSStoppedNotByScheduler: len(stoppedNotBySchedulerInstances), RDSAlreadyInDesiredState: len(alreadyRunningInstances), RDSNotActionable: len(notActionableInstances), RDSFailed: len(failedInstances)}, nil
 }
 
-func testRDSInstances(RDSClient IRDSInstancesAPI) *RDSInstanceCount {
-	result, err := RDSClient.DescribeDBInstances(context.TODO(), &rds.DescribeDBInstancesInput{})
+func testRDSInstances(RDSClient IRDSInstancesAPI, run *SchedulingRun) (*RDSInstanceCount, error) {
+	RDSInstances, err := listRDSInstances(RDSClient, run)
 	if err != nil {
-		log.Print("ERROR: Could not retrieve information about Amazon RDS instances in member account:\n", err)
-		return &RDSInstanceCount{RDSActedUpon: 0, RDSSkipped: 0}
+		return nil, fmt.Errorf("could not retrieve information about Amazon RDS instances: %w", err)
 	}
 
 	instancesActedUpon := []string{}
 	skippedInstances := []string{}
 
-	for _, RDSInstance := range result.DBInstances {
-		log.Printf("INFO: RDS Instance Identifier: [ %v ]\n", *RDSInstance.DBInstanceIdentifier)
-		instanceSchedulingTag, skipInstance, skippedInstancesModified := parseRDSInstanceTags(RDSInstance, skippedInstances)
+	for _, RDSInstance := range RDSInstances {
+		run.Printf("INFO: RDS Instance Identifier: [ %v ]\n", *RDSInstance.DBInstanceIdentifier)
+		resource := newRDSInstanceResource(RDSClient, RDSInstance)
+		if RDSInstance.DBClusterIdentifier != nil {
+			run.Printf("INFO: Skipped RDS instance because it is a member of DB cluster %v, which is scheduled as a whole\n", *RDSInstance.DBClusterIdentifier)
+			continue
+		}
+		instanceSchedulingTag, overrideUntil, _, skipInstance, skippedInstancesModified := parseResourceTags(run, resource, skippedInstances)
 		skippedInstances = skippedInstancesModified
 
 		if skipInstance {
 			continue
 		}
 
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		if isOverrideActive(overrideUntil, run.Now) {
+			skippedInstances = append(skippedInstances, *RDSInstance.DBInstanceIdentifier)
+			run.Printf("INFO: Skipped RDS instance because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+			continue
+		}
+
+		if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+			skippedInstances = append(skippedInstances, *RDSInstance.DBInstanceIdentifier)
+			run.Printf("INFO: Skipped RDS instance because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+			continue
+		}
+
 		if instanceSchedulingTag == "skip-auto-stop" || instanceSchedulingTag == "skip-auto-start" {
 			skippedInstances = append(skippedInstances, *RDSInstance.DBInstanceIdentifier)
-			log.Printf("INFO: Skipped RDS instance with DB instance identifier %v because instance-scheduling tag having value 'skip-auto-stop' or 'skip-auto-start'", *RDSInstance.DBInstanceIdentifier)
+			run.Printf("INFO: Skipped RDS instance with DB instance identifier %v because instance-scheduling tag having value 'skip-auto-stop' or 'skip-auto-start'", *RDSInstance.DBInstanceIdentifier)
 			continue
 		}
 
 		instancesActedUpon = append(instancesActedUpon, *RDSInstance.DBInstanceIdentifier)
-		log.Printf("INFO: Successfully tested RDS instance with DB instance identifier %v because instance-scheduling tag is absent\n", *RDSInstance.DBInstanceIdentifier)
+		run.Printf("INFO: Successfully tested RDS instance with DB instance identifier %v because instance-scheduling tag is absent\n", *RDSInstance.DBInstanceIdentifier)
 	}
 
-	log.Printf("INFO: Started %v RDS instances: %v\n", len(instancesActedUpon), instancesActedUpon)
-	log.Printf("INFO: Skipped %v RDS instances due to instance-scheduling tag: %v\n", len(skippedInstances), skippedInstances)
+	run.Printf("INFO: Started %v RDS instances: %v\n", len(instancesActedUpon), instancesActedUpon)
+	run.Printf("INFO: Skipped %v RDS instances due to instance-scheduling tag: %v\n", len(skippedInstances), skippedInstances)
 
-	return &RDSInstanceCount{RDSActedUpon: len(instancesActedUpon), RDSSkipped: len(skippedInstances)}
+	return &RDSInstanceCount{RDSActedUpon: len(instancesActedUpon), RDSSkipped: len(skippedInstances)}, nil
 }
 
-func getRDSClientForMemberAccount(cfg aws.Config, accountName string, accountId string) IRDSInstancesAPI {
-	roleARN := fmt.Sprintf("arn:aws:iam::%v:role/InstanceSchedulerAccess", accountId)
-	stsClient := sts.NewFromConfig(cfg)
-	provider := stscreds.NewAssumeRoleProvider(stsClient, roleARN)
-	cfg.Credentials = aws.NewCredentialsCache(provider)
+func reconcileRDSInstances(RDSClient IRDSInstancesAPI, run *SchedulingRun) (*RDSInstanceCount, error) {
+	RDSInstances, err := listRDSInstances(RDSClient, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about Amazon RDS instances: %w", err)
+	}
+
+	instancesStarted := []string{}
+	instancesStopped := []string{}
+	instancesRestopped := []string{}
+	skippedInstances := []string{}
+	failedInstances := []string{}
 
-	// Create RDS client
-	rdsClient := rds.NewFromConfig(cfg)
-	rdsInput := &rds.DescribeDBInstancesInput{}
-	_, rdsErr := rdsClient.DescribeDBInstances(context.TODO(), rdsInput)
-	if rdsErr != nil {
-		if strings.Contains(rdsErr.Error(), "is not authorized to perform: sts:AssumeRole on resource") {
-			log.Printf("WARN: account %v is ignored because it does not have the role InstanceSchedulerAccess, therefore is not a member account\n", accountName)
-			return nil
-		} else {
-			log.Fatal(rdsErr)
+	for _, RDSInstance := range RDSInstances {
+		run.Printf("INFO: RDS Instance Identifier: [ %v ]\n", *RDSInstance.DBInstanceIdentifier)
+		resource := newRDSInstanceResource(RDSClient, RDSInstance)
+		if RDSInstance.DBClusterIdentifier != nil {
+			run.Printf("INFO: Skipped RDS instance because it is a member of DB cluster %v, which is scheduled as a whole\n", *RDSInstance.DBClusterIdentifier)
+			continue
+		}
+		instanceSchedulingTag, overrideUntil, _, skipInstance, skippedInstancesModified := parseResourceTags(run, resource, skippedInstances)
+		skippedInstances = skippedInstancesModified
+
+		if skipInstance {
+			continue
+		}
+
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		// an instance started by AWS is stopped again whenever it should be stopped now, whether it has no schedule or
+		// its schedule expects it to be stopped
+		schedule := run.Schedules.getSchedule(run, instanceSchedulingTag)
+		shouldBeStopped := schedule == nil || run.desiredState(schedule) == scheduleStateStopped
+		if shouldBeStopped && isRDSInstanceAutoStarted(RDSClient, run, RDSInstance) {
+			if isOverrideActive(overrideUntil, run.Now) {
+				skippedInstances = append(skippedInstances, *RDSInstance.DBInstanceIdentifier)
+				run.Printf("INFO: Skipped stopping RDS instance started by AWS because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+				continue
+			}
+			run.Printf("INFO: Stopping RDS instance again because AWS started it after it had been stopped by the scheduler for seven days\n")
+			if stopRDSInstance(RDSClient, run, *RDSInstance.DBInstanceIdentifier) != nil {
+				failedInstances = append(failedInstances, *RDSInstance.DBInstanceIdentifier)
+				continue
+			}
+			tagResourceStoppedByScheduler(run, resource)
+			instancesRestopped = append(instancesRestopped, *RDSInstance.DBInstanceIdentifier)
+			continue
+		}
+
+		if schedule == nil {
+			skippedInstances = append(skippedInstances, *RDSInstance.DBInstanceIdentifier)
+			run.Printf("INFO: Skipped RDS instance because its instance-scheduling tag does not reference a schedule\n")
+			continue
+		}
+
+		desiredState := run.desiredState(schedule)
+		instanceStatus := aws.ToString(RDSInstance.DBInstanceStatus)
+
+		if desiredState == scheduleStateRunning && instanceStatus == "stopped" {
+			run.Printf("INFO: Starting RDS instance because schedule '%v' expects it to be running\n", schedule.Name)
+			if startRDSInstance(RDSClient, run, *RDSInstance.DBInstanceIdentifier) != nil {
+				failedInstances = append(failedInstances, *RDSInstance.DBInstanceIdentifier)
+				continue
+			}
+			untagResourceStoppedByScheduler(run, resource)
+			instancesStarted = append(instancesStarted, *RDSInstance.DBInstanceIdentifier)
+			continue
 		}
+
+		if desiredState == scheduleStateStopped && instanceStatus == "available" && isOverrideActive(overrideUntil, run.Now) {
+			skippedInstances = append(skippedInstances, *RDSInstance.DBInstanceIdentifier)
+			run.Printf("INFO: Skipped stopping RDS instance because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+			continue
+		}
+
+		if desiredState == scheduleStateStopped && instanceStatus == "available" {
+			run.Printf("INFO: Stopping RDS instance because schedule '%v' expects it to be stopped\n", schedule.Name)
+			if stopRDSInstance(RDSClient, run, *RDSInstance.DBInstanceIdentifier) != nil {
+				failedInstances = append(failedInstances, *RDSInstance.DBInstanceIdentifier)
+				continue
+			}
+			tagResourceStoppedByScheduler(run, resource)
+			instancesStopped = append(instancesStopped, *RDSInstance.DBInstanceIdentifier)
+			continue
+		}
+
+		skippedInstances = append(skippedInstances, *RDSInstance.DBInstanceIdentifier)
+		run.Printf("INFO: Skipped RDS instance with status '%v' because schedule '%v' expects it to be '%v'\n", instanceStatus, schedule.Name, desiredState)
 	}
-	return rdsClient
+
+	run.Printf("INFO: Started %v RDS instances: %v\n", len(instancesStarted), instancesStarted)
+	run.Printf("INFO: Stopped %v RDS instances: %v\n", len(instancesStopped), instancesStopped)
+	run.Printf("INFO: Stopped again %v RDS instances started by AWS: %v\n", len(instancesRestopped), instancesRestopped)
+	run.Printf("INFO: Skipped %v RDS instances due to instance-scheduling tag or schedule: %v\n", len(skippedInstances), skippedInstances)
+	run.Printf("INFO: Could not start or stop %v RDS instances: %v\n", len(failedInstances), failedInstances)
+
+	return &RDSInstanceCount{RDSActedUpon: len(instancesStarted) + len(instancesStopped) + len(instancesRestopped), RDSSkipped: len(skippedInstances), RDSAutoRestartedRestopped: len(instancesRestopped), RDSFailed: len(failedInstances)}, nil
+}
+
+// isRDSInstanceAutoStarted reports whether an available RDS instance was started by AWS rather than by the start
+// action or an engineer
+func isRDSInstanceAutoStarted(RDSClient IRDSEventsAPI, run *SchedulingRun, RDSInstance rdstype.DBInstance) bool {
+	return isRDSAutoStarted(RDSClient, run, rdstype.SourceTypeDbInstance, *RDSInstance.DBInstanceIdentifier, aws.ToString(RDSInstance.DBInstanceStatus), RDSInstance.TagList)
+}
+
+// isRDSAutoStarted reports whether an available DB instance or cluster was started by AWS. That is only possible when
+// it is still tagged as stopped by the scheduler at least seven days ago, and is confirmed by the event RDS records
+// when it starts it itself.
+func isRDSAutoStarted(RDSClient IRDSEventsAPI, run *SchedulingRun, sourceType rdstype.SourceType, identifier string, status string, tagList []rdstype.Tag) bool {
+	if status != "available" {
+		return false
+	}
+	stoppedByScheduler := false
+	var stoppedAt time.Time
+	for _, tag := range tagList {
+		if isStoppedBySchedulerTag(*tag.Key, *tag.Value) {
+			stoppedByScheduler = true
+		}
+		if *tag.Key == stoppedAtTagKey {
+			stoppedAt = parseStoppedAtTag(*tag.Value)
+		}
+	}
+	if !stoppedByScheduler || stoppedAt.IsZero() || run.Now.Sub(stoppedAt) < rdsAutoStartAfter {
+		return false
+	}
+
+	// AWS starts it once rdsAutoStartAfter has passed, so an engineer who started it did so before then
+	pages := rds.NewDescribeEventsPaginator(RDSClient, &rds.DescribeEventsInput{
+		SourceIdentifier: aws.String(identifier),
+		SourceType:       sourceType,
+		StartTime:        aws.Time(stoppedAt.Add(rdsAutoStartAfter)),
+		EndTime:          aws.Time(run.Now),
+		EventCategories:  []string{rdsAutoStartEventCategory},
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(run.ctx())
+		if err != nil {
+			run.Printf("ERROR: Could not retrieve events of %v %v, so it is not stopped again: %v\n", sourceType, identifier, err)
+			return false
+		}
+		for _, event := range page.Events {
+			if slices.Contains(event.EventCategories, rdsAutoStartEventCategory) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IRDSTaggingAPI is the part of the RDS API which tags DB instances and clusters
+type IRDSTaggingAPI interface {
+	AddTagsToResource(ctx context.Context, params *rds.AddTagsToResourceInput, optFns ...func(*rds.Options)) (*rds.AddTagsToResourceOutput, error)
+	RemoveTagsFromResource(ctx context.Context, params *rds.RemoveTagsFromResourceInput, optFns ...func(*rds.Options)) (*rds.RemoveTagsFromResourceOutput, error)
+}
+
+// rdsResource adapts an RDS instance or DB cluster to the tag helpers shared by every service
+type rdsResource struct {
+	client       IRDSTaggingAPI
+	resourceKind string
+	identifier   string
+	arn          *string
+	tagList      []rdstype.Tag
+}
+
+func newRDSInstanceResource(RDSClient IRDSTaggingAPI, RDSInstance rdstype.DBInstance) rdsResource {
+	return rdsResource{
+		client:       RDSClient,
+		resourceKind: "RDS instance",
+		identifier:   aws.ToString(RDSInstance.DBInstanceIdentifier),
+		arn:          RDSInstance.DBInstanceArn,
+		tagList:      RDSInstance.TagList,
+	}
+}
+
+func newRDSClusterResource(RDSClient IRDSTaggingAPI, cluster rdstype.DBCluster) rdsResource {
+	return rdsResource{
+		client:       RDSClient,
+		resourceKind: "DB cluster",
+		identifier:   aws.ToString(cluster.DBClusterIdentifier),
+		arn:          cluster.DBClusterArn,
+		tagList:      cluster.TagList,
+	}
+}
+
+func (resource rdsResource) kind() string {
+	return resource.resourceKind
+}
+
+func (resource rdsResource) id() string {
+	return resource.identifier
+}
+
+func (resource rdsResource) tags() map[string]string {
+	tags := map[string]string{}
+	for _, tag := range resource.tagList {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags
+}
+
+func (resource rdsResource) addTags(run *SchedulingRun, tags []resourceTag) error {
+	rdsTags := []rdstype.Tag{}
+	for _, tag := range tags {
+		rdsTags = append(rdsTags, rdstype.Tag{Key: aws.String(tag.key), Value: aws.String(tag.value)})
+	}
+	_, err := resource.client.AddTagsToResource(run.ctx(), &rds.AddTagsToResourceInput{
+		ResourceName: resource.arn,
+		Tags:         rdsTags,
+	})
+	return err
+}
+
+func (resource rdsResource) removeTags(run *SchedulingRun, keys []string) error {
+	_, err := resource.client.RemoveTagsFromResource(run.ctx(), &rds.RemoveTagsFromResourceInput{
+		ResourceName: resource.arn,
+		TagKeys:      keys,
+	})
+	return err
+}
+
+func getRDSClientForMemberAccount(session *MemberAccountSession) IRDSInstancesAPI {
+	return rds.NewFromConfig(session.Config)
 }
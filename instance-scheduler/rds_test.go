@@ -2,30 +2,75 @@ package main
 
 import (
 	"context"
+	"errors"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/rds"
 	rdstype "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/stretchr/testify/assert"
 )
 
 type mockIRDSInstancesAPI struct {
 	DescribeDBInstancesOutput *rds.DescribeDBInstancesOutput
+	DescribeDBInstancesPages  []*rds.DescribeDBInstancesOutput
+	DescribeDBInstancesInputs []*rds.DescribeDBInstancesInput
 	StartDBInstanceOutput     *rds.StartDBInstanceOutput
 	StopDBInstanceOutput      *rds.StopDBInstanceOutput
+	StopDBInstanceError       error
+	StoppedInstances          []string
+	AddTagsInputs             []*rds.AddTagsToResourceInput
+	RemoveTagsInputs          []*rds.RemoveTagsFromResourceInput
+	Events                    map[string][]rdstype.Event
+	DescribeEventsInputs      []*rds.DescribeEventsInput
 }
 
 func (m *mockIRDSInstancesAPI) DescribeDBInstances(ctx context.Context, params *rds.DescribeDBInstancesInput, optFns ...func(*rds.Options)) (*rds.DescribeDBInstancesOutput, error) {
-	return m.DescribeDBInstancesOutput, nil
+	m.DescribeDBInstancesInputs = append(m.DescribeDBInstancesInputs, params)
+	if len(m.DescribeDBInstancesPages) == 0 {
+		return m.DescribeDBInstancesOutput, nil
+	}
+
+	// pages are chained by their index, as Marker
+	page := 0
+	if params.Marker != nil {
+		page, _ = strconv.Atoi(*params.Marker)
+	}
+	output := *m.DescribeDBInstancesPages[page]
+	if page+1 < len(m.DescribeDBInstancesPages) {
+		output.Marker = aws.String(strconv.Itoa(page + 1))
+	}
+	return &output, nil
 }
 
 func (m *mockIRDSInstancesAPI) StopDBInstance(ctx context.Context, params *rds.StopDBInstanceInput, optFns ...func(*rds.Options)) (*rds.StopDBInstanceOutput, error) {
+	if m.StopDBInstanceError != nil {
+		return nil, m.StopDBInstanceError
+	}
+	m.StoppedInstances = append(m.StoppedInstances, *params.DBInstanceIdentifier)
 	return m.StopDBInstanceOutput, nil
 }
 
 func (m *mockIRDSInstancesAPI) StartDBInstance(ctx context.Context, params *rds.StartDBInstanceInput, optFns ...func(*rds.Options)) (*rds.StartDBInstanceOutput, error) {
 	return m.StartDBInstanceOutput, nil
 }
+
+func (m *mockIRDSInstancesAPI) AddTagsToResource(ctx context.Context, params *rds.AddTagsToResourceInput, optFns ...func(*rds.Options)) (*rds.AddTagsToResourceOutput, error) {
+	m.AddTagsInputs = append(m.AddTagsInputs, params)
+	return &rds.AddTagsToResourceOutput{}, nil
+}
+
+func (m *mockIRDSInstancesAPI) RemoveTagsFromResource(ctx context.Context, params *rds.RemoveTagsFromResourceInput, optFns ...func(*rds.Options)) (*rds.RemoveTagsFromResourceOutput, error) {
+	m.RemoveTagsInputs = append(m.RemoveTagsInputs, params)
+	return &rds.RemoveTagsFromResourceOutput{}, nil
+}
+
+func (m *mockIRDSInstancesAPI) DescribeEvents(ctx context.Context, params *rds.DescribeEventsInput, optFns ...func(*rds.Options)) (*rds.DescribeEventsOutput, error) {
+	m.DescribeEventsInputs = append(m.DescribeEventsInputs, params)
+	return &rds.DescribeEventsOutput{Events: m.Events[*params.SourceIdentifier]}, nil
+}
 func TestStopStartTestRDSInstancesInMemberAccount(t *testing.T) {
 	tests := []struct {
 		testTitle     string
@@ -106,16 +151,22 @@ func TestStopStartTestRDSInstancesInMemberAccount(t *testing.T) {
 				},
 			},
 			action:        "test",
-			expectedCount: RDSInstanceCount{4, 3},
+			expectedCount: RDSInstanceCount{RDSActedUpon: 4, RDSSkipped: 3},
 		},
 		{
 			testTitle: "RDS testing Stop action",
 			client: &mockIRDSInstancesAPI{
 				DescribeDBInstancesOutput: &rds.DescribeDBInstancesOutput{
 					DBInstances: []rdstype.DBInstance{
+						// member of an Aurora cluster, therefore left to the cluster and not counted
+						{
+							DBInstanceIdentifier: aws.String("test-aurora-instance-1"),
+							DBClusterIdentifier:  aws.String("test-aurora"),
+						},
 						// RDS instance-scheduling = default, therefore schedule an instance, acted upon: 1
 						{
 							DBInstanceIdentifier: aws.String("test-database"),
+							DBInstanceStatus:     aws.String("available"),
 							TagList: []rdstype.Tag{
 								{
 									Key:   aws.String("instance-scheduling"),
@@ -126,10 +177,12 @@ func TestStopStartTestRDSInstancesInMemberAccount(t *testing.T) {
 						// no RDS instance-scheduling and no aws:autoscaling:groupName tags, therefore schedule an instance, acted upon: 1
 						{
 							DBInstanceIdentifier: aws.String("test-database-2"),
+							DBInstanceStatus:     aws.String("available"),
 						},
 						// RDS instance-scheduling = skip-scheduling, therefore skip scheduling, skipped: 1
 						{
 							DBInstanceIdentifier: aws.String("i-2162279001"),
+							DBInstanceStatus:     aws.String("available"),
 							TagList: []rdstype.Tag{
 								{
 									Key:   aws.String("instance-scheduling"),
@@ -140,6 +193,7 @@ func TestStopStartTestRDSInstancesInMemberAccount(t *testing.T) {
 						// RDS instance-scheduling is set to an empty string, therefore ignore the tag and auto schedule, acted upon: 1
 						{
 							DBInstanceIdentifier: aws.String("test-database-3"),
+							DBInstanceStatus:     aws.String("available"),
 							TagList: []rdstype.Tag{
 								{
 									Key:   aws.String("instance-scheduling"),
@@ -150,6 +204,7 @@ func TestStopStartTestRDSInstancesInMemberAccount(t *testing.T) {
 						// RDS instance-scheduling = "invalid-value", therefore ignore the tag and auto schedule, acted upon: 1
 						{
 							DBInstanceIdentifier: aws.String("test-database-4"),
+							DBInstanceStatus:     aws.String("available"),
 							TagList: []rdstype.Tag{
 								{
 									Key:   aws.String("instance-scheduling"),
@@ -160,6 +215,7 @@ func TestStopStartTestRDSInstancesInMemberAccount(t *testing.T) {
 						// RDS instance-scheduling = skip-auto-stop, therefore skip auto stop, skipped: 1
 						{
 							DBInstanceIdentifier: aws.String("test-database-5"),
+							DBInstanceStatus:     aws.String("available"),
 							TagList: []rdstype.Tag{
 								{
 									Key:   aws.String("instance-scheduling"),
@@ -170,6 +226,7 @@ func TestStopStartTestRDSInstancesInMemberAccount(t *testing.T) {
 						// RDS instance-scheduling = skip-auto-start, therefore skip auto start, but not stop, acted upon: 1
 						{
 							DBInstanceIdentifier: aws.String("test-database-6"),
+							DBInstanceStatus:     aws.String("available"),
 							TagList: []rdstype.Tag{
 								{
 									Key:   aws.String("instance-scheduling"),
@@ -181,7 +238,7 @@ func TestStopStartTestRDSInstancesInMemberAccount(t *testing.T) {
 				},
 			},
 			action:        "stop",
-			expectedCount: RDSInstanceCount{5, 2},
+			expectedCount: RDSInstanceCount{RDSActedUpon: 5, RDSSkipped: 2},
 		},
 		{
 			testTitle: "RDS testing Start action",
@@ -191,7 +248,12 @@ func TestStopStartTestRDSInstancesInMemberAccount(t *testing.T) {
 						// instance-scheduling = default, therefore schedule an instance, acted upon: 1
 						{
 							DBInstanceIdentifier: aws.String("test-database"),
+							DBInstanceStatus:     aws.String("stopped"),
 							TagList: []rdstype.Tag{
+								{
+									Key:   aws.String("instance-scheduler:stopped-by"),
+									Value: aws.String("scheduler"),
+								},
 								{
 									Key:   aws.String("instance-scheduling"),
 									Value: aws.String("default"),
@@ -201,11 +263,33 @@ func TestStopStartTestRDSInstancesInMemberAccount(t *testing.T) {
 						// no RDS instance-scheduling and no aws:autoscaling:groupName tags, therefore schedule an instance, acted upon: 1
 						{
 							DBInstanceIdentifier: aws.String("test-database-2"),
+							DBInstanceStatus:     aws.String("stopped"),
+							TagList: []rdstype.Tag{
+								{
+									Key:   aws.String("instance-scheduler:stopped-by"),
+									Value: aws.String("scheduler"),
+								},
+							},
+						},
+						// stopped without the instance-scheduler:stopped-by tag, therefore not stopped by the scheduler, stopped not by scheduler: 1
+						{
+							DBInstanceIdentifier: aws.String("test-database-8"),
+							DBInstanceStatus:     aws.String("stopped"),
+						},
+						// available without the instance-scheduler:stopped-by tag, therefore already running, already in desired state: 1
+						{
+							DBInstanceIdentifier: aws.String("test-database-9"),
+							DBInstanceStatus:     aws.String("available"),
 						},
 						// RDS instance-scheduling = skip-scheduling, therefore skip scheduling, skipped: 1
 						{
 							DBInstanceIdentifier: aws.String("test-database-7"),
+							DBInstanceStatus:     aws.String("stopped"),
 							TagList: []rdstype.Tag{
+								{
+									Key:   aws.String("instance-scheduler:stopped-by"),
+									Value: aws.String("scheduler"),
+								},
 								{
 									Key:   aws.String("instance-scheduling"),
 									Value: aws.String("skip-scheduling"),
@@ -215,7 +299,12 @@ func TestStopStartTestRDSInstancesInMemberAccount(t *testing.T) {
 						// RDS instance-scheduling is set to an empty string, therefore ignore the tag and auto schedule, acted upon: 1
 						{
 							DBInstanceIdentifier: aws.String("test-database-3"),
+							DBInstanceStatus:     aws.String("stopped"),
 							TagList: []rdstype.Tag{
+								{
+									Key:   aws.String("instance-scheduler:stopped-by"),
+									Value: aws.String("scheduler"),
+								},
 								{
 									Key:   aws.String("instance-scheduling"),
 									Value: aws.String(""),
@@ -225,7 +314,12 @@ func TestStopStartTestRDSInstancesInMemberAccount(t *testing.T) {
 						// RDS instance-scheduling = "invalid-value", therefore ignore the tag and auto schedule, acted upon: 1
 						{
 							DBInstanceIdentifier: aws.String("test-database-4"),
+							DBInstanceStatus:     aws.String("stopped"),
 							TagList: []rdstype.Tag{
+								{
+									Key:   aws.String("instance-scheduler:stopped-by"),
+									Value: aws.String("scheduler"),
+								},
 								{
 									Key:   aws.String("instance-scheduling"),
 									Value: aws.String("invalid-value"),
@@ -235,7 +329,12 @@ func TestStopStartTestRDSInstancesInMemberAccount(t *testing.T) {
 						// RDS instance-scheduling = skip-auto-stop, therefore skip auto stop, but not start, acted upon: 1
 						{
 							DBInstanceIdentifier: aws.String("test-database-5"),
+							DBInstanceStatus:     aws.String("stopped"),
 							TagList: []rdstype.Tag{
+								{
+									Key:   aws.String("instance-scheduler:stopped-by"),
+									Value: aws.String("scheduler"),
+								},
 								{
 									Key:   aws.String("instance-scheduling"),
 									Value: aws.String("skip-auto-stop"),
@@ -245,7 +344,12 @@ func TestStopStartTestRDSInstancesInMemberAccount(t *testing.T) {
 						// RDS instance-scheduling = skip-auto-start, therefore skip auto start, skipped: 1
 						{
 							DBInstanceIdentifier: aws.String("test-database-6"),
+							DBInstanceStatus:     aws.String("stopped"),
 							TagList: []rdstype.Tag{
+								{
+									Key:   aws.String("instance-scheduler:stopped-by"),
+									Value: aws.String("scheduler"),
+								},
 								{
 									Key:   aws.String("instance-scheduling"),
 									Value: aws.String("skip-auto-start"),
@@ -256,16 +360,500 @@ func TestStopStartTestRDSInstancesInMemberAccount(t *testing.T) {
 				},
 			},
 			action:        "start",
-			expectedCount: RDSInstanceCount{5, 2},
+			expectedCount: RDSInstanceCount{RDSActedUpon: 5, RDSSkipped: 2, RDSStoppedNotByScheduler: 1, RDSAlreadyInDesiredState: 1},
+		},
+		{
+			testTitle: "RDS testing Reconcile action",
+			client: &mockIRDSInstancesAPI{
+				DescribeDBInstancesOutput: &rds.DescribeDBInstancesOutput{
+					DBInstances: []rdstype.DBInstance{
+						// no instance-scheduling tag, therefore left to the stop and start actions, skipped: 1
+						{
+							DBInstanceIdentifier: aws.String("test-database"),
+							DBInstanceStatus:     aws.String("available"),
+						},
+						// RDS instance-scheduling = skip-scheduling, therefore skip scheduling, skipped: 1
+						{
+							DBInstanceIdentifier: aws.String("test-database-2"),
+							DBInstanceStatus:     aws.String("available"),
+							TagList: []rdstype.Tag{
+								{
+									Key:   aws.String("instance-scheduling"),
+									Value: aws.String("skip-scheduling"),
+								},
+							},
+						},
+						// office-hours expects the instance to be running at midday and the instance is stopped, acted upon: 1
+						{
+							DBInstanceIdentifier: aws.String("test-database-3"),
+							DBInstanceStatus:     aws.String("stopped"),
+							TagList: []rdstype.Tag{
+								{
+									Key:   aws.String("instance-scheduling"),
+									Value: aws.String("office-hours"),
+								},
+							},
+						},
+						// night time schedule expects the instance to be stopped at midday and the instance is stopped, skipped: 1
+						{
+							DBInstanceIdentifier: aws.String("test-database-4"),
+							DBInstanceStatus:     aws.String("stopped"),
+							TagList: []rdstype.Tag{
+								{
+									Key:   aws.String("instance-scheduling"),
+									Value: aws.String("cron(0 19 ? * MON-FRI)/cron(0 7 ? * MON-FRI)"),
+								},
+							},
+						},
+						// night time schedule expects the instance to be stopped at midday and the instance is available, acted upon: 1
+						{
+							DBInstanceIdentifier: aws.String("test-database-5"),
+							DBInstanceStatus:     aws.String("available"),
+							TagList: []rdstype.Tag{
+								{
+									Key:   aws.String("instance-scheduling"),
+									Value: aws.String("cron(0 19 ? * MON-FRI)/cron(0 7 ? * MON-FRI)"),
+								},
+							},
+						},
+					},
+				},
+			},
+			action:        "reconcile",
+			expectedCount: RDSInstanceCount{RDSActedUpon: 2, RDSSkipped: 3},
 		},
 	}
 
 	for _, subtest := range tests {
 		t.Run(subtest.testTitle, func(t *testing.T) {
-			actualInstanceCount := StopStartTestRDSInstancesInMemberAccount(subtest.client, subtest.action)
+			actualInstanceCount, err := StopStartTestRDSInstancesInMemberAccount(subtest.client, &SchedulingRun{Action: subtest.action, Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+			assert.NoError(t, err)
+			if want, got := subtest.expectedCount, actualInstanceCount; want != *got {
+				t.Errorf("want %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestStopRDSInstancesWithOverrideUntilTag(t *testing.T) {
+	tests := []struct {
+		testTitle              string
+		action                 string
+		removeExpiredOverrides bool
+		instanceSchedulingTag  string
+		expectedCount          RDSInstanceCount
+		expectedRemovedTags    int
+	}{
+		{
+			testTitle:     "RDS testing Stop action keeps expired override tags",
+			action:        "stop",
+			expectedCount: RDSInstanceCount{RDSActedUpon: 2, RDSSkipped: 1},
+		},
+		{
+			testTitle:              "RDS testing Stop action removes expired override tags",
+			action:                 "stop",
+			removeExpiredOverrides: true,
+			expectedCount:          RDSInstanceCount{RDSActedUpon: 2, RDSSkipped: 1},
+			expectedRemovedTags:    1,
+		},
+		{
+			testTitle:              "RDS testing Reconcile action skips stopping during an override",
+			action:                 "reconcile",
+			removeExpiredOverrides: true,
+			instanceSchedulingTag:  "nights-only",
+			expectedCount:          RDSInstanceCount{RDSActedUpon: 1, RDSSkipped: 2},
+			expectedRemovedTags:    1,
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.testTitle, func(t *testing.T) {
+			client := &mockIRDSInstancesAPI{
+				DescribeDBInstancesOutput: &rds.DescribeDBInstancesOutput{
+					DBInstances: []rdstype.DBInstance{
+						// override until later today, therefore skip stopping, skipped: 1
+						{
+							DBInstanceIdentifier: aws.String("test-database"),
+							DBInstanceArn:        aws.String("arn:aws:rds:eu-west-2:123456789012:db:test-database"),
+							DBInstanceStatus:     aws.String("available"),
+							TagList: []rdstype.Tag{
+								{
+									Key:   aws.String("instance-scheduling"),
+									Value: aws.String(subtest.instanceSchedulingTag),
+								},
+								{
+									Key:   aws.String("instance-scheduling-override-until"),
+									Value: aws.String("2026-10-14T22:00:00+01:00"),
+								},
+							},
+						},
+						// override expired yesterday, therefore stop as normal, acted upon: 1
+						{
+							DBInstanceIdentifier: aws.String("test-database-2"),
+							DBInstanceArn:        aws.String("arn:aws:rds:eu-west-2:123456789012:db:test-database-2"),
+							DBInstanceStatus:     aws.String("available"),
+							TagList: []rdstype.Tag{
+								{
+									Key:   aws.String("instance-scheduling"),
+									Value: aws.String(subtest.instanceSchedulingTag),
+								},
+								{
+									Key:   aws.String("instance-scheduling-override-until"),
+									Value: aws.String("2026-10-13T22:00Z"),
+								},
+							},
+						},
+						// no override, acted upon by stop, skipped by reconcile as it is unscheduled: 1
+						{
+							DBInstanceIdentifier: aws.String("test-database-3"),
+							DBInstanceStatus:     aws.String("available"),
+						},
+					},
+				},
+			}
+			run := &SchedulingRun{Action: subtest.action, Now: testSchedulingTime, Schedules: testScheduleCatalogue, RemoveExpiredOverrides: subtest.removeExpiredOverrides}
+			actualInstanceCount, err := StopStartTestRDSInstancesInMemberAccount(client, run)
+			assert.NoError(t, err)
 			if want, got := subtest.expectedCount, actualInstanceCount; want != *got {
 				t.Errorf("want %v, got %v", want, got)
 			}
+			if want, got := subtest.expectedRemovedTags, len(client.RemoveTagsInputs); want != got {
+				t.Errorf("want %v removed tags, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestRDSStoppedBySchedulerTags(t *testing.T) {
+	tests := []struct {
+		testTitle           string
+		action              string
+		status              string
+		tags                []rdstype.Tag
+		expectedAddedTags   []rdstype.Tag
+		expectedRemovedTags []string
+	}{
+		{
+			testTitle: "RDS testing Stop action tags the instances it stops",
+			action:    "stop",
+			status:    "available",
+			expectedAddedTags: []rdstype.Tag{
+				{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+				{Key: aws.String("instance-scheduler:stopped-at"), Value: aws.String("2026-10-14T12:00:00Z")},
+			},
+		},
+		{
+			testTitle: "RDS testing Start action removes the tags from the instances it starts",
+			action:    "start",
+			status:    "stopped",
+			tags: []rdstype.Tag{
+				{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+			},
+			expectedRemovedTags: []string{"instance-scheduler:stopped-by", "instance-scheduler:stopped-at"},
+		},
+		{
+			testTitle: "RDS testing Start action leaves instances stopped by someone else",
+			action:    "start",
+			status:    "stopped",
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.testTitle, func(t *testing.T) {
+			client := &mockIRDSInstancesAPI{
+				DescribeDBInstancesOutput: &rds.DescribeDBInstancesOutput{
+					DBInstances: []rdstype.DBInstance{
+						{
+							DBInstanceIdentifier: aws.String("test-database"),
+							DBInstanceArn:        aws.String("arn:aws:rds:eu-west-2:123456789012:db:test-database"),
+							DBInstanceStatus:     aws.String(subtest.status),
+							TagList:              subtest.tags,
+						},
+					},
+				},
+			}
+			StopStartTestRDSInstancesInMemberAccount(client, &SchedulingRun{Action: subtest.action, Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+
+			var addedTags []rdstype.Tag
+			var removedTags []string
+			for _, input := range client.AddTagsInputs {
+				addedTags = append(addedTags, input.Tags...)
+			}
+			for _, input := range client.RemoveTagsInputs {
+				removedTags = append(removedTags, input.TagKeys...)
+			}
+			assert.Equal(t, subtest.expectedAddedTags, addedTags)
+			assert.Equal(t, subtest.expectedRemovedTags, removedTags)
+		})
+	}
+}
+
+func TestReconcileRDSInstancesStartedByAWS(t *testing.T) {
+	autoStartEvent := rdstype.Event{
+		Date:            aws.Time(time.Date(2026, time.October, 13, 19, 5, 0, 0, time.UTC)),
+		EventCategories: []string{"notification"},
+		Message:         aws.String("DB instance is being started due to it exceeding the maximum allowed time being stopped."),
+	}
+	client := &mockIRDSInstancesAPI{
+		Events: map[string][]rdstype.Event{
+			"test-database":   {autoStartEvent},
+			"test-database-3": {autoStartEvent},
+			"test-database-6": {autoStartEvent},
+		},
+		DescribeDBInstancesOutput: &rds.DescribeDBInstancesOutput{
+			DBInstances: []rdstype.DBInstance{
+				// stopped by the scheduler eight days ago and started by AWS, acted upon: 1
+				{
+					DBInstanceIdentifier: aws.String("test-database"),
+					DBInstanceArn:        aws.String("arn:aws:rds:eu-west-2:123456789012:db:test-database"),
+					DBInstanceStatus:     aws.String("available"),
+					TagList: []rdstype.Tag{
+						{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+						{Key: aws.String("instance-scheduler:stopped-at"), Value: aws.String("2026-10-06T19:00:00Z")},
+					},
+				},
+				// stopped by the scheduler two days ago and started by an engineer, skipped: 1
+				{
+					DBInstanceIdentifier: aws.String("test-database-2"),
+					DBInstanceArn:        aws.String("arn:aws:rds:eu-west-2:123456789012:db:test-database-2"),
+					DBInstanceStatus:     aws.String("available"),
+					TagList: []rdstype.Tag{
+						{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+						{Key: aws.String("instance-scheduler:stopped-at"), Value: aws.String("2026-10-12T19:00:00Z")},
+					},
+				},
+				// started by AWS but kept running by an override, skipped: 1
+				{
+					DBInstanceIdentifier: aws.String("test-database-3"),
+					DBInstanceArn:        aws.String("arn:aws:rds:eu-west-2:123456789012:db:test-database-3"),
+					DBInstanceStatus:     aws.String("available"),
+					TagList: []rdstype.Tag{
+						{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+						{Key: aws.String("instance-scheduler:stopped-at"), Value: aws.String("2026-10-06T19:00:00Z")},
+						{Key: aws.String("instance-scheduling-override-until"), Value: aws.String("2026-10-14T22:00Z")},
+					},
+				},
+				// stopped by the scheduler eight days ago and started by an engineer, as AWS recorded no event, skipped: 1
+				{
+					DBInstanceIdentifier: aws.String("test-database-5"),
+					DBInstanceArn:        aws.String("arn:aws:rds:eu-west-2:123456789012:db:test-database-5"),
+					DBInstanceStatus:     aws.String("available"),
+					TagList: []rdstype.Tag{
+						{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+						{Key: aws.String("instance-scheduler:stopped-at"), Value: aws.String("2026-10-06T19:00:00Z")},
+					},
+				},
+				// started by AWS while nights-only expects it to be stopped at midday, acted upon: 1
+				{
+					DBInstanceIdentifier: aws.String("test-database-6"),
+					DBInstanceArn:        aws.String("arn:aws:rds:eu-west-2:123456789012:db:test-database-6"),
+					DBInstanceStatus:     aws.String("available"),
+					TagList: []rdstype.Tag{
+						{Key: aws.String("instance-scheduling"), Value: aws.String("nights-only")},
+						{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+						{Key: aws.String("instance-scheduler:stopped-at"), Value: aws.String("2026-10-06T19:00:00Z")},
+					},
+				},
+				// still stopped by the scheduler, skipped: 1
+				{
+					DBInstanceIdentifier: aws.String("test-database-4"),
+					DBInstanceArn:        aws.String("arn:aws:rds:eu-west-2:123456789012:db:test-database-4"),
+					DBInstanceStatus:     aws.String("stopped"),
+					TagList: []rdstype.Tag{
+						{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+						{Key: aws.String("instance-scheduler:stopped-at"), Value: aws.String("2026-10-06T19:00:00Z")},
+					},
+				},
+			},
+		},
+	}
+	actualInstanceCount, err := StopStartTestRDSInstancesInMemberAccount(client, &SchedulingRun{Action: "reconcile", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+	assert.NoError(t, err)
+
+	assert.Equal(t, RDSInstanceCount{RDSActedUpon: 2, RDSSkipped: 4, RDSAutoRestartedRestopped: 2}, *actualInstanceCount)
+	assert.Equal(t, []string{"test-database", "test-database-6"}, client.StoppedInstances)
+	assert.Len(t, client.DescribeEventsInputs, 4)
+	assert.Equal(t, time.Date(2026, time.October, 13, 19, 0, 0, 0, time.UTC), *client.DescribeEventsInputs[0].StartTime)
+	assert.Equal(t, []string{"notification"}, client.DescribeEventsInputs[0].EventCategories)
+	assert.Len(t, client.AddTagsInputs, 2)
+	assert.Equal(t, "arn:aws:rds:eu-west-2:123456789012:db:test-database", *client.AddTagsInputs[0].ResourceName)
+	assert.Equal(t, []rdstype.Tag{
+		{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+		{Key: aws.String("instance-scheduler:stopped-at"), Value: aws.String("2026-10-14T12:00:00Z")},
+	}, client.AddTagsInputs[0].Tags)
+}
+
+func TestStartRDSInstancesUntagsRunningInstances(t *testing.T) {
+	client := &mockIRDSInstancesAPI{
+		DescribeDBInstancesOutput: &rds.DescribeDBInstancesOutput{
+			DBInstances: []rdstype.DBInstance{
+				// stopped by the scheduler and started since by AWS or an engineer
+				{
+					DBInstanceIdentifier: aws.String("test-database"),
+					DBInstanceArn:        aws.String("arn:aws:rds:eu-west-2:123456789012:db:test-database"),
+					DBInstanceStatus:     aws.String("available"),
+					TagList: []rdstype.Tag{
+						{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+						{Key: aws.String("instance-scheduler:stopped-at"), Value: aws.String("2026-10-06T19:00:00Z")},
+					},
+				},
+				// running and never stopped by the scheduler, so there are no tags to remove
+				{
+					DBInstanceIdentifier: aws.String("test-database-2"),
+					DBInstanceArn:        aws.String("arn:aws:rds:eu-west-2:123456789012:db:test-database-2"),
+					DBInstanceStatus:     aws.String("available"),
+				},
+			},
+		},
+	}
+	actualInstanceCount, err := StopStartTestRDSInstancesInMemberAccount(client, &SchedulingRun{Action: "start", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+	assert.NoError(t, err)
+
+	assert.Equal(t, RDSInstanceCount{RDSAlreadyInDesiredState: 2}, *actualInstanceCount)
+	assert.Len(t, client.RemoveTagsInputs, 1)
+	assert.Equal(t, "arn:aws:rds:eu-west-2:123456789012:db:test-database", *client.RemoveTagsInputs[0].ResourceName)
+	assert.Equal(t, []string{"instance-scheduler:stopped-by", "instance-scheduler:stopped-at"}, client.RemoveTagsInputs[0].TagKeys)
+}
+
+func TestStopRDSInstancesWhenTheyCannotBeStopped(t *testing.T) {
+	client := &mockIRDSInstancesAPI{
+		DescribeDBInstancesOutput: &rds.DescribeDBInstancesOutput{
+			DBInstances: []rdstype.DBInstance{
+				{
+					DBInstanceIdentifier: aws.String("test-database"),
+					DBInstanceArn:        aws.String("arn:aws:rds:eu-west-2:123456789012:db:test-database"),
+					DBInstanceStatus:     aws.String("available"),
+				},
+			},
+		},
+		StopDBInstanceError: errors.New("StopDBInstance failed"),
+	}
+	actualInstanceCount, err := StopStartTestRDSInstancesInMemberAccount(client, &SchedulingRun{Action: "stop", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+	assert.NoError(t, err)
+
+	assert.Equal(t, RDSInstanceCount{RDSFailed: 1}, *actualInstanceCount)
+	assert.Empty(t, client.AddTagsInputs)
+}
+
+func TestStopStartRDSInstancesInActionableStates(t *testing.T) {
+	tests := []struct {
+		testTitle     string
+		action        string
+		status        string
+		untagged      bool
+		expectedCount RDSInstanceCount
+	}{
+		{
+			testTitle:     "RDS testing Stop action stops available instances",
+			action:        "stop",
+			status:        "available",
+			expectedCount: RDSInstanceCount{RDSActedUpon: 1},
+		},
+		{
+			testTitle:     "RDS testing Stop action skips stopped instances",
+			action:        "stop",
+			status:        "stopped",
+			expectedCount: RDSInstanceCount{RDSAlreadyInDesiredState: 1},
+		},
+		{
+			testTitle:     "RDS testing Stop action skips instances being backed up",
+			action:        "stop",
+			status:        "backing-up",
+			expectedCount: RDSInstanceCount{RDSNotActionable: 1},
+		},
+		{
+			testTitle:     "RDS testing Start action starts stopped instances",
+			action:        "start",
+			status:        "stopped",
+			expectedCount: RDSInstanceCount{RDSActedUpon: 1},
+		},
+		{
+			testTitle:     "RDS testing Start action skips available instances",
+			action:        "start",
+			status:        "available",
+			expectedCount: RDSInstanceCount{RDSAlreadyInDesiredState: 1},
+		},
+		{
+			testTitle:     "RDS testing Start action skips instances being modified",
+			action:        "start",
+			status:        "modifying",
+			expectedCount: RDSInstanceCount{RDSNotActionable: 1},
+		},
+		{
+			testTitle:     "RDS testing Start action counts untagged available instances as already running",
+			action:        "start",
+			status:        "available",
+			untagged:      true,
+			expectedCount: RDSInstanceCount{RDSAlreadyInDesiredState: 1},
+		},
+		{
+			testTitle:     "RDS testing Start action leaves untagged stopped instances stopped",
+			action:        "start",
+			status:        "stopped",
+			untagged:      true,
+			expectedCount: RDSInstanceCount{RDSStoppedNotByScheduler: 1},
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.testTitle, func(t *testing.T) {
+			tags := []rdstype.Tag{
+				{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+			}
+			if subtest.untagged {
+				tags = nil
+			}
+			client := &mockIRDSInstancesAPI{
+				DescribeDBInstancesOutput: &rds.DescribeDBInstancesOutput{
+					DBInstances: []rdstype.DBInstance{
+						{
+							DBInstanceIdentifier: aws.String("test-database"),
+							DBInstanceArn:        aws.String("arn:aws:rds:eu-west-2:123456789012:db:test-database"),
+							DBInstanceStatus:     aws.String(subtest.status),
+							TagList:              tags,
+						},
+					},
+				},
+			}
+			actualInstanceCount, err := StopStartTestRDSInstancesInMemberAccount(client, &SchedulingRun{Action: subtest.action, Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+			assert.NoError(t, err)
+			assert.Equal(t, subtest.expectedCount, *actualInstanceCount)
 		})
 	}
 }
+
+func TestListRDSInstancesAcrossPages(t *testing.T) {
+	client := &mockIRDSInstancesAPI{
+		DescribeDBInstancesPages: []*rds.DescribeDBInstancesOutput{
+			{
+				DBInstances: []rdstype.DBInstance{
+					{DBInstanceIdentifier: aws.String("test-database"), DBInstanceStatus: aws.String("available")},
+				},
+			},
+			{
+				DBInstances: []rdstype.DBInstance{
+					{DBInstanceIdentifier: aws.String("test-database-2"), DBInstanceStatus: aws.String("available")},
+					{DBInstanceIdentifier: aws.String("test-database-3"), DBInstanceStatus: aws.String("stopped")},
+				},
+			},
+		},
+	}
+	actualInstanceCount, err := StopStartTestRDSInstancesInMemberAccount(client, &SchedulingRun{Action: "stop", Now: testSchedulingTime, Schedules: testScheduleCatalogue, PageSize: 20})
+	assert.NoError(t, err)
+
+	assert.Equal(t, RDSInstanceCount{RDSActedUpon: 2, RDSAlreadyInDesiredState: 1}, *actualInstanceCount)
+	assert.Equal(t, []string{"test-database", "test-database-2"}, client.StoppedInstances)
+	assert.Len(t, client.DescribeDBInstancesInputs, 2)
+	for _, input := range client.DescribeDBInstancesInputs {
+		assert.Equal(t, int32(20), aws.ToInt32(input.MaxRecords))
+	}
+}
+
+func TestListRDSInstancesClampsPageSize(t *testing.T) {
+	for pageSize, wantMaxRecords := range map[int32]int32{5: 20, 1000: 100} {
+		client := &mockIRDSInstancesAPI{DescribeDBInstancesOutput: &rds.DescribeDBInstancesOutput{}}
+		_, err := listRDSInstances(client, &SchedulingRun{PageSize: pageSize})
+		assert.NoError(t, err)
+		assert.Equal(t, wantMaxRecords, aws.ToInt32(client.DescribeDBInstancesInputs[0].MaxRecords))
+	}
+}
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	rdstype "github.com/aws/aws-sdk-go-v2/service/rds/types"
+)
+
+// Aurora, DocumentDB and Neptune clusters are stopped and started as a whole, using the instance-scheduling tag on
+// the cluster. Their member instances cannot be stopped individually and are left out of
+// StopStartTestRDSInstancesInMemberAccount. Each engine is counted separately.
+
+const (
+	dbClusterEngineAurora  string = "Aurora"
+	dbClusterEngineDocDB   string = "DocumentDB"
+	dbClusterEngineNeptune string = "Neptune"
+)
+
+type RDSClusterCount struct {
+	RDSClustersActedUpon             int
+	RDSClustersSkipped               int
+	DocDBClustersActedUpon           int
+	DocDBClustersSkipped             int
+	NeptuneClustersActedUpon         int
+	NeptuneClustersSkipped           int
+	DBClustersAlreadyInDesiredState  int
+	DBClustersStoppedNotByScheduler  int
+	DBClustersNotActionable          int
+	DBClustersAutoRestartedRestopped int
+	DBClustersFailed                 int
+}
+
+// dbClusterCount counts the clusters of one engine
+type dbClusterCount struct {
+	actedUpon              int
+	skipped                int
+	alreadyInDesiredState  int
+	stoppedNotByScheduler  int
+	notActionable          int
+	autoRestartedRestopped int
+	failed                 int
+}
+
+type IRDSClustersAPI interface {
+	StopDBCluster(ctx context.Context, params *rds.StopDBClusterInput, optFns ...func(*rds.Options)) (*rds.StopDBClusterOutput, error)
+	StartDBCluster(ctx context.Context, params *rds.StartDBClusterInput, optFns ...func(*rds.Options)) (*rds.StartDBClusterOutput, error)
+	DescribeDBClusters(ctx context.Context, params *rds.DescribeDBClustersInput, optFns ...func(*rds.Options)) (*rds.DescribeDBClustersOutput, error)
+	AddTagsToResource(ctx context.Context, params *rds.AddTagsToResourceInput, optFns ...func(*rds.Options)) (*rds.AddTagsToResourceOutput, error)
+	RemoveTagsFromResource(ctx context.Context, params *rds.RemoveTagsFromResourceInput, optFns ...func(*rds.Options)) (*rds.RemoveTagsFromResourceOutput, error)
+	DescribeEvents(ctx context.Context, params *rds.DescribeEventsInput, optFns ...func(*rds.Options)) (*rds.DescribeEventsOutput, error)
+}
+
+func StopStartTestRDSClustersInMemberAccount(RDSClient IRDSClustersAPI, run *SchedulingRun) (*RDSClusterCount, error) {
+	clusters, err := listRDSClusters(RDSClient, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about Amazon RDS clusters: %w", err)
+	}
+
+	clustersByEngine := map[string][]rdstype.DBCluster{}
+	skippedMultiAZClusters := []string{}
+	for _, cluster := range clusters {
+		engine := dbClusterEngine(cluster)
+		if engine == "" {
+			skippedMultiAZClusters = append(skippedMultiAZClusters, *cluster.DBClusterIdentifier)
+			run.Printf("INFO: Skipped DB cluster %v because Multi-AZ DB clusters with engine %v are not scheduled\n", *cluster.DBClusterIdentifier, aws.ToString(cluster.Engine))
+			continue
+		}
+		clustersByEngine[engine] = append(clustersByEngine[engine], cluster)
+	}
+
+	counts := map[string]dbClusterCount{}
+	for _, engine := range []string{dbClusterEngineAurora, dbClusterEngineDocDB, dbClusterEngineNeptune} {
+		count, err := stopStartTestDBClusters(RDSClient, run, engine, clustersByEngine[engine])
+		if err != nil {
+			return nil, err
+		}
+		counts[engine] = count
+	}
+	run.Printf("INFO: Skipped %v Multi-AZ DB clusters: %v\n", len(skippedMultiAZClusters), skippedMultiAZClusters)
+
+	// Multi-AZ DB clusters are RDS clusters, so they are counted as skipped alongside the Aurora ones
+	aurora, docDB, neptune := counts[dbClusterEngineAurora], counts[dbClusterEngineDocDB], counts[dbClusterEngineNeptune]
+	return &RDSClusterCount{
+		RDSClustersActedUpon:             aurora.actedUpon,
+		RDSClustersSkipped:               aurora.skipped + len(skippedMultiAZClusters),
+		DocDBClustersActedUpon:           docDB.actedUpon,
+		DocDBClustersSkipped:             docDB.skipped,
+		NeptuneClustersActedUpon:         neptune.actedUpon,
+		NeptuneClustersSkipped:           neptune.skipped,
+		DBClustersAlreadyInDesiredState:  aurora.alreadyInDesiredState + docDB.alreadyInDesiredState + neptune.alreadyInDesiredState,
+		DBClustersStoppedNotByScheduler:  aurora.stoppedNotByScheduler + docDB.stoppedNotByScheduler + neptune.stoppedNotByScheduler,
+		DBClustersNotActionable:          aurora.notActionable + docDB.notActionable + neptune.notActionable,
+		DBClustersAutoRestartedRestopped: aurora.autoRestartedRestopped + docDB.autoRestartedRestopped + neptune.autoRestartedRestopped,
+		DBClustersFailed:                 aurora.failed + docDB.failed + neptune.failed,
+	}, nil
+}
+
+// listRDSClusters returns every DB cluster in the member account, requesting the run's page size per page, within
+// the range RDS accepts, unless it is zero
+func listRDSClusters(RDSClient IRDSClustersAPI, run *SchedulingRun) ([]rdstype.DBCluster, error) {
+	input := &rds.DescribeDBClustersInput{}
+	if run.PageSize > 0 {
+		input.MaxRecords = aws.Int32(clampPageSize(run.PageSize, rdsMinPageSize, rdsMaxPageSize))
+	}
+
+	clusters := []rdstype.DBCluster{}
+	pages := rds.NewDescribeDBClustersPaginator(RDSClient, input)
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(run.ctx())
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, page.DBClusters...)
+	}
+	return clusters, nil
+}
+
+// stopStartTestDBClusters acts on the clusters of one engine and counts them
+func stopStartTestDBClusters(RDSClient IRDSClustersAPI, run *SchedulingRun, engine string, clusters []rdstype.DBCluster) (dbClusterCount, error) {
+	action := run.Action
+	if action == "stop" {
+		return stopRDSClusters(RDSClient, run, engine, clusters), nil
+	}
+
+	if action == "start" {
+		return startRDSClusters(RDSClient, run, engine, clusters), nil
+	}
+
+	if action == "test" {
+		return testRDSClusters(RDSClient, run, engine, clusters), nil
+	}
+
+	if action == "reconcile" {
+		return reconcileRDSClusters(RDSClient, run, engine, clusters), nil
+	}
+
+	return dbClusterCount{}, invalidActionError(action)
+}
+
+// dbClusterEngine returns the engine of a cluster returned by DescribeDBClusters, which returns DocumentDB and Neptune
+// clusters as well as Aurora ones. Multi-AZ DB clusters are not scheduled and return an empty string.
+func dbClusterEngine(cluster rdstype.DBCluster) string {
+	engine := aws.ToString(cluster.Engine)
+	if strings.HasPrefix(engine, "aurora") {
+		return dbClusterEngineAurora
+	}
+	if engine == "docdb" {
+		return dbClusterEngineDocDB
+	}
+	if engine == "neptune" {
+		return dbClusterEngineNeptune
+	}
+	return ""
+}
+
+func startRDSCluster(client IRDSClustersAPI, run *SchedulingRun, dbClusterIdentifier string) error {
+	input := &rds.StartDBClusterInput{
+		DBClusterIdentifier: aws.String(dbClusterIdentifier),
+	}
+
+	_, err := client.StartDBCluster(run.ctx(), input)
+	if err == nil {
+		run.Printf("INFO: Successfully started DB cluster with Identifier %v\n", dbClusterIdentifier)
+	} else {
+		run.Printf("ERROR: Could not start DB cluster: %v\n", err)
+	}
+	return err
+}
+
+func stopRDSCluster(client IRDSClustersAPI, run *SchedulingRun, dbClusterIdentifier string) error {
+	input := &rds.StopDBClusterInput{
+		DBClusterIdentifier: aws.String(dbClusterIdentifier),
+	}
+
+	_, err := client.StopDBCluster(run.ctx(), input)
+	if err == nil {
+		run.Printf("INFO: Successfully stopped DB cluster with Identifier %v\n", dbClusterIdentifier)
+	} else {
+		run.Printf("ERROR: Could not stop DB cluster: %v\n", err)
+	}
+	return err
+}
+
+func stopRDSClusters(RDSClient IRDSClustersAPI, run *SchedulingRun, engine string, clusters []rdstype.DBCluster) dbClusterCount {
+	clustersActedUpon := []string{}
+	skippedClusters := []string{}
+	failedClusters := []string{}
+	alreadyStoppedClusters := []string{}
+	notActionableClusters := []string{}
+
+	for _, cluster := range clusters {
+		run.Printf("INFO: %v Cluster Identifier: [ %v ]\n", engine, *cluster.DBClusterIdentifier)
+		resource := newRDSClusterResource(RDSClient, cluster)
+		instanceSchedulingTag, overrideUntil, _, skipCluster, skippedClustersModified := parseResourceTags(run, resource, skippedClusters)
+		skippedClusters = skippedClustersModified
+
+		if skipCluster {
+			continue
+		}
+
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		if isOverrideActive(overrideUntil, run.Now) {
+			skippedClusters = append(skippedClusters, *cluster.DBClusterIdentifier)
+			run.Printf("INFO: Skipped DB cluster because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+			continue
+		}
+
+		if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+			skippedClusters = append(skippedClusters, *cluster.DBClusterIdentifier)
+			run.Printf("INFO: Skipped DB cluster because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+			continue
+		}
+
+		if instanceSchedulingTag == "skip-auto-stop" {
+			skippedClusters = append(skippedClusters, *cluster.DBClusterIdentifier)
+			run.Printf("INFO: Skipped DB cluster because instance-scheduling tag having value 'skip-auto-stop'\n")
+			continue
+		}
+
+		clusterStatus := aws.ToString(cluster.Status)
+		if clusterStatus == "stopped" || clusterStatus == "stopping" {
+			alreadyStoppedClusters = append(alreadyStoppedClusters, *cluster.DBClusterIdentifier)
+			run.Printf("INFO: Skipped DB cluster because it is already %v\n", clusterStatus)
+			continue
+		}
+		if clusterStatus != "available" {
+			notActionableClusters = append(notActionableClusters, *cluster.DBClusterIdentifier)
+			run.Printf("INFO: Skipped DB cluster because it cannot be stopped while %v\n", clusterStatus)
+			continue
+		}
+
+		run.Printf("INFO: Stopping DB cluster because instance-scheduling tag is absent\n")
+		if stopRDSCluster(RDSClient, run, *cluster.DBClusterIdentifier) != nil {
+			failedClusters = append(failedClusters, *cluster.DBClusterIdentifier)
+			continue
+		}
+		tagResourceStoppedByScheduler(run, resource)
+		clustersActedUpon = append(clustersActedUpon, *cluster.DBClusterIdentifier)
+	}
+
+	run.Printf("INFO: Stopped %v %v clusters: %v\n", len(clustersActedUpon), engine, clustersActedUpon)
+	run.Printf("INFO: Skipped %v %v clusters due to instance-scheduling tag: %v\n", len(skippedClusters), engine, skippedClusters)
+	run.Printf("INFO: Skipped %v %v clusters which were already stopped: %v\n", len(alreadyStoppedClusters), engine, alreadyStoppedClusters)
+	run.Printf("INFO: Skipped %v %v clusters which could not be stopped in their current status: %v\n", len(notActionableClusters), engine, notActionableClusters)
+	run.Printf("INFO: Could not stop %v %v clusters: %v\n", len(failedClusters), engine, failedClusters)
+
+	return dbClusterCount{actedUpon: len(clustersActedUpon), skipped: len(skippedClusters), alreadyInDesiredState: len(alreadyStoppedClusters), notActionable: len(notActionableClusters), failed: len(failedClusters)}
+}
+
+func startRDSClusters(RDSClient IRDSClustersAPI, run *SchedulingRun, engine string, clusters []rdstype.DBCluster) dbClusterCount {
+	clustersActedUpon := []string{}
+	skippedClusters := []string{}
+	failedClusters := []string{}
+	stoppedNotBySchedulerClusters := []string{}
+	alreadyRunningClusters := []string{}
+	notActionableClusters := []string{}
+
+	for _, cluster := range clusters {
+		run.Printf("INFO: %v Cluster Identifier: [ %v ]\n", engine, *cluster.DBClusterIdentifier)
+		resource := newRDSClusterResource(RDSClient, cluster)
+		instanceSchedulingTag, overrideUntil, stoppedByScheduler, skipCluster, skippedClustersModified := parseResourceTags(run, resource, skippedClusters)
+		skippedClusters = skippedClustersModified
+
+		if skipCluster {
+			continue
+		}
+
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+			skippedClusters = append(skippedClusters, *cluster.DBClusterIdentifier)
+			run.Printf("INFO: Skipped DB cluster because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+			continue
+		}
+
+		if instanceSchedulingTag == "skip-auto-start" {
+			skippedClusters = append(skippedClusters, *cluster.DBClusterIdentifier)
+			run.Printf("INFO: Skipped DB cluster because instance-scheduling tag having value 'skip-auto-start'\n")
+			continue
+		}
+
+		clusterStatus := aws.ToString(cluster.Status)
+		if clusterStatus == "available" || clusterStatus == "starting" {
+			alreadyRunningClusters = append(alreadyRunningClusters, *cluster.DBClusterIdentifier)
+			run.Printf("INFO: Skipped DB cluster because it is already %v\n", clusterStatus)
+			continue
+		}
+		if clusterStatus != "stopped" {
+			notActionableClusters = append(notActionableClusters, *cluster.DBClusterIdentifier)
+			run.Printf("INFO: Skipped DB cluster because it cannot be started while %v\n", clusterStatus)
+			continue
+		}
+
+		if !stoppedByScheduler {
+			stoppedNotBySchedulerClusters = append(stoppedNotBySchedulerClusters, *cluster.DBClusterIdentifier)
+			run.Printf("INFO: Skipped DB cluster because it was not stopped by the scheduler\n")
+			continue
+		}
+
+		run.Printf("INFO: Starting DB cluster because it was stopped by the scheduler\n")
+		if startRDSCluster(RDSClient, run, *cluster.DBClusterIdentifier) != nil {
+			failedClusters = append(failedClusters, *cluster.DBClusterIdentifier)
+			continue
+		}
+		untagResourceStoppedByScheduler(run, resource)
+		clustersActedUpon = append(clustersActedUpon, *cluster.DBClusterIdentifier)
+	}
+
+	run.Printf("INFO: Started %v %v clusters: %v\n", len(clustersActedUpon), engine, clustersActedUpon)
+	run.Printf("INFO: Skipped %v %v clusters due to instance-scheduling tag: %v\n", len(skippedClusters), engine, skippedClusters)
+	run.Printf("INFO: Found %v stopped %v clusters which were not stopped by the scheduler: %v\n", len(stoppedNotBySchedulerClusters), engine, stoppedNotBySchedulerClusters)
+	run.Printf("INFO: Skipped %v %v clusters which were already running: %v\n", len(alreadyRunningClusters), engine, alreadyRunningClusters)
+	run.Printf("INFO: Skipped %v %v clusters which could not be started in their current status: %v\n", len(notActionableClusters), engine, notActionableClusters)
+	run.Printf("INFO: Could not start %v %v clusters: %v\n", len(failedClusters), engine, failedClusters)
+
+	return dbClusterCount{actedUpon: len(clustersActedUpon), skipped: len(skippedClusters), stoppedNotByScheduler: len(stoppedNotBySchedulerClusters), alreadyInDesiredState: len(alreadyRunningClusters), notActionable: len(notActionableClusters), failed: len(failedClusters)}
+}
+
+func testRDSClusters(RDSClient IRDSClustersAPI, run *SchedulingRun, engine string, clusters []rdstype.DBCluster) dbClusterCount {
+	clustersActedUpon := []string{}
+	skippedClusters := []string{}
+
+	for _, cluster := range clusters {
+		run.Printf("INFO: %v Cluster Identifier: [ %v ]\n", engine, *cluster.DBClusterIdentifier)
+		resource := newRDSClusterResource(RDSClient, cluster)
+		instanceSchedulingTag, overrideUntil, _, skipCluster, skippedClustersModified := parseResourceTags(run, resource, skippedClusters)
+		skippedClusters = skippedClustersModified
+
+		if skipCluster {
+			continue
+		}
+
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		if isOverrideActive(overrideUntil, run.Now) {
+			skippedClusters = append(skippedClusters, *cluster.DBClusterIdentifier)
+			run.Printf("INFO: Skipped DB cluster because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+			continue
+		}
+
+		if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+			skippedClusters = append(skippedClusters, *cluster.DBClusterIdentifier)
+			run.Printf("INFO: Skipped DB cluster because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+			continue
+		}
+
+		if instanceSchedulingTag == "skip-auto-stop" || instanceSchedulingTag == "skip-auto-start" {
+			skippedClusters = append(skippedClusters, *cluster.DBClusterIdentifier)
+			run.Printf("INFO: Skipped DB cluster with identifier %v because instance-scheduling tag having value 'skip-auto-stop' or 'skip-auto-start'", *cluster.DBClusterIdentifier)
+			continue
+		}
+
+		clustersActedUpon = append(clustersActedUpon, *cluster.DBClusterIdentifier)
+		run.Printf("INFO: Successfully tested DB cluster with identifier %v because instance-scheduling tag is absent\n", *cluster.DBClusterIdentifier)
+	}
+
+	run.Printf("INFO: Tested %v %v clusters: %v\n", len(clustersActedUpon), engine, clustersActedUpon)
+	run.Printf("INFO: Skipped %v %v clusters due to instance-scheduling tag: %v\n", len(skippedClusters), engine, skippedClusters)
+
+	return dbClusterCount{actedUpon: len(clustersActedUpon), skipped: len(skippedClusters)}
+}
+
+func reconcileRDSClusters(RDSClient IRDSClustersAPI, run *SchedulingRun, engine string, clusters []rdstype.DBCluster) dbClusterCount {
+	clustersStarted := []string{}
+	clustersStopped := []string{}
+	clustersRestopped := []string{}
+	skippedClusters := []string{}
+	failedClusters := []string{}
+
+	for _, cluster := range clusters {
+		run.Printf("INFO: %v Cluster Identifier: [ %v ]\n", engine, *cluster.DBClusterIdentifier)
+		resource := newRDSClusterResource(RDSClient, cluster)
+		instanceSchedulingTag, overrideUntil, _, skipCluster, skippedClustersModified := parseResourceTags(run, resource, skippedClusters)
+		skippedClusters = skippedClustersModified
+
+		if skipCluster {
+			continue
+		}
+
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		// a cluster started by AWS is stopped again whenever it should be stopped now, whether it has no schedule or
+		// its schedule expects it to be stopped
+		schedule := run.Schedules.getSchedule(run, instanceSchedulingTag)
+		shouldBeStopped := schedule == nil || run.desiredState(schedule) == scheduleStateStopped
+		if shouldBeStopped && isRDSClusterAutoStarted(RDSClient, run, cluster) {
+			if isOverrideActive(overrideUntil, run.Now) {
+				skippedClusters = append(skippedClusters, *cluster.DBClusterIdentifier)
+				run.Printf("INFO: Skipped stopping DB cluster started by AWS because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+				continue
+			}
+			run.Printf("INFO: Stopping DB cluster again because AWS started it after it had been stopped by the scheduler for seven days\n")
+			if stopRDSCluster(RDSClient, run, *cluster.DBClusterIdentifier) != nil {
+				failedClusters = append(failedClusters, *cluster.DBClusterIdentifier)
+				continue
+			}
+			tagResourceStoppedByScheduler(run, resource)
+			clustersRestopped = append(clustersRestopped, *cluster.DBClusterIdentifier)
+			continue
+		}
+
+		if schedule == nil {
+			skippedClusters = append(skippedClusters, *cluster.DBClusterIdentifier)
+			run.Printf("INFO: Skipped DB cluster because its instance-scheduling tag does not reference a schedule\n")
+			continue
+		}
+
+		desiredState := run.desiredState(schedule)
+		clusterStatus := aws.ToString(cluster.Status)
+
+		if desiredState == scheduleStateRunning && clusterStatus == "stopped" {
+			run.Printf("INFO: Starting DB cluster because schedule '%v' expects it to be running\n", schedule.Name)
+			if startRDSCluster(RDSClient, run, *cluster.DBClusterIdentifier) != nil {
+				failedClusters = append(failedClusters, *cluster.DBClusterIdentifier)
+				continue
+			}
+			untagResourceStoppedByScheduler(run, resource)
+			clustersStarted = append(clustersStarted, *cluster.DBClusterIdentifier)
+			continue
+		}
+
+		if desiredState == scheduleStateStopped && clusterStatus == "available" && isOverrideActive(overrideUntil, run.Now) {
+			skippedClusters = append(skippedClusters, *cluster.DBClusterIdentifier)
+			run.Printf("INFO: Skipped stopping DB cluster because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+			continue
+		}
+
+		if desiredState == scheduleStateStopped && clusterStatus == "available" {
+			run.Printf("INFO: Stopping DB cluster because schedule '%v' expects it to be stopped\n", schedule.Name)
+			if stopRDSCluster(RDSClient, run, *cluster.DBClusterIdentifier) != nil {
+				failedClusters = append(failedClusters, *cluster.DBClusterIdentifier)
+				continue
+			}
+			tagResourceStoppedByScheduler(run, resource)
+			clustersStopped = append(clustersStopped, *cluster.DBClusterIdentifier)
+			continue
+		}
+
+		skippedClusters = append(skippedClusters, *cluster.DBClusterIdentifier)
+		run.Printf("INFO: Skipped DB cluster with status '%v' because schedule '%v' expects it to be '%v'\n", clusterStatus, schedule.Name, desiredState)
+	}
+
+	run.Printf("INFO: Started %v %v clusters: %v\n", len(clustersStarted), engine, clustersStarted)
+	run.Printf("INFO: Stopped %v %v clusters: %v\n", len(clustersStopped), engine, clustersStopped)
+	run.Printf("INFO: Stopped again %v %v clusters started by AWS: %v\n", len(clustersRestopped), engine, clustersRestopped)
+	run.Printf("INFO: Skipped %v %v clusters due to instance-scheduling tag or schedule: %v\n", len(skippedClusters), engine, skippedClusters)
+	run.Printf("INFO: Could not start or stop %v %v clusters: %v\n", len(failedClusters), engine, failedClusters)
+
+	return dbClusterCount{actedUpon: len(clustersStarted) + len(clustersStopped) + len(clustersRestopped), skipped: len(skippedClusters), autoRestartedRestopped: len(clustersRestopped), failed: len(failedClusters)}
+}
+
+// isRDSClusterAutoStarted reports whether an available DB cluster was started by AWS rather than by the start action
+// or an engineer
+func isRDSClusterAutoStarted(RDSClient IRDSEventsAPI, run *SchedulingRun, cluster rdstype.DBCluster) bool {
+	return isRDSAutoStarted(RDSClient, run, rdstype.SourceTypeDbCluster, *cluster.DBClusterIdentifier, aws.ToString(cluster.Status), cluster.TagList)
+}
+
+func getRDSClusterClientForMemberAccount(session *MemberAccountSession) IRDSClustersAPI {
+	return rds.NewFromConfig(session.Config)
+}
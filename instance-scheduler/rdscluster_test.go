@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	rdstype "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockIRDSClustersAPI struct {
+	DescribeDBClustersOutput *rds.DescribeDBClustersOutput
+	StoppedClusters          []string
+	StartedClusters          []string
+	AddTagsInputs            []*rds.AddTagsToResourceInput
+	RemoveTagsInputs         []*rds.RemoveTagsFromResourceInput
+	Events                   map[string][]rdstype.Event
+	DescribeEventsInputs     []*rds.DescribeEventsInput
+}
+
+func (m *mockIRDSClustersAPI) DescribeDBClusters(ctx context.Context, params *rds.DescribeDBClustersInput, optFns ...func(*rds.Options)) (*rds.DescribeDBClustersOutput, error) {
+	return m.DescribeDBClustersOutput, nil
+}
+
+func (m *mockIRDSClustersAPI) StopDBCluster(ctx context.Context, params *rds.StopDBClusterInput, optFns ...func(*rds.Options)) (*rds.StopDBClusterOutput, error) {
+	m.StoppedClusters = append(m.StoppedClusters, *params.DBClusterIdentifier)
+	return &rds.StopDBClusterOutput{}, nil
+}
+
+func (m *mockIRDSClustersAPI) StartDBCluster(ctx context.Context, params *rds.StartDBClusterInput, optFns ...func(*rds.Options)) (*rds.StartDBClusterOutput, error) {
+	m.StartedClusters = append(m.StartedClusters, *params.DBClusterIdentifier)
+	return &rds.StartDBClusterOutput{}, nil
+}
+
+func (m *mockIRDSClustersAPI) AddTagsToResource(ctx context.Context, params *rds.AddTagsToResourceInput, optFns ...func(*rds.Options)) (*rds.AddTagsToResourceOutput, error) {
+	m.AddTagsInputs = append(m.AddTagsInputs, params)
+	return &rds.AddTagsToResourceOutput{}, nil
+}
+
+func (m *mockIRDSClustersAPI) RemoveTagsFromResource(ctx context.Context, params *rds.RemoveTagsFromResourceInput, optFns ...func(*rds.Options)) (*rds.RemoveTagsFromResourceOutput, error) {
+	m.RemoveTagsInputs = append(m.RemoveTagsInputs, params)
+	return &rds.RemoveTagsFromResourceOutput{}, nil
+}
+
+func (m *mockIRDSClustersAPI) DescribeEvents(ctx context.Context, params *rds.DescribeEventsInput, optFns ...func(*rds.Options)) (*rds.DescribeEventsOutput, error) {
+	m.DescribeEventsInputs = append(m.DescribeEventsInputs, params)
+	return &rds.DescribeEventsOutput{Events: m.Events[*params.SourceIdentifier]}, nil
+}
+
+func TestStopStartTestRDSClustersInMemberAccount(t *testing.T) {
+	clusters := []rdstype.DBCluster{
+		// no instance-scheduling tag and available, acted upon by stop and test
+		{
+			DBClusterIdentifier: aws.String("test-aurora"),
+			DBClusterArn:        aws.String("arn:aws:rds:eu-west-2:123456789012:cluster:test-aurora"),
+			Engine:              aws.String("aurora-postgresql"),
+			Status:              aws.String("available"),
+		},
+		// instance-scheduling = skip-scheduling, skipped: 1
+		{
+			DBClusterIdentifier: aws.String("test-aurora-2"),
+			Engine:              aws.String("aurora-mysql"),
+			Status:              aws.String("available"),
+			TagList: []rdstype.Tag{
+				{Key: aws.String("instance-scheduling"), Value: aws.String("skip-scheduling")},
+			},
+		},
+		// stopped by the scheduler, acted upon by start and test, already in desired state for stop
+		{
+			DBClusterIdentifier: aws.String("test-aurora-3"),
+			DBClusterArn:        aws.String("arn:aws:rds:eu-west-2:123456789012:cluster:test-aurora-3"),
+			Engine:              aws.String("aurora-postgresql"),
+			Status:              aws.String("stopped"),
+			TagList: []rdstype.Tag{
+				{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+			},
+		},
+		// instance-scheduling = skip-auto-stop, skipped by stop and test, already in desired state for start
+		{
+			DBClusterIdentifier: aws.String("test-aurora-4"),
+			Engine:              aws.String("aurora-postgresql"),
+			Status:              aws.String("available"),
+			TagList: []rdstype.Tag{
+				{Key: aws.String("instance-scheduling"), Value: aws.String("skip-auto-stop")},
+			},
+		},
+		// nights-only expects the cluster to be stopped at midday and it is available, acted upon by reconcile
+		{
+			DBClusterIdentifier: aws.String("test-aurora-5"),
+			Engine:              aws.String("aurora-postgresql"),
+			Status:              aws.String("available"),
+			TagList: []rdstype.Tag{
+				{Key: aws.String("instance-scheduling"), Value: aws.String("nights-only")},
+			},
+		},
+		// backing up, so it is not actionable by stop and start, acted upon by test and skipped by reconcile
+		{
+			DBClusterIdentifier: aws.String("test-aurora-6"),
+			Engine:              aws.String("aurora-mysql"),
+			Status:              aws.String("backing-up"),
+		},
+		// DocumentDB cluster without instance-scheduling tag and available, counted separately and acted upon by stop and test
+		{
+			DBClusterIdentifier: aws.String("test-docdb"),
+			Engine:              aws.String("docdb"),
+			Status:              aws.String("available"),
+		},
+		// Neptune cluster stopped by the scheduler, counted separately, acted upon by start and test and already in
+		// desired state for stop
+		{
+			DBClusterIdentifier: aws.String("test-neptune"),
+			Engine:              aws.String("neptune"),
+			Status:              aws.String("stopped"),
+			TagList: []rdstype.Tag{
+				{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+			},
+		},
+		// Neptune cluster stopped by an engineer, skipped by stop, test and reconcile, and counted as stopped not by the
+		// scheduler for start
+		{
+			DBClusterIdentifier: aws.String("test-neptune-2"),
+			Engine:              aws.String("neptune"),
+			Status:              aws.String("stopped"),
+			TagList: []rdstype.Tag{
+				{Key: aws.String("instance-scheduling"), Value: aws.String("skip-auto-stop")},
+			},
+		},
+		// Multi-AZ DB cluster, therefore not scheduled and skipped by every action
+		{
+			DBClusterIdentifier: aws.String("test-multi-az"),
+			Engine:              aws.String("postgres"),
+			Status:              aws.String("available"),
+		},
+	}
+
+	tests := []struct {
+		testTitle       string
+		action          string
+		expectedCount   RDSClusterCount
+		expectedStopped []string
+		expectedStarted []string
+	}{
+		{
+			testTitle: "DB cluster testing Test action",
+			action:    "test",
+			expectedCount: RDSClusterCount{
+				RDSClustersActedUpon: 3, RDSClustersSkipped: 4,
+				DocDBClustersActedUpon: 1, DocDBClustersSkipped: 0,
+				NeptuneClustersActedUpon: 1, NeptuneClustersSkipped: 1,
+			},
+		},
+		{
+			testTitle: "DB cluster testing Stop action",
+			action:    "stop",
+			expectedCount: RDSClusterCount{
+				RDSClustersActedUpon: 1, RDSClustersSkipped: 4,
+				DocDBClustersActedUpon: 1, DocDBClustersSkipped: 0,
+				NeptuneClustersActedUpon: 0, NeptuneClustersSkipped: 1,
+				DBClustersAlreadyInDesiredState: 2, DBClustersNotActionable: 1,
+			},
+			expectedStopped: []string{"test-aurora", "test-docdb"},
+		},
+		{
+			testTitle: "DB cluster testing Start action",
+			action:    "start",
+			expectedCount: RDSClusterCount{
+				RDSClustersActedUpon: 1, RDSClustersSkipped: 3,
+				DocDBClustersActedUpon: 0, DocDBClustersSkipped: 0,
+				NeptuneClustersActedUpon: 1, NeptuneClustersSkipped: 0,
+				DBClustersAlreadyInDesiredState: 3, DBClustersStoppedNotByScheduler: 1, DBClustersNotActionable: 1,
+			},
+			expectedStarted: []string{"test-aurora-3", "test-neptune"},
+		},
+		{
+			testTitle: "DB cluster testing Reconcile action",
+			action:    "reconcile",
+			expectedCount: RDSClusterCount{
+				RDSClustersActedUpon: 1, RDSClustersSkipped: 6,
+				DocDBClustersActedUpon: 0, DocDBClustersSkipped: 1,
+				NeptuneClustersActedUpon: 0, NeptuneClustersSkipped: 2,
+			},
+			expectedStopped: []string{"test-aurora-5"},
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.testTitle, func(t *testing.T) {
+			client := &mockIRDSClustersAPI{
+				DescribeDBClustersOutput: &rds.DescribeDBClustersOutput{DBClusters: clusters},
+			}
+			actualCount, err := StopStartTestRDSClustersInMemberAccount(client, &SchedulingRun{Action: subtest.action, Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+			assert.NoError(t, err)
+			assert.Equal(t, subtest.expectedCount, *actualCount)
+			assert.Equal(t, subtest.expectedStopped, client.StoppedClusters)
+			assert.Equal(t, subtest.expectedStarted, client.StartedClusters)
+		})
+	}
+}
+
+func TestReconcileRDSClustersStartedByAWS(t *testing.T) {
+	autoStartEvent := rdstype.Event{
+		Date:            aws.Time(time.Date(2026, time.October, 13, 19, 5, 0, 0, time.UTC)),
+		EventCategories: []string{"notification"},
+	}
+	stoppedBySchedulerTags := []rdstype.Tag{
+		{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+		{Key: aws.String("instance-scheduler:stopped-at"), Value: aws.String("2026-10-06T19:00:00Z")},
+	}
+	client := &mockIRDSClustersAPI{
+		Events: map[string][]rdstype.Event{
+			"test-aurora":   {autoStartEvent},
+			"test-aurora-2": {autoStartEvent},
+		},
+		DescribeDBClustersOutput: &rds.DescribeDBClustersOutput{
+			DBClusters: []rdstype.DBCluster{
+				// stopped by the scheduler eight days ago and started by AWS, acted upon: 1
+				{
+					DBClusterIdentifier: aws.String("test-aurora"),
+					DBClusterArn:        aws.String("arn:aws:rds:eu-west-2:123456789012:cluster:test-aurora"),
+					Engine:              aws.String("aurora-postgresql"),
+					Status:              aws.String("available"),
+					TagList:             stoppedBySchedulerTags,
+				},
+				// started by AWS while nights-only expects it to be stopped at midday, acted upon: 1
+				{
+					DBClusterIdentifier: aws.String("test-aurora-2"),
+					DBClusterArn:        aws.String("arn:aws:rds:eu-west-2:123456789012:cluster:test-aurora-2"),
+					Engine:              aws.String("aurora-postgresql"),
+					Status:              aws.String("available"),
+					TagList:             append([]rdstype.Tag{{Key: aws.String("instance-scheduling"), Value: aws.String("nights-only")}}, stoppedBySchedulerTags...),
+				},
+				// stopped by the scheduler eight days ago and started by an engineer, as AWS recorded no event, skipped: 1
+				{
+					DBClusterIdentifier: aws.String("test-aurora-3"),
+					DBClusterArn:        aws.String("arn:aws:rds:eu-west-2:123456789012:cluster:test-aurora-3"),
+					Engine:              aws.String("aurora-postgresql"),
+					Status:              aws.String("available"),
+					TagList:             stoppedBySchedulerTags,
+				},
+			},
+		},
+	}
+	actualCount, err := StopStartTestRDSClustersInMemberAccount(client, &SchedulingRun{Action: "reconcile", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+	assert.NoError(t, err)
+
+	assert.Equal(t, RDSClusterCount{RDSClustersActedUpon: 2, RDSClustersSkipped: 1, DBClustersAutoRestartedRestopped: 2}, *actualCount)
+	assert.Equal(t, []string{"test-aurora", "test-aurora-2"}, client.StoppedClusters)
+	assert.Len(t, client.DescribeEventsInputs, 3)
+	assert.Equal(t, rdstype.SourceTypeDbCluster, client.DescribeEventsInputs[0].SourceType)
+	assert.Len(t, client.AddTagsInputs, 2)
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/redshift"
+	redshifttype "github.com/aws/aws-sdk-go-v2/service/redshift/types"
+)
+
+// Provisioned Redshift clusters are stopped by pausing them and started by resuming them. Compute is not billed
+// while a cluster is paused, but storage still is.
+
+type RedshiftClusterCount struct {
+	RedshiftActedUpon             int
+	RedshiftSkipped               int
+	RedshiftAlreadyInDesiredState int
+	RedshiftNotActionable         int
+}
+
+type IRedshiftAPI interface {
+	DescribeClusters(ctx context.Context, params *redshift.DescribeClustersInput, optFns ...func(*redshift.Options)) (*redshift.DescribeClustersOutput, error)
+	PauseCluster(ctx context.Context, params *redshift.PauseClusterInput, optFns ...func(*redshift.Options)) (*redshift.PauseClusterOutput, error)
+	ResumeCluster(ctx context.Context, params *redshift.ResumeClusterInput, optFns ...func(*redshift.Options)) (*redshift.ResumeClusterOutput, error)
+	CreateTags(ctx context.Context, params *redshift.CreateTagsInput, optFns ...func(*redshift.Options)) (*redshift.CreateTagsOutput, error)
+	DeleteTags(ctx context.Context, params *redshift.DeleteTagsInput, optFns ...func(*redshift.Options)) (*redshift.DeleteTagsOutput, error)
+}
+
+func stopStartTestRedshiftClustersInMemberAccount(client IRedshiftAPI, run *SchedulingRun) (*RedshiftClusterCount, error) {
+	action := run.Action
+	if action == "stop" {
+		return stopRedshiftClusters(client, run)
+	}
+	if action == "start" {
+		return startRedshiftClusters(client, run)
+	}
+	if action == "test" {
+		return testRedshiftClusters(client, run)
+	}
+	if action == "reconcile" {
+		return reconcileRedshiftClusters(client, run)
+	}
+	return nil, invalidActionError(action)
+}
+
+func listRedshiftClusters(client IRedshiftAPI, run *SchedulingRun) ([]redshifttype.Cluster, error) {
+	clusters := []redshifttype.Cluster{}
+	pages := redshift.NewDescribeClustersPaginator(client, &redshift.DescribeClustersInput{})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(run.ctx())
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, page.Clusters...)
+	}
+	return clusters, nil
+}
+
+// redshiftClusterResource adapts a Redshift cluster to the tag helpers shared by every service
+type redshiftClusterResource struct {
+	client  IRedshiftAPI
+	cluster redshifttype.Cluster
+}
+
+func (resource redshiftClusterResource) kind() string {
+	return "Redshift cluster"
+}
+
+func (resource redshiftClusterResource) id() string {
+	return aws.ToString(resource.cluster.ClusterIdentifier)
+}
+
+func (resource redshiftClusterResource) tags() map[string]string {
+	tags := map[string]string{}
+	for _, tag := range resource.cluster.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags
+}
+
+func (resource redshiftClusterResource) addTags(run *SchedulingRun, tags []resourceTag) error {
+	clusterArn, err := redshiftClusterArn(resource.cluster)
+	if err != nil {
+		return err
+	}
+	redshiftTags := []redshifttype.Tag{}
+	for _, tag := range tags {
+		redshiftTags = append(redshiftTags, redshifttype.Tag{Key: aws.String(tag.key), Value: aws.String(tag.value)})
+	}
+	_, err = resource.client.CreateTags(run.ctx(), &redshift.CreateTagsInput{
+		ResourceName: aws.String(clusterArn),
+		Tags:         redshiftTags,
+	})
+	return err
+}
+
+func (resource redshiftClusterResource) removeTags(run *SchedulingRun, keys []string) error {
+	clusterArn, err := redshiftClusterArn(resource.cluster)
+	if err != nil {
+		return err
+	}
+	_, err = resource.client.DeleteTags(run.ctx(), &redshift.DeleteTagsInput{
+		ResourceName: aws.String(clusterArn),
+		TagKeys:      keys,
+	})
+	return err
+}
+
+// redshiftClusterArn builds the ARN used to tag a cluster, which DescribeClusters does not return, from the ARN of
+// its namespace
+func redshiftClusterArn(cluster redshifttype.Cluster) (string, error) {
+	namespaceArn, err := arn.Parse(aws.ToString(cluster.ClusterNamespaceArn))
+	if err != nil {
+		return "", err
+	}
+	namespaceArn.Resource = "cluster:" + aws.ToString(cluster.ClusterIdentifier)
+	return namespaceArn.String(), nil
+}
+
+func pauseRedshiftCluster(client IRedshiftAPI, run *SchedulingRun, clusterIdentifier string) error {
+	_, err := client.PauseCluster(run.ctx(), &redshift.PauseClusterInput{
+		ClusterIdentifier: aws.String(clusterIdentifier),
+	})
+	if err == nil {
+		run.Printf("INFO: Successfully paused Redshift cluster with Identifier %v\n", clusterIdentifier)
+	} else {
+		run.Printf("ERROR: Could not pause Redshift cluster: %v\n", err)
+	}
+	return err
+}
+
+func resumeRedshiftCluster(client IRedshiftAPI, run *SchedulingRun, clusterIdentifier string) error {
+	_, err := client.ResumeCluster(run.ctx(), &redshift.ResumeClusterInput{
+		ClusterIdentifier: aws.String(clusterIdentifier),
+	})
+	if err == nil {
+		run.Printf("INFO: Successfully resumed Redshift cluster with Identifier %v\n", clusterIdentifier)
+	} else {
+		run.Printf("ERROR: Could not resume Redshift cluster: %v\n", err)
+	}
+	return err
+}
+
+func stopRedshiftClusters(client IRedshiftAPI, run *SchedulingRun) (*RedshiftClusterCount, error) {
+	clusters, err := listRedshiftClusters(client, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about Redshift clusters: %w", err)
+	}
+
+	clustersActedUpon := []string{}
+	skippedClusters := []string{}
+	alreadyPausedClusters := []string{}
+	notActionableClusters := []string{}
+	for _, cluster := range clusters {
+		run.Printf("INFO: Redshift Cluster Identifier: [ %v ]\n", *cluster.ClusterIdentifier)
+		resource := redshiftClusterResource{client: client, cluster: cluster}
+		instanceSchedulingTag, overrideUntil, _, skipCluster, skippedClustersModified := parseResourceTags(run, resource, skippedClusters)
+		skippedClusters = skippedClustersModified
+
+		if skipCluster {
+			continue
+		}
+
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		if isOverrideActive(overrideUntil, run.Now) {
+			skippedClusters = append(skippedClusters, *cluster.ClusterIdentifier)
+			run.Printf("INFO: Skipped Redshift cluster because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+			continue
+		}
+
+		if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+			skippedClusters = append(skippedClusters, *cluster.ClusterIdentifier)
+			run.Printf("INFO: Skipped Redshift cluster because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+			continue
+		}
+
+		if instanceSchedulingTag == "skip-auto-stop" {
+			skippedClusters = append(skippedClusters, *cluster.ClusterIdentifier)
+			run.Printf("INFO: Skipped Redshift cluster because instance-scheduling tag having value 'skip-auto-stop'\n")
+			continue
+		}
+
+		clusterStatus := aws.ToString(cluster.ClusterStatus)
+		if clusterStatus == "paused" || clusterStatus == "pausing" {
+			alreadyPausedClusters = append(alreadyPausedClusters, *cluster.ClusterIdentifier)
+			run.Printf("INFO: Skipped Redshift cluster because it is already %v\n", clusterStatus)
+			continue
+		}
+		if clusterStatus != "available" {
+			notActionableClusters = append(notActionableClusters, *cluster.ClusterIdentifier)
+			run.Printf("INFO: Skipped Redshift cluster because it cannot be paused while %v\n", clusterStatus)
+			continue
+		}
+
+		clustersActedUpon = append(clustersActedUpon, *cluster.ClusterIdentifier)
+		if pauseRedshiftCluster(client, run, *cluster.ClusterIdentifier) == nil {
+			tagResourceStoppedByScheduler(run, resource)
+		}
+		run.Printf("INFO: Paused Redshift cluster because instance-scheduling tag is absent\n")
+	}
+
+	run.Printf("INFO: Paused %v Redshift clusters: %v\n", len(clustersActedUpon), clustersActedUpon)
+	run.Printf("INFO: Skipped %v Redshift clusters due to instance-scheduling tag: %v\n", len(skippedClusters), skippedClusters)
+	run.Printf("INFO: Skipped %v Redshift clusters which were already paused: %v\n", len(alreadyPausedClusters), alreadyPausedClusters)
+	run.Printf("INFO: Skipped %v Redshift clusters which could not be paused in their current status: %v\n", len(notActionableClusters), notActionableClusters)
+
+	return &RedshiftClusterCount{
+		RedshiftActedUpon:             len(clustersActedUpon),
+		RedshiftSkipped:               len(skippedClusters),
+		RedshiftAlreadyInDesiredState: len(alreadyPausedClusters),
+		RedshiftNotActionable:         len(notActionableClusters),
+	}, nil
+}
+
+func startRedshiftClusters(client IRedshiftAPI, run *SchedulingRun) (*RedshiftClusterCount, error) {
+	clusters, err := listRedshiftClusters(client, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about Redshift clusters: %w", err)
+	}
+
+	clustersActedUpon := []string{}
+	skippedClusters := []string{}
+	alreadyResumedClusters := []string{}
+	notActionableClusters := []string{}
+	for _, cluster := range clusters {
+		run.Printf("INFO: Redshift Cluster Identifier: [ %v ]\n", *cluster.ClusterIdentifier)
+		resource := redshiftClusterResource{client: client, cluster: cluster}
+		instanceSchedulingTag, overrideUntil, stoppedByScheduler, skipCluster, skippedClustersModified := parseResourceTags(run, resource, skippedClusters)
+		skippedClusters = skippedClustersModified
+
+		if skipCluster {
+			continue
+		}
+
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+			skippedClusters = append(skippedClusters, *cluster.ClusterIdentifier)
+			run.Printf("INFO: Skipped Redshift cluster because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+			continue
+		}
+
+		if instanceSchedulingTag == "skip-auto-start" {
+			skippedClusters = append(skippedClusters, *cluster.ClusterIdentifier)
+			run.Printf("INFO: Skipped Redshift cluster because instance-scheduling tag having value 'skip-auto-start'\n")
+			continue
+		}
+
+		clusterStatus := aws.ToString(cluster.ClusterStatus)
+		if clusterStatus == "available" || clusterStatus == "resuming" {
+			alreadyResumedClusters = append(alreadyResumedClusters, *cluster.ClusterIdentifier)
+			run.Printf("INFO: Skipped Redshift cluster because it is already %v\n", clusterStatus)
+			continue
+		}
+		if clusterStatus != "paused" {
+			notActionableClusters = append(notActionableClusters, *cluster.ClusterIdentifier)
+			run.Printf("INFO: Skipped Redshift cluster because it cannot be resumed while %v\n", clusterStatus)
+			continue
+		}
+
+		if !stoppedByScheduler {
+			skippedClusters = append(skippedClusters, *cluster.ClusterIdentifier)
+			run.Printf("INFO: Skipped Redshift cluster because it was not paused by the scheduler\n")
+			continue
+		}
+
+		clustersActedUpon = append(clustersActedUpon, *cluster.ClusterIdentifier)
+		if resumeRedshiftCluster(client, run, *cluster.ClusterIdentifier) == nil {
+			untagResourceStoppedByScheduler(run, resource)
+		}
+		run.Printf("INFO: Resumed Redshift cluster because it was paused by the scheduler\n")
+	}
+
+	run.Printf("INFO: Resumed %v Redshift clusters: %v\n", len(clustersActedUpon), clustersActedUpon)
+	run.Printf("INFO: Skipped %v Redshift clusters due to instance-scheduling tag or not being paused by the scheduler: %v\n", len(skippedClusters), skippedClusters)
+	run.Printf("INFO: Skipped %v Redshift clusters which were already resumed: %v\n", len(alreadyResumedClusters), alreadyResumedClusters)
+	run.Printf("INFO: Skipped %v Redshift clusters which could not be resumed in their current status: %v\n", len(notActionableClusters), notActionableClusters)
+
+	return &RedshiftClusterCount{
+		RedshiftActedUpon:             len(clustersActedUpon),
+		RedshiftSkipped:               len(skippedClusters),
+		RedshiftAlreadyInDesiredState: len(alreadyResumedClusters),
+		RedshiftNotActionable:         len(notActionableClusters),
+	}, nil
+}
+
+func testRedshiftClusters(client IRedshiftAPI, run *SchedulingRun) (*RedshiftClusterCount, error) {
+	clusters, err := listRedshiftClusters(client, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about Redshift clusters: %w", err)
+	}
+
+	clustersActedUpon := []string{}
+	skippedClusters := []string{}
+	for _, cluster := range clusters {
+		run.Printf("INFO: Redshift Cluster Identifier: [ %v ]\n", *cluster.ClusterIdentifier)
+		resource := redshiftClusterResource{client: client, cluster: cluster}
+		instanceSchedulingTag, overrideUntil, _, skipCluster, skippedClustersModified := parseResourceTags(run, resource, skippedClusters)
+		skippedClusters = skippedClustersModified
+
+		if skipCluster {
+			continue
+		}
+
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		if isOverrideActive(overrideUntil, run.Now) {
+			skippedClusters = append(skippedClusters, *cluster.ClusterIdentifier)
+			run.Printf("INFO: Skipped Redshift cluster because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+			continue
+		}
+
+		if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+			skippedClusters = append(skippedClusters, *cluster.ClusterIdentifier)
+			run.Printf("INFO: Skipped Redshift cluster because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+			continue
+		}
+
+		if instanceSchedulingTag == "skip-auto-stop" || instanceSchedulingTag == "skip-auto-start" {
+			skippedClusters = append(skippedClusters, *cluster.ClusterIdentifier)
+			run.Printf("INFO: Skipped Redshift cluster with identifier %v because instance-scheduling tag having value 'skip-auto-stop' or 'skip-auto-start'\n", *cluster.ClusterIdentifier)
+			continue
+		}
+
+		clustersActedUpon = append(clustersActedUpon, *cluster.ClusterIdentifier)
+		run.Printf("INFO: Successfully tested Redshift cluster with identifier %v because instance-scheduling tag is absent\n", *cluster.ClusterIdentifier)
+	}
+
+	run.Printf("INFO: Tested %v Redshift clusters: %v\n", len(clustersActedUpon), clustersActedUpon)
+	run.Printf("INFO: Skipped %v Redshift clusters due to instance-scheduling tag: %v\n", len(skippedClusters), skippedClusters)
+
+	return &RedshiftClusterCount{RedshiftActedUpon: len(clustersActedUpon), RedshiftSkipped: len(skippedClusters)}, nil
+}
+
+func reconcileRedshiftClusters(client IRedshiftAPI, run *SchedulingRun) (*RedshiftClusterCount, error) {
+	clusters, err := listRedshiftClusters(client, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about Redshift clusters: %w", err)
+	}
+
+	clustersResumed := []string{}
+	clustersPaused := []string{}
+	skippedClusters := []string{}
+	for _, cluster := range clusters {
+		run.Printf("INFO: Redshift Cluster Identifier: [ %v ]\n", *cluster.ClusterIdentifier)
+		resource := redshiftClusterResource{client: client, cluster: cluster}
+		instanceSchedulingTag, overrideUntil, _, skipCluster, skippedClustersModified := parseResourceTags(run, resource, skippedClusters)
+		skippedClusters = skippedClustersModified
+
+		if skipCluster {
+			continue
+		}
+
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		schedule := run.Schedules.getSchedule(run, instanceSchedulingTag)
+		if schedule == nil {
+			skippedClusters = append(skippedClusters, *cluster.ClusterIdentifier)
+			run.Printf("INFO: Skipped Redshift cluster because its instance-scheduling tag does not reference a schedule\n")
+			continue
+		}
+
+		desiredState := run.desiredState(schedule)
+		clusterStatus := aws.ToString(cluster.ClusterStatus)
+
+		if desiredState == scheduleStateRunning && clusterStatus == "paused" {
+			clustersResumed = append(clustersResumed, *cluster.ClusterIdentifier)
+			resumeRedshiftCluster(client, run, *cluster.ClusterIdentifier)
+			run.Printf("INFO: Resumed Redshift cluster because schedule '%v' expects it to be running\n", schedule.Name)
+			continue
+		}
+
+		if desiredState == scheduleStateStopped && clusterStatus == "available" && isOverrideActive(overrideUntil, run.Now) {
+			skippedClusters = append(skippedClusters, *cluster.ClusterIdentifier)
+			run.Printf("INFO: Skipped pausing Redshift cluster because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+			continue
+		}
+
+		if desiredState == scheduleStateStopped && clusterStatus == "available" {
+			clustersPaused = append(clustersPaused, *cluster.ClusterIdentifier)
+			pauseRedshiftCluster(client, run, *cluster.ClusterIdentifier)
+			run.Printf("INFO: Paused Redshift cluster because schedule '%v' expects it to be stopped\n", schedule.Name)
+			continue
+		}
+
+		skippedClusters = append(skippedClusters, *cluster.ClusterIdentifier)
+		run.Printf("INFO: Skipped Redshift cluster with status '%v' because schedule '%v' expects it to be '%v'\n", clusterStatus, schedule.Name, desiredState)
+	}
+
+	run.Printf("INFO: Resumed %v Redshift clusters: %v\n", len(clustersResumed), clustersResumed)
+	run.Printf("INFO: Paused %v Redshift clusters: %v\n", len(clustersPaused), clustersPaused)
+	run.Printf("INFO: Skipped %v Redshift clusters due to instance-scheduling tag or schedule: %v\n", len(skippedClusters), skippedClusters)
+
+	return &RedshiftClusterCount{RedshiftActedUpon: len(clustersResumed) + len(clustersPaused), RedshiftSkipped: len(skippedClusters)}, nil
+}
+
+func getRedshiftClientForMemberAccount(session *MemberAccountSession) IRedshiftAPI {
+	return redshift.NewFromConfig(session.Config)
+}
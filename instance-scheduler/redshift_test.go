@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/redshift"
+	redshifttype "github.com/aws/aws-sdk-go-v2/service/redshift/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockIRedshiftAPI struct {
+	DescribeClustersOutput *redshift.DescribeClustersOutput
+	PausedClusters         []string
+	ResumedClusters        []string
+	CreateTagsInputs       []*redshift.CreateTagsInput
+	DeleteTagsInputs       []*redshift.DeleteTagsInput
+}
+
+func (m *mockIRedshiftAPI) DescribeClusters(ctx context.Context, params *redshift.DescribeClustersInput, optFns ...func(*redshift.Options)) (*redshift.DescribeClustersOutput, error) {
+	return m.DescribeClustersOutput, nil
+}
+
+func (m *mockIRedshiftAPI) PauseCluster(ctx context.Context, params *redshift.PauseClusterInput, optFns ...func(*redshift.Options)) (*redshift.PauseClusterOutput, error) {
+	m.PausedClusters = append(m.PausedClusters, *params.ClusterIdentifier)
+	return &redshift.PauseClusterOutput{}, nil
+}
+
+func (m *mockIRedshiftAPI) ResumeCluster(ctx context.Context, params *redshift.ResumeClusterInput, optFns ...func(*redshift.Options)) (*redshift.ResumeClusterOutput, error) {
+	m.ResumedClusters = append(m.ResumedClusters, *params.ClusterIdentifier)
+	return &redshift.ResumeClusterOutput{}, nil
+}
+
+func (m *mockIRedshiftAPI) CreateTags(ctx context.Context, params *redshift.CreateTagsInput, optFns ...func(*redshift.Options)) (*redshift.CreateTagsOutput, error) {
+	m.CreateTagsInputs = append(m.CreateTagsInputs, params)
+	return &redshift.CreateTagsOutput{}, nil
+}
+
+func (m *mockIRedshiftAPI) DeleteTags(ctx context.Context, params *redshift.DeleteTagsInput, optFns ...func(*redshift.Options)) (*redshift.DeleteTagsOutput, error) {
+	m.DeleteTagsInputs = append(m.DeleteTagsInputs, params)
+	return &redshift.DeleteTagsOutput{}, nil
+}
+
+func TestStopStartTestRedshiftClustersInMemberAccount(t *testing.T) {
+	clusters := []redshifttype.Cluster{
+		// no instance-scheduling tag and available, acted upon by stop and test
+		{
+			ClusterIdentifier:   aws.String("analytics"),
+			ClusterNamespaceArn: aws.String("arn:aws:redshift:eu-west-2:123456789012:namespace:2b5f4cbd-1e5c-4b2b-9f5e-0d6e0b8f1a11"),
+			ClusterStatus:       aws.String("available"),
+		},
+		// instance-scheduling = skip-scheduling, skipped: 1
+		{
+			ClusterIdentifier: aws.String("analytics-2"),
+			ClusterStatus:     aws.String("available"),
+			Tags: []redshifttype.Tag{
+				{Key: aws.String("instance-scheduling"), Value: aws.String("skip-scheduling")},
+			},
+		},
+		// paused by the scheduler, acted upon by start and test, already in desired state for stop
+		{
+			ClusterIdentifier:   aws.String("analytics-3"),
+			ClusterNamespaceArn: aws.String("arn:aws:redshift:eu-west-2:123456789012:namespace:5c1d1f0e-8a55-4a8e-9d0c-3f3bb0c5c2e4"),
+			ClusterStatus:       aws.String("paused"),
+			Tags: []redshifttype.Tag{
+				{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+			},
+		},
+		// instance-scheduling = skip-auto-stop, skipped by stop and test, already in desired state for start
+		{
+			ClusterIdentifier: aws.String("analytics-4"),
+			ClusterStatus:     aws.String("available"),
+			Tags: []redshifttype.Tag{
+				{Key: aws.String("instance-scheduling"), Value: aws.String("skip-auto-stop")},
+			},
+		},
+		// nights-only expects the cluster to be stopped at midday and it is available, acted upon by reconcile
+		{
+			ClusterIdentifier: aws.String("analytics-5"),
+			ClusterStatus:     aws.String("available"),
+			Tags: []redshifttype.Tag{
+				{Key: aws.String("instance-scheduling"), Value: aws.String("nights-only")},
+			},
+		},
+		// modifying, so it is not actionable by stop and start, acted upon by test and skipped by reconcile
+		{
+			ClusterIdentifier: aws.String("analytics-6"),
+			ClusterStatus:     aws.String("modifying"),
+		},
+	}
+
+	tests := []struct {
+		testTitle       string
+		action          string
+		expectedCount   RedshiftClusterCount
+		expectedPaused  []string
+		expectedResumed []string
+	}{
+		{
+			testTitle:     "Redshift testing Test action",
+			action:        "test",
+			expectedCount: RedshiftClusterCount{RedshiftActedUpon: 3, RedshiftSkipped: 3},
+		},
+		{
+			testTitle:      "Redshift testing Stop action",
+			action:         "stop",
+			expectedCount:  RedshiftClusterCount{RedshiftActedUpon: 1, RedshiftSkipped: 3, RedshiftAlreadyInDesiredState: 1, RedshiftNotActionable: 1},
+			expectedPaused: []string{"analytics"},
+		},
+		{
+			testTitle:       "Redshift testing Start action",
+			action:          "start",
+			expectedCount:   RedshiftClusterCount{RedshiftActedUpon: 1, RedshiftSkipped: 2, RedshiftAlreadyInDesiredState: 2, RedshiftNotActionable: 1},
+			expectedResumed: []string{"analytics-3"},
+		},
+		{
+			testTitle:      "Redshift testing Reconcile action",
+			action:         "reconcile",
+			expectedCount:  RedshiftClusterCount{RedshiftActedUpon: 1, RedshiftSkipped: 5},
+			expectedPaused: []string{"analytics-5"},
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.testTitle, func(t *testing.T) {
+			client := &mockIRedshiftAPI{
+				DescribeClustersOutput: &redshift.DescribeClustersOutput{Clusters: clusters},
+			}
+			actualCount, err := stopStartTestRedshiftClustersInMemberAccount(client, &SchedulingRun{Action: subtest.action, Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+			assert.NoError(t, err)
+			assert.Equal(t, subtest.expectedCount, *actualCount)
+			assert.Equal(t, subtest.expectedPaused, client.PausedClusters)
+			assert.Equal(t, subtest.expectedResumed, client.ResumedClusters)
+		})
+	}
+}
+
+func TestRedshiftStoppedBySchedulerTags(t *testing.T) {
+	cluster := redshifttype.Cluster{
+		ClusterIdentifier:   aws.String("analytics"),
+		ClusterNamespaceArn: aws.String("arn:aws:redshift:eu-west-2:123456789012:namespace:2b5f4cbd-1e5c-4b2b-9f5e-0d6e0b8f1a11"),
+		ClusterStatus:       aws.String("available"),
+	}
+	client := &mockIRedshiftAPI{DescribeClustersOutput: &redshift.DescribeClustersOutput{Clusters: []redshifttype.Cluster{cluster}}}
+	stopStartTestRedshiftClustersInMemberAccount(client, &SchedulingRun{Action: "stop", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+
+	assert.Len(t, client.CreateTagsInputs, 1)
+	assert.Equal(t, "arn:aws:redshift:eu-west-2:123456789012:cluster:analytics", *client.CreateTagsInputs[0].ResourceName)
+	assert.Equal(t, []redshifttype.Tag{
+		{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+		{Key: aws.String("instance-scheduler:stopped-at"), Value: aws.String("2026-10-14T12:00:00Z")},
+	}, client.CreateTagsInputs[0].Tags)
+
+	cluster.ClusterStatus = aws.String("paused")
+	cluster.Tags = client.CreateTagsInputs[0].Tags
+	client = &mockIRedshiftAPI{DescribeClustersOutput: &redshift.DescribeClustersOutput{Clusters: []redshifttype.Cluster{cluster}}}
+	stopStartTestRedshiftClustersInMemberAccount(client, &SchedulingRun{Action: "start", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+
+	assert.Equal(t, []string{"analytics"}, client.ResumedClusters)
+	assert.Len(t, client.DeleteTagsInputs, 1)
+	assert.Equal(t, "arn:aws:redshift:eu-west-2:123456789012:cluster:analytics", *client.DeleteTagsInputs[0].ResourceName)
+	assert.Equal(t, []string{"instance-scheduler:stopped-by", "instance-scheduler:stopped-at"}, client.DeleteTagsInputs[0].TagKeys)
+}
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sagemaker"
+	sagemakertype "github.com/aws/aws-sdk-go-v2/service/sagemaker/types"
+)
+
+// SageMaker notebook instances are stopped and started like EC2 instances. Studio KernelGateway apps cannot be
+// stopped, so when configured to the stop action deletes those nobody has used for sageMakerAppIdleTimeout. Studio
+// keeps the user's files and recreates the app when a notebook next asks for a kernel.
+
+const sageMakerAppIdleTimeout time.Duration = time.Hour
+
+type SageMakerCount struct {
+	SageMakerActedUpon             int
+	SageMakerSkipped               int
+	SageMakerAppsDeleted           int
+	SageMakerFailed                int
+	SageMakerStoppedNotByScheduler int
+}
+
+type ISageMakerAPI interface {
+	ListNotebookInstances(ctx context.Context, params *sagemaker.ListNotebookInstancesInput, optFns ...func(*sagemaker.Options)) (*sagemaker.ListNotebookInstancesOutput, error)
+	ListTags(ctx context.Context, params *sagemaker.ListTagsInput, optFns ...func(*sagemaker.Options)) (*sagemaker.ListTagsOutput, error)
+	StopNotebookInstance(ctx context.Context, params *sagemaker.StopNotebookInstanceInput, optFns ...func(*sagemaker.Options)) (*sagemaker.StopNotebookInstanceOutput, error)
+	StartNotebookInstance(ctx context.Context, params *sagemaker.StartNotebookInstanceInput, optFns ...func(*sagemaker.Options)) (*sagemaker.StartNotebookInstanceOutput, error)
+	AddTags(ctx context.Context, params *sagemaker.AddTagsInput, optFns ...func(*sagemaker.Options)) (*sagemaker.AddTagsOutput, error)
+	DeleteTags(ctx context.Context, params *sagemaker.DeleteTagsInput, optFns ...func(*sagemaker.Options)) (*sagemaker.DeleteTagsOutput, error)
+	ListApps(ctx context.Context, params *sagemaker.ListAppsInput, optFns ...func(*sagemaker.Options)) (*sagemaker.ListAppsOutput, error)
+	DescribeApp(ctx context.Context, params *sagemaker.DescribeAppInput, optFns ...func(*sagemaker.Options)) (*sagemaker.DescribeAppOutput, error)
+	DeleteApp(ctx context.Context, params *sagemaker.DeleteAppInput, optFns ...func(*sagemaker.Options)) (*sagemaker.DeleteAppOutput, error)
+}
+
+// SageMakerNotebook is a notebook instance together with its tags, which ListNotebookInstances does not return
+type SageMakerNotebook struct {
+	Summary sagemakertype.NotebookInstanceSummary
+	Tags    []sagemakertype.Tag
+}
+
+func stopStartTestSageMakerInMemberAccount(client ISageMakerAPI, run *SchedulingRun) (*SageMakerCount, error) {
+	action := run.Action
+	if action == "stop" {
+		count, err := stopSageMakerNotebooks(client, run)
+		if err != nil {
+			return nil, err
+		}
+		if run.DeleteIdleSageMakerApps {
+			count.SageMakerAppsDeleted, err = deleteIdleSageMakerApps(client, run)
+		}
+		return count, err
+	}
+	if action == "start" {
+		return startSageMakerNotebooks(client, run)
+	}
+	if action == "test" {
+		return testSageMakerNotebooks(client, run)
+	}
+	if action == "reconcile" {
+		return reconcileSageMakerNotebooks(client, run)
+	}
+	return nil, invalidActionError(action)
+}
+
+func listSageMakerNotebooks(client ISageMakerAPI, run *SchedulingRun) ([]SageMakerNotebook, error) {
+	notebooks := []SageMakerNotebook{}
+	pages := sagemaker.NewListNotebookInstancesPaginator(client, &sagemaker.ListNotebookInstancesInput{})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(run.ctx())
+		if err != nil {
+			return nil, err
+		}
+		for _, summary := range page.NotebookInstances {
+			tags, err := listSageMakerTags(client, run, summary.NotebookInstanceArn)
+			if err != nil {
+				return nil, err
+			}
+			notebooks = append(notebooks, SageMakerNotebook{Summary: summary, Tags: tags})
+		}
+	}
+	return notebooks, nil
+}
+
+// listSageMakerTags returns the tags of a notebook instance or Studio app, which SageMaker does not list with them
+func listSageMakerTags(client ISageMakerAPI, run *SchedulingRun, resourceArn *string) ([]sagemakertype.Tag, error) {
+	tags := []sagemakertype.Tag{}
+	pages := sagemaker.NewListTagsPaginator(client, &sagemaker.ListTagsInput{ResourceArn: resourceArn})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(run.ctx())
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, page.Tags...)
+	}
+	return tags, nil
+}
+
+// sageMakerTags converts the tags of a notebook instance or Studio app to a map
+func sageMakerTags(tagList []sagemakertype.Tag) map[string]string {
+	tags := map[string]string{}
+	for _, tag := range tagList {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags
+}
+
+func stopSageMakerNotebook(client ISageMakerAPI, run *SchedulingRun, notebookName string) error {
+	_, err := client.StopNotebookInstance(run.ctx(), &sagemaker.StopNotebookInstanceInput{
+		NotebookInstanceName: aws.String(notebookName),
+	})
+	if err == nil {
+		run.Printf("INFO: Successfully stopped SageMaker notebook instance %v\n", notebookName)
+	} else {
+		run.Printf("ERROR: Could not stop SageMaker notebook instance: %v\n", err)
+	}
+	return err
+}
+
+func startSageMakerNotebook(client ISageMakerAPI, run *SchedulingRun, notebookName string) error {
+	_, err := client.StartNotebookInstance(run.ctx(), &sagemaker.StartNotebookInstanceInput{
+		NotebookInstanceName: aws.String(notebookName),
+	})
+	if err == nil {
+		run.Printf("INFO: Successfully started SageMaker notebook instance %v\n", notebookName)
+	} else {
+		run.Printf("ERROR: Could not start SageMaker notebook instance: %v\n", err)
+	}
+	return err
+}
+
+func stopSageMakerNotebooks(client ISageMakerAPI, run *SchedulingRun) (*SageMakerCount, error) {
+	notebooks, err := listSageMakerNotebooks(client, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about SageMaker notebook instances: %w", err)
+	}
+
+	notebooksActedUpon := []string{}
+	skippedNotebooks := []string{}
+	failedNotebooks := []string{}
+	for _, notebook := range notebooks {
+		notebookName := *notebook.Summary.NotebookInstanceName
+		run.Printf("INFO: SageMaker notebook instance: [ %v ]\n", notebookName)
+		resource := sageMakerNotebookResource{client: client, notebook: notebook}
+		instanceSchedulingTag, overrideUntil, _, skipNotebook, skippedNotebooksModified := parseResourceTags(run, resource, skippedNotebooks)
+		skippedNotebooks = skippedNotebooksModified
+
+		if skipNotebook {
+			continue
+		}
+
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		if isOverrideActive(overrideUntil, run.Now) {
+			skippedNotebooks = append(skippedNotebooks, notebookName)
+			run.Printf("INFO: Skipped SageMaker notebook instance because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+			continue
+		}
+
+		if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+			skippedNotebooks = append(skippedNotebooks, notebookName)
+			run.Printf("INFO: Skipped SageMaker notebook instance because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+			continue
+		}
+
+		if instanceSchedulingTag == "skip-auto-stop" {
+			skippedNotebooks = append(skippedNotebooks, notebookName)
+			run.Printf("INFO: Skipped SageMaker notebook instance because instance-scheduling tag having value 'skip-auto-stop'\n")
+			continue
+		}
+
+		if notebook.Summary.NotebookInstanceStatus != sagemakertype.NotebookInstanceStatusInService {
+			skippedNotebooks = append(skippedNotebooks, notebookName)
+			run.Printf("INFO: Skipped SageMaker notebook instance with status '%v' because it is not in service\n", notebook.Summary.NotebookInstanceStatus)
+			continue
+		}
+
+		run.Printf("INFO: Stopping SageMaker notebook instance because instance-scheduling tag is absent\n")
+		if stopSageMakerNotebook(client, run, notebookName) != nil {
+			failedNotebooks = append(failedNotebooks, notebookName)
+			continue
+		}
+		tagResourceStoppedByScheduler(run, resource)
+		notebooksActedUpon = append(notebooksActedUpon, notebookName)
+	}
+
+	run.Printf("INFO: Stopped %v SageMaker notebook instances: %v\n", len(notebooksActedUpon), notebooksActedUpon)
+	run.Printf("INFO: Skipped %v SageMaker notebook instances due to instance-scheduling tag or status: %v\n", len(skippedNotebooks), skippedNotebooks)
+	run.Printf("INFO: Could not stop %v SageMaker notebook instances: %v\n", len(failedNotebooks), failedNotebooks)
+
+	return &SageMakerCount{SageMakerActedUpon: len(notebooksActedUpon), SageMakerSkipped: len(skippedNotebooks), SageMakerFailed: len(failedNotebooks)}, nil
+}
+
+func startSageMakerNotebooks(client ISageMakerAPI, run *SchedulingRun) (*SageMakerCount, error) {
+	notebooks, err := listSageMakerNotebooks(client, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about SageMaker notebook instances: %w", err)
+	}
+
+	notebooksActedUpon := []string{}
+	skippedNotebooks := []string{}
+	failedNotebooks := []string{}
+	stoppedNotBySchedulerNotebooks := []string{}
+	for _, notebook := range notebooks {
+		notebookName := *notebook.Summary.NotebookInstanceName
+		run.Printf("INFO: SageMaker notebook instance: [ %v ]\n", notebookName)
+		resource := sageMakerNotebookResource{client: client, notebook: notebook}
+		instanceSchedulingTag, overrideUntil, stoppedByScheduler, skipNotebook, skippedNotebooksModified := parseResourceTags(run, resource, skippedNotebooks)
+		skippedNotebooks = skippedNotebooksModified
+
+		if skipNotebook {
+			continue
+		}
+
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+			skippedNotebooks = append(skippedNotebooks, notebookName)
+			run.Printf("INFO: Skipped SageMaker notebook instance because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+			continue
+		}
+
+		if instanceSchedulingTag == "skip-auto-start" {
+			skippedNotebooks = append(skippedNotebooks, notebookName)
+			run.Printf("INFO: Skipped SageMaker notebook instance because instance-scheduling tag having value 'skip-auto-start'\n")
+			continue
+		}
+
+		if notebook.Summary.NotebookInstanceStatus != sagemakertype.NotebookInstanceStatusStopped {
+			skippedNotebooks = append(skippedNotebooks, notebookName)
+			run.Printf("INFO: Skipped SageMaker notebook instance with status '%v' because it is not stopped\n", notebook.Summary.NotebookInstanceStatus)
+			continue
+		}
+
+		if !stoppedByScheduler {
+			stoppedNotBySchedulerNotebooks = append(stoppedNotBySchedulerNotebooks, notebookName)
+			run.Printf("INFO: Skipped SageMaker notebook instance because it was not stopped by the scheduler\n")
+			continue
+		}
+
+		run.Printf("INFO: Starting SageMaker notebook instance because it was stopped by the scheduler\n")
+		if startSageMakerNotebook(client, run, notebookName) != nil {
+			failedNotebooks = append(failedNotebooks, notebookName)
+			continue
+		}
+		untagResourceStoppedByScheduler(run, resource)
+		notebooksActedUpon = append(notebooksActedUpon, notebookName)
+	}
+
+	run.Printf("INFO: Started %v SageMaker notebook instances: %v\n", len(notebooksActedUpon), notebooksActedUpon)
+	run.Printf("INFO: Skipped %v SageMaker notebook instances due to instance-scheduling tag or status: %v\n", len(skippedNotebooks), skippedNotebooks)
+	run.Printf("INFO: Found %v stopped SageMaker notebook instances which were not stopped by the scheduler: %v\n", len(stoppedNotBySchedulerNotebooks), stoppedNotBySchedulerNotebooks)
+	run.Printf("INFO: Could not start %v SageMaker notebook instances: %v\n", len(failedNotebooks), failedNotebooks)
+
+	return &SageMakerCount{SageMakerActedUpon: len(notebooksActedUpon), SageMakerSkipped: len(skippedNotebooks), SageMakerStoppedNotByScheduler: len(stoppedNotBySchedulerNotebooks), SageMakerFailed: len(failedNotebooks)}, nil
+}
+
+func testSageMakerNotebooks(client ISageMakerAPI, run *SchedulingRun) (*SageMakerCount, error) {
+	notebooks, err := listSageMakerNotebooks(client, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about SageMaker notebook instances: %w", err)
+	}
+
+	notebooksActedUpon := []string{}
+	skippedNotebooks := []string{}
+	for _, notebook := range notebooks {
+		notebookName := *notebook.Summary.NotebookInstanceName
+		run.Printf("INFO: SageMaker notebook instance: [ %v ]\n", notebookName)
+		resource := sageMakerNotebookResource{client: client, notebook: notebook}
+		instanceSchedulingTag, overrideUntil, _, skipNotebook, skippedNotebooksModified := parseResourceTags(run, resource, skippedNotebooks)
+		skippedNotebooks = skippedNotebooksModified
+
+		if skipNotebook {
+			continue
+		}
+
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		if isOverrideActive(overrideUntil, run.Now) {
+			skippedNotebooks = append(skippedNotebooks, notebookName)
+			run.Printf("INFO: Skipped SageMaker notebook instance because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+			continue
+		}
+
+		if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+			skippedNotebooks = append(skippedNotebooks, notebookName)
+			run.Printf("INFO: Skipped SageMaker notebook instance because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+			continue
+		}
+
+		if instanceSchedulingTag == "skip-auto-stop" || instanceSchedulingTag == "skip-auto-start" {
+			skippedNotebooks = append(skippedNotebooks, notebookName)
+			run.Printf("INFO: Skipped SageMaker notebook instance %v because instance-scheduling tag having value 'skip-auto-stop' or 'skip-auto-start'\n", notebookName)
+			continue
+		}
+
+		notebooksActedUpon = append(notebooksActedUpon, notebookName)
+		run.Printf("INFO: Successfully tested SageMaker notebook instance %v because instance-scheduling tag is absent\n", notebookName)
+	}
+
+	run.Printf("INFO: Tested %v SageMaker notebook instances: %v\n", len(notebooksActedUpon), notebooksActedUpon)
+	run.Printf("INFO: Skipped %v SageMaker notebook instances due to instance-scheduling tag: %v\n", len(skippedNotebooks), skippedNotebooks)
+
+	return &SageMakerCount{SageMakerActedUpon: len(notebooksActedUpon), SageMakerSkipped: len(skippedNotebooks)}, nil
+}
+
+func reconcileSageMakerNotebooks(client ISageMakerAPI, run *SchedulingRun) (*SageMakerCount, error) {
+	notebooks, err := listSageMakerNotebooks(client, run)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve information about SageMaker notebook instances: %w", err)
+	}
+
+	notebooksStarted := []string{}
+	notebooksStopped := []string{}
+	skippedNotebooks := []string{}
+	failedNotebooks := []string{}
+	for _, notebook := range notebooks {
+		notebookName := *notebook.Summary.NotebookInstanceName
+		run.Printf("INFO: SageMaker notebook instance: [ %v ]\n", notebookName)
+		resource := sageMakerNotebookResource{client: client, notebook: notebook}
+		instanceSchedulingTag, overrideUntil, _, skipNotebook, skippedNotebooksModified := parseResourceTags(run, resource, skippedNotebooks)
+		skippedNotebooks = skippedNotebooksModified
+
+		if skipNotebook {
+			continue
+		}
+
+		removeExpiredOverride(run, resource, overrideUntil)
+
+		schedule := run.Schedules.getSchedule(run, instanceSchedulingTag)
+		if schedule == nil {
+			skippedNotebooks = append(skippedNotebooks, notebookName)
+			run.Printf("INFO: Skipped SageMaker notebook instance because its instance-scheduling tag does not reference a schedule\n")
+			continue
+		}
+
+		desiredState := run.desiredState(schedule)
+		notebookStatus := notebook.Summary.NotebookInstanceStatus
+
+		if desiredState == scheduleStateRunning && notebookStatus == sagemakertype.NotebookInstanceStatusStopped {
+			run.Printf("INFO: Starting SageMaker notebook instance because schedule '%v' expects it to be running\n", schedule.Name)
+			if startSageMakerNotebook(client, run, notebookName) != nil {
+				failedNotebooks = append(failedNotebooks, notebookName)
+				continue
+			}
+			notebooksStarted = append(notebooksStarted, notebookName)
+			continue
+		}
+
+		if desiredState == scheduleStateStopped && notebookStatus == sagemakertype.NotebookInstanceStatusInService && isOverrideActive(overrideUntil, run.Now) {
+			skippedNotebooks = append(skippedNotebooks, notebookName)
+			run.Printf("INFO: Skipped stopping SageMaker notebook instance because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+			continue
+		}
+
+		if desiredState == scheduleStateStopped && notebookStatus == sagemakertype.NotebookInstanceStatusInService {
+			run.Printf("INFO: Stopping SageMaker notebook instance because schedule '%v' expects it to be stopped\n", schedule.Name)
+			if stopSageMakerNotebook(client, run, notebookName) != nil {
+				failedNotebooks = append(failedNotebooks, notebookName)
+				continue
+			}
+			notebooksStopped = append(notebooksStopped, notebookName)
+			continue
+		}
+
+		skippedNotebooks = append(skippedNotebooks, notebookName)
+		run.Printf("INFO: Skipped SageMaker notebook instance with status '%v' because schedule '%v' expects it to be '%v'\n", notebookStatus, schedule.Name, desiredState)
+	}
+
+	run.Printf("INFO: Started %v SageMaker notebook instances: %v\n", len(notebooksStarted), notebooksStarted)
+	run.Printf("INFO: Stopped %v SageMaker notebook instances: %v\n", len(notebooksStopped), notebooksStopped)
+	run.Printf("INFO: Skipped %v SageMaker notebook instances due to instance-scheduling tag or schedule: %v\n", len(skippedNotebooks), skippedNotebooks)
+	run.Printf("INFO: Could not start or stop %v SageMaker notebook instances: %v\n", len(failedNotebooks), failedNotebooks)
+
+	return &SageMakerCount{SageMakerActedUpon: len(notebooksStarted) + len(notebooksStopped), SageMakerSkipped: len(skippedNotebooks), SageMakerFailed: len(failedNotebooks)}, nil
+}
+
+// deleteIdleSageMakerApps deletes the in-service Studio KernelGateway apps that have had no user activity for
+// sageMakerAppIdleTimeout and returns how many were deleted. Apps are kept when their instance-scheduling tag skips
+// stopping or references a schedule, when an override keeps them running, or when SageMaker recorded no activity.
+func deleteIdleSageMakerApps(client ISageMakerAPI, run *SchedulingRun) (int, error) {
+	appsDeleted := []string{}
+	pages := sagemaker.NewListAppsPaginator(client, &sagemaker.ListAppsInput{})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(run.ctx())
+		if err != nil {
+			return len(appsDeleted), fmt.Errorf("could not retrieve information about SageMaker Studio apps: %w", err)
+		}
+		for _, app := range page.Apps {
+			if app.AppType != sagemakertype.AppTypeKernelGateway || app.Status != sagemakertype.AppStatusInService {
+				continue
+			}
+			run.Printf("INFO: SageMaker Studio app: [ %v ]\n", *app.AppName)
+
+			details, err := client.DescribeApp(run.ctx(), &sagemaker.DescribeAppInput{
+				DomainId:        app.DomainId,
+				AppType:         app.AppType,
+				AppName:         app.AppName,
+				UserProfileName: app.UserProfileName,
+				SpaceName:       app.SpaceName,
+			})
+			if err != nil {
+				run.Printf("ERROR: Could not describe SageMaker Studio app %v: %v\n", *app.AppName, err)
+				continue
+			}
+
+			tags, err := listSageMakerTags(client, run, details.AppArn)
+			if err != nil {
+				run.Printf("ERROR: Could not retrieve tags of SageMaker Studio app %v: %v\n", *app.AppName, err)
+				continue
+			}
+			instanceSchedulingTag, overrideUntil, _ := parseSchedulingTags(run, sageMakerTags(tags))
+			if instanceSchedulingTag == "skip-scheduling" || instanceSchedulingTag == "skip-auto-stop" {
+				run.Printf("INFO: Skipped SageMaker Studio app because instance-scheduling tag having value '%v'\n", instanceSchedulingTag)
+				continue
+			}
+			if run.Schedules.getSchedule(run, instanceSchedulingTag) != nil {
+				run.Printf("INFO: Skipped SageMaker Studio app because it follows the schedule '%v' in its instance-scheduling tag\n", instanceSchedulingTag)
+				continue
+			}
+			if isOverrideActive(overrideUntil, run.Now) {
+				run.Printf("INFO: Skipped SageMaker Studio app because %v tag keeps it running until %v\n", overrideUntilTagKey, overrideUntil.Format(time.RFC3339))
+				continue
+			}
+
+			if details.LastUserActivityTimestamp == nil {
+				run.Printf("INFO: Skipped SageMaker Studio app because SageMaker recorded no user activity for it\n")
+				continue
+			}
+			if run.Now.Sub(*details.LastUserActivityTimestamp) < sageMakerAppIdleTimeout {
+				run.Printf("INFO: Skipped SageMaker Studio app because it was last used at %v\n", details.LastUserActivityTimestamp.Format(time.RFC3339))
+				continue
+			}
+
+			_, err = client.DeleteApp(run.ctx(), &sagemaker.DeleteAppInput{
+				DomainId:        app.DomainId,
+				AppType:         app.AppType,
+				AppName:         app.AppName,
+				UserProfileName: app.UserProfileName,
+				SpaceName:       app.SpaceName,
+			})
+			if err != nil {
+				run.Printf("ERROR: Could not delete SageMaker Studio app %v: %v\n", *app.AppName, err)
+				continue
+			}
+			run.Printf("INFO: Successfully deleted idle SageMaker Studio app %v\n", *app.AppName)
+			appsDeleted = append(appsDeleted, *app.AppName)
+		}
+	}
+
+	run.Printf("INFO: Deleted %v idle SageMaker Studio apps: %v\n", len(appsDeleted), appsDeleted)
+	return len(appsDeleted), nil
+}
+
+// sageMakerNotebookResource adapts a SageMaker notebook instance to the tag helpers shared by every service
+type sageMakerNotebookResource struct {
+	client   ISageMakerAPI
+	notebook SageMakerNotebook
+}
+
+func (resource sageMakerNotebookResource) kind() string {
+	return "SageMaker notebook instance"
+}
+
+func (resource sageMakerNotebookResource) id() string {
+	return aws.ToString(resource.notebook.Summary.NotebookInstanceName)
+}
+
+func (resource sageMakerNotebookResource) tags() map[string]string {
+	return sageMakerTags(resource.notebook.Tags)
+}
+
+func (resource sageMakerNotebookResource) addTags(run *SchedulingRun, tags []resourceTag) error {
+	notebookTags := []sagemakertype.Tag{}
+	for _, tag := range tags {
+		notebookTags = append(notebookTags, sagemakertype.Tag{Key: aws.String(tag.key), Value: aws.String(tag.value)})
+	}
+	_, err := resource.client.AddTags(run.ctx(), &sagemaker.AddTagsInput{
+		ResourceArn: resource.notebook.Summary.NotebookInstanceArn,
+		Tags:        notebookTags,
+	})
+	return err
+}
+
+func (resource sageMakerNotebookResource) removeTags(run *SchedulingRun, keys []string) error {
+	_, err := resource.client.DeleteTags(run.ctx(), &sagemaker.DeleteTagsInput{
+		ResourceArn: resource.notebook.Summary.NotebookInstanceArn,
+		TagKeys:     keys,
+	})
+	return err
+}
+
+func getSageMakerClientForMemberAccount(session *MemberAccountSession) ISageMakerAPI {
+	return sagemaker.NewFromConfig(session.Config)
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sagemaker"
+	sagemakertype "github.com/aws/aws-sdk-go-v2/service/sagemaker/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockISageMakerAPI struct {
+	Notebooks        []SageMakerNotebook
+	Apps             []sagemakertype.AppDetails
+	LastUserActivity map[string]time.Time
+	AppTags          map[string][]sagemakertype.Tag
+	StoppedNotebooks []string
+	StartedNotebooks []string
+	DeletedApps      []string
+	AddTagsInputs    []*sagemaker.AddTagsInput
+	DeleteTagsInputs []*sagemaker.DeleteTagsInput
+}
+
+func (m *mockISageMakerAPI) ListNotebookInstances(ctx context.Context, params *sagemaker.ListNotebookInstancesInput, optFns ...func(*sagemaker.Options)) (*sagemaker.ListNotebookInstancesOutput, error) {
+	output := &sagemaker.ListNotebookInstancesOutput{}
+	for _, notebook := range m.Notebooks {
+		output.NotebookInstances = append(output.NotebookInstances, notebook.Summary)
+	}
+	return output, nil
+}
+
+func (m *mockISageMakerAPI) ListTags(ctx context.Context, params *sagemaker.ListTagsInput, optFns ...func(*sagemaker.Options)) (*sagemaker.ListTagsOutput, error) {
+	for _, notebook := range m.Notebooks {
+		if *notebook.Summary.NotebookInstanceArn == *params.ResourceArn {
+			return &sagemaker.ListTagsOutput{Tags: notebook.Tags}, nil
+		}
+	}
+	for appName, tags := range m.AppTags {
+		if testSageMakerAppArn(appName) == *params.ResourceArn {
+			return &sagemaker.ListTagsOutput{Tags: tags}, nil
+		}
+	}
+	return &sagemaker.ListTagsOutput{}, nil
+}
+
+func (m *mockISageMakerAPI) StopNotebookInstance(ctx context.Context, params *sagemaker.StopNotebookInstanceInput, optFns ...func(*sagemaker.Options)) (*sagemaker.StopNotebookInstanceOutput, error) {
+	m.StoppedNotebooks = append(m.StoppedNotebooks, *params.NotebookInstanceName)
+	return &sagemaker.StopNotebookInstanceOutput{}, nil
+}
+
+func (m *mockISageMakerAPI) StartNotebookInstance(ctx context.Context, params *sagemaker.StartNotebookInstanceInput, optFns ...func(*sagemaker.Options)) (*sagemaker.StartNotebookInstanceOutput, error) {
+	m.StartedNotebooks = append(m.StartedNotebooks, *params.NotebookInstanceName)
+	return &sagemaker.StartNotebookInstanceOutput{}, nil
+}
+
+func (m *mockISageMakerAPI) AddTags(ctx context.Context, params *sagemaker.AddTagsInput, optFns ...func(*sagemaker.Options)) (*sagemaker.AddTagsOutput, error) {
+	m.AddTagsInputs = append(m.AddTagsInputs, params)
+	return &sagemaker.AddTagsOutput{}, nil
+}
+
+func (m *mockISageMakerAPI) DeleteTags(ctx context.Context, params *sagemaker.DeleteTagsInput, optFns ...func(*sagemaker.Options)) (*sagemaker.DeleteTagsOutput, error) {
+	m.DeleteTagsInputs = append(m.DeleteTagsInputs, params)
+	return &sagemaker.DeleteTagsOutput{}, nil
+}
+
+func (m *mockISageMakerAPI) ListApps(ctx context.Context, params *sagemaker.ListAppsInput, optFns ...func(*sagemaker.Options)) (*sagemaker.ListAppsOutput, error) {
+	return &sagemaker.ListAppsOutput{Apps: m.Apps}, nil
+}
+
+func (m *mockISageMakerAPI) DescribeApp(ctx context.Context, params *sagemaker.DescribeAppInput, optFns ...func(*sagemaker.Options)) (*sagemaker.DescribeAppOutput, error) {
+	output := &sagemaker.DescribeAppOutput{AppName: params.AppName, AppArn: aws.String(testSageMakerAppArn(*params.AppName))}
+	if lastUserActivity, ok := m.LastUserActivity[*params.AppName]; ok {
+		output.LastUserActivityTimestamp = aws.Time(lastUserActivity)
+	}
+	return output, nil
+}
+
+func (m *mockISageMakerAPI) DeleteApp(ctx context.Context, params *sagemaker.DeleteAppInput, optFns ...func(*sagemaker.Options)) (*sagemaker.DeleteAppOutput, error) {
+	m.DeletedApps = append(m.DeletedApps, *params.AppName)
+	return &sagemaker.DeleteAppOutput{}, nil
+}
+
+func testSageMakerAppArn(name string) string {
+	return "arn:aws:sagemaker:eu-west-2:123456789012:app/d-abcdefghijkl/data-scientist/kernelgateway/" + name
+}
+
+func testSageMakerNotebook(name string, status sagemakertype.NotebookInstanceStatus, tags map[string]string) SageMakerNotebook {
+	notebook := SageMakerNotebook{
+		Summary: sagemakertype.NotebookInstanceSummary{
+			NotebookInstanceName:   aws.String(name),
+			NotebookInstanceArn:    aws.String("arn:aws:sagemaker:eu-west-2:123456789012:notebook-instance/" + name),
+			NotebookInstanceStatus: status,
+		},
+	}
+	for key, value := range tags {
+		notebook.Tags = append(notebook.Tags, sagemakertype.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	return notebook
+}
+
+func TestStopStartTestSageMakerInMemberAccount(t *testing.T) {
+	notebooks := []SageMakerNotebook{
+		// no instance-scheduling tag and in service, acted upon by stop and test
+		testSageMakerNotebook("analysis", sagemakertype.NotebookInstanceStatusInService, nil),
+		// instance-scheduling = skip-scheduling, skipped: 1
+		testSageMakerNotebook("analysis-2", sagemakertype.NotebookInstanceStatusInService, map[string]string{"instance-scheduling": "skip-scheduling"}),
+		// stopped by the scheduler, skipped by stop as it is not in service, acted upon by start and test
+		testSageMakerNotebook("analysis-3", sagemakertype.NotebookInstanceStatusStopped, map[string]string{"instance-scheduler:stopped-by": "scheduler"}),
+		// stopped by someone else, skipped by stop and start, acted upon by test
+		testSageMakerNotebook("analysis-4", sagemakertype.NotebookInstanceStatusStopped, nil),
+		// nights-only expects the notebook instance to be stopped at midday and it is in service, acted upon by reconcile
+		testSageMakerNotebook("analysis-5", sagemakertype.NotebookInstanceStatusInService, map[string]string{"instance-scheduling": "nights-only"}),
+	}
+
+	tests := []struct {
+		testTitle       string
+		action          string
+		expectedCount   SageMakerCount
+		expectedStopped []string
+		expectedStarted []string
+	}{
+		{
+			testTitle:     "SageMaker testing Test action",
+			action:        "test",
+			expectedCount: SageMakerCount{SageMakerActedUpon: 3, SageMakerSkipped: 2},
+		},
+		{
+			testTitle:       "SageMaker testing Stop action",
+			action:          "stop",
+			expectedCount:   SageMakerCount{SageMakerActedUpon: 1, SageMakerSkipped: 4},
+			expectedStopped: []string{"analysis"},
+		},
+		{
+			testTitle:       "SageMaker testing Start action",
+			action:          "start",
+			expectedCount:   SageMakerCount{SageMakerActedUpon: 1, SageMakerSkipped: 3, SageMakerStoppedNotByScheduler: 1},
+			expectedStarted: []string{"analysis-3"},
+		},
+		{
+			testTitle:       "SageMaker testing Reconcile action",
+			action:          "reconcile",
+			expectedCount:   SageMakerCount{SageMakerActedUpon: 1, SageMakerSkipped: 4},
+			expectedStopped: []string{"analysis-5"},
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.testTitle, func(t *testing.T) {
+			client := &mockISageMakerAPI{Notebooks: notebooks}
+			actualCount, err := stopStartTestSageMakerInMemberAccount(client, &SchedulingRun{Action: subtest.action, Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+			assert.NoError(t, err)
+			assert.Equal(t, subtest.expectedCount, *actualCount)
+			assert.Equal(t, subtest.expectedStopped, client.StoppedNotebooks)
+			assert.Equal(t, subtest.expectedStarted, client.StartedNotebooks)
+		})
+	}
+}
+
+func TestSageMakerStoppedBySchedulerTags(t *testing.T) {
+	client := &mockISageMakerAPI{Notebooks: []SageMakerNotebook{testSageMakerNotebook("analysis", sagemakertype.NotebookInstanceStatusInService, nil)}}
+	stopStartTestSageMakerInMemberAccount(client, &SchedulingRun{Action: "stop", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+
+	assert.Len(t, client.AddTagsInputs, 1)
+	assert.Equal(t, "arn:aws:sagemaker:eu-west-2:123456789012:notebook-instance/analysis", *client.AddTagsInputs[0].ResourceArn)
+	assert.Equal(t, []sagemakertype.Tag{
+		{Key: aws.String("instance-scheduler:stopped-by"), Value: aws.String("scheduler")},
+		{Key: aws.String("instance-scheduler:stopped-at"), Value: aws.String("2026-10-14T12:00:00Z")},
+	}, client.AddTagsInputs[0].Tags)
+
+	stoppedNotebook := testSageMakerNotebook("analysis", sagemakertype.NotebookInstanceStatusStopped, nil)
+	stoppedNotebook.Tags = client.AddTagsInputs[0].Tags
+	client = &mockISageMakerAPI{Notebooks: []SageMakerNotebook{stoppedNotebook}}
+	stopStartTestSageMakerInMemberAccount(client, &SchedulingRun{Action: "start", Now: testSchedulingTime, Schedules: testScheduleCatalogue})
+
+	assert.Equal(t, []string{"analysis"}, client.StartedNotebooks)
+	assert.Len(t, client.DeleteTagsInputs, 1)
+	assert.Equal(t, []string{"instance-scheduler:stopped-by", "instance-scheduler:stopped-at"}, client.DeleteTagsInputs[0].TagKeys)
+}
+
+func TestDeleteIdleSageMakerApps(t *testing.T) {
+	app := func(name string, appType sagemakertype.AppType, status sagemakertype.AppStatus) sagemakertype.AppDetails {
+		return sagemakertype.AppDetails{
+			AppName:         aws.String(name),
+			AppType:         appType,
+			Status:          status,
+			DomainId:        aws.String("d-abcdefghijkl"),
+			UserProfileName: aws.String("data-scientist"),
+		}
+	}
+	apps := []sagemakertype.AppDetails{
+		// idle for two hours, deleted
+		app("idle-kernel", sagemakertype.AppTypeKernelGateway, sagemakertype.AppStatusInService),
+		// used ten minutes ago, kept
+		app("busy-kernel", sagemakertype.AppTypeKernelGateway, sagemakertype.AppStatusInService),
+		// no recorded user activity, kept
+		app("unused-kernel", sagemakertype.AppTypeKernelGateway, sagemakertype.AppStatusInService),
+		// idle but instance-scheduling = skip-auto-stop, kept
+		app("skipped-kernel", sagemakertype.AppTypeKernelGateway, sagemakertype.AppStatusInService),
+		// idle but kept running by an override, kept
+		app("overridden-kernel", sagemakertype.AppTypeKernelGateway, sagemakertype.AppStatusInService),
+		// idle with an expired override, deleted
+		app("expired-override-kernel", sagemakertype.AppTypeKernelGateway, sagemakertype.AppStatusInService),
+		// not a KernelGateway app, kept
+		app("default", sagemakertype.AppTypeJupyterServer, sagemakertype.AppStatusInService),
+		// already deleted, kept
+		app("deleted-kernel", sagemakertype.AppTypeKernelGateway, sagemakertype.AppStatusDeleted),
+	}
+	lastUserActivity := map[string]time.Time{
+		"idle-kernel":             testSchedulingTime.Add(-2 * time.Hour),
+		"busy-kernel":             testSchedulingTime.Add(-10 * time.Minute),
+		"skipped-kernel":          testSchedulingTime.Add(-2 * time.Hour),
+		"overridden-kernel":       testSchedulingTime.Add(-2 * time.Hour),
+		"expired-override-kernel": testSchedulingTime.Add(-2 * time.Hour),
+	}
+	appTags := map[string][]sagemakertype.Tag{
+		"skipped-kernel": {
+			{Key: aws.String("instance-scheduling"), Value: aws.String("skip-auto-stop")},
+		},
+		"overridden-kernel": {
+			{Key: aws.String("instance-scheduling-override-until"), Value: aws.String("2026-10-14T22:00Z")},
+		},
+		"expired-override-kernel": {
+			{Key: aws.String("instance-scheduling-override-until"), Value: aws.String("2026-10-14T09:00Z")},
+		},
+	}
+
+	tests := []struct {
+		testTitle               string
+		action                  string
+		deleteIdleSageMakerApps bool
+		expectedDeleted         []string
+	}{
+		{
+			testTitle:               "Stop action deletes idle apps when configured to",
+			action:                  "stop",
+			deleteIdleSageMakerApps: true,
+			expectedDeleted:         []string{"idle-kernel", "expired-override-kernel"},
+		},
+		{
+			testTitle:               "Stop action keeps apps by default",
+			action:                  "stop",
+			deleteIdleSageMakerApps: false,
+		},
+		{
+			testTitle:               "Test action keeps apps",
+			action:                  "test",
+			deleteIdleSageMakerApps: true,
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.testTitle, func(t *testing.T) {
+			client := &mockISageMakerAPI{Apps: apps, LastUserActivity: lastUserActivity, AppTags: appTags}
+			actualCount, err := stopStartTestSageMakerInMemberAccount(client, &SchedulingRun{Action: subtest.action, Now: testSchedulingTime, Schedules: testScheduleCatalogue, DeleteIdleSageMakerApps: subtest.deleteIdleSageMakerApps})
+			assert.NoError(t, err)
+			assert.Equal(t, subtest.expectedDeleted, client.DeletedApps)
+			assert.Equal(t, len(subtest.expectedDeleted), actualCount.SageMakerAppsDeleted)
+		})
+	}
+}
@@ -0,0 +1,431 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	// Embed the time zone database so that schedules can be evaluated whatever the Lambda runtime provides
+	_ "time/tzdata"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Resources whose instance-scheduling tag names a schedule (or carries an inline pair of cron expressions)
+// are not touched by the global 'stop' and 'start' actions. Instead, the 'reconcile' action evaluates their
+// schedule against the current time and starts or stops them accordingly.
+
+const (
+	scheduleStateRunning string = "running"
+	scheduleStateStopped string = "stopped"
+)
+
+// How far back to look for the last start or stop of a schedule before giving up.
+const scheduleLookbackDays int = 366
+
+// Cron expressions are evaluated in the local time of the schedule's time zone, so that a schedule keeps
+// following office hours when the clocks change rather than shifting by an hour like the UTC based EventBridge rules.
+const defaultScheduleTimeZone string = "Europe/London"
+
+type Schedule struct {
+	Name     string
+	Start    *CronExpression
+	Stop     *CronExpression
+	Location *time.Location
+}
+
+// ScheduleCatalogue holds the named schedules which can be referenced from the instance-scheduling tag,
+// e.g. instance-scheduling=office-hours
+type ScheduleCatalogue map[string]*Schedule
+
+type ScheduleDefinition struct {
+	Description string `yaml:"description"`
+	Start       string `yaml:"start"`
+	Stop        string `yaml:"stop"`
+	TimeZone    string `yaml:"time_zone"`
+}
+
+// ScheduleDocument is the format of the schedule catalogue. JSON documents are accepted as well as YAML.
+type ScheduleDocument struct {
+	Schedules map[string]ScheduleDefinition `yaml:"schedules"`
+}
+
+// InvalidScheduleCatalogueError lists every invalid definition found in a schedule catalogue
+type InvalidScheduleCatalogueError struct {
+	InvalidSchedules []string
+}
+
+func (err *InvalidScheduleCatalogueError) Error() string {
+	return fmt.Sprintf("ERROR: Invalid schedule catalogue: %v", strings.Join(err.InvalidSchedules, "; "))
+}
+
+//go:embed schedules.yaml
+var defaultScheduleDocument string
+
+// loadScheduleDocument returns the schedule catalogue from SSM Parameter Store or a local file when one is
+// configured, otherwise the default catalogue bundled with the lambda
+func loadScheduleDocument(ctx context.Context, client ISSMGetParameter) (string, error) {
+	if parameterName := getEnv("INSTANCE_SCHEDULING_SCHEDULES_PARAMETER", ""); parameterName != "" {
+		log.Printf("INFO: Loading schedules from SSM parameter %v\n", parameterName)
+		return getParameter(ctx, client, parameterName)
+	}
+	if fileName := getEnv("INSTANCE_SCHEDULING_SCHEDULES_FILE", ""); fileName != "" {
+		log.Printf("INFO: Loading schedules from file %v\n", fileName)
+		document, err := os.ReadFile(fileName)
+		if err != nil {
+			return "", fmt.Errorf("failed to read schedules file: %w", err)
+		}
+		return string(document), nil
+	}
+	return defaultScheduleDocument, nil
+}
+
+// parseScheduleCatalogue parses and validates every definition in the document. An InvalidScheduleCatalogueError
+// is returned when any definition is invalid, so that a broken catalogue is never partially applied.
+func parseScheduleCatalogue(document string) (ScheduleCatalogue, error) {
+	var scheduleDocument ScheduleDocument
+	decoder := yaml.NewDecoder(strings.NewReader(document))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&scheduleDocument); err != nil && err != io.EOF {
+		return nil, &InvalidScheduleCatalogueError{InvalidSchedules: []string{fmt.Sprintf("document could not be parsed: %v", err)}}
+	}
+
+	catalogue := ScheduleCatalogue{}
+	invalidSchedules := []string{}
+	for name, definition := range scheduleDocument.Schedules {
+		schedule, err := parseScheduleDefinition(name, definition)
+		if err != nil {
+			invalidSchedules = append(invalidSchedules, fmt.Sprintf("%v: %v", name, err))
+			continue
+		}
+		catalogue[name] = schedule
+	}
+
+	if len(invalidSchedules) > 0 {
+		sort.Strings(invalidSchedules)
+		return nil, &InvalidScheduleCatalogueError{InvalidSchedules: invalidSchedules}
+	}
+	return catalogue, nil
+}
+
+func parseScheduleDefinition(name string, definition ScheduleDefinition) (*Schedule, error) {
+	switch {
+	case name == "" || name == "default" || strings.HasPrefix(name, "skip-") || strings.HasPrefix(name, "cron("):
+		return nil, errors.New("name is reserved for other instance-scheduling tag values")
+	case definition.Start == "" && definition.Stop == "":
+		return nil, errors.New("at least one of start or stop must be set")
+	}
+
+	timeZone := definition.TimeZone
+	if timeZone == "" {
+		timeZone = defaultScheduleTimeZone
+	}
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone '%v': %w", timeZone, err)
+	}
+
+	schedule := &Schedule{Name: name, Location: location}
+	if definition.Start != "" {
+		if schedule.Start, err = parseCronExpression(definition.Start); err != nil {
+			return nil, fmt.Errorf("invalid start expression: %w", err)
+		}
+	}
+	if definition.Stop != "" {
+		if schedule.Stop, err = parseCronExpression(definition.Stop); err != nil {
+			return nil, fmt.Errorf("invalid stop expression: %w", err)
+		}
+	}
+	return schedule, nil
+}
+
+func mustLoadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return location
+}
+
+// getSchedule returns the schedule referenced by the value of an instance-scheduling tag, or nil when the
+// value neither names a schedule in the catalogue nor contains an inline schedule such as
+// 'cron(0 7 ? * MON-FRI)/cron(0 19 ? * MON-FRI)'.
+// Inline schedules are evaluated in Europe/London unless followed by another IANA time zone, e.g. '...@Europe/Paris'
+func (catalogue ScheduleCatalogue) getSchedule(run *SchedulingRun, instanceSchedulingTag string) *Schedule {
+	if schedule, ok := catalogue[instanceSchedulingTag]; ok {
+		return schedule
+	}
+	if !strings.HasPrefix(instanceSchedulingTag, "cron(") {
+		return nil
+	}
+	schedule, err := parseInlineSchedule(instanceSchedulingTag)
+	if err != nil {
+		run.Printf("WARN: Ignoring invalid schedule in instance-scheduling tag '%v': %v\n", instanceSchedulingTag, err)
+		return nil
+	}
+	return schedule
+}
+
+// parseInlineSchedule parses a schedule in the format 'cron(<start expression>)/cron(<stop expression>)[@<time zone>]'
+func parseInlineSchedule(value string) (*Schedule, error) {
+	expressions, timeZone := value, defaultScheduleTimeZone
+	if i := strings.LastIndex(value, ")@"); i >= 0 {
+		expressions, timeZone = value[:i+1], value[i+2:]
+	}
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone '%v': %w", timeZone, err)
+	}
+
+	parts := strings.Split(expressions, ")/cron(")
+	if len(parts) != 2 {
+		return nil, errors.New("expected a start and a stop expression in the format 'cron(...)/cron(...)'")
+	}
+	start, err := parseCronExpression(parts[0] + ")")
+	if err != nil {
+		return nil, fmt.Errorf("invalid start expression: %w", err)
+	}
+	stop, err := parseCronExpression("cron(" + parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid stop expression: %w", err)
+	}
+	return &Schedule{Name: value, Start: start, Stop: stop, Location: location}, nil
+}
+
+// desiredState returns scheduleStateRunning or scheduleStateStopped depending on whether the schedule last started
+// or stopped before now. An empty string is returned when neither happened within the lookback period.
+func (schedule *Schedule) desiredState(now time.Time) string {
+	var lastStart, lastStop time.Time
+	if schedule.Start != nil {
+		lastStart, _ = schedule.Start.previous(now, schedule.Location)
+	}
+	if schedule.Stop != nil {
+		lastStop, _ = schedule.Stop.previous(now, schedule.Location)
+	}
+
+	switch {
+	case lastStart.IsZero() && lastStop.IsZero():
+		return ""
+	case lastStart.After(lastStop):
+		return scheduleStateRunning
+	default:
+		return scheduleStateStopped
+	}
+}
+
+// CronExpression is an EventBridge style cron expression: cron(minutes hours day-of-month month day-of-week [year])
+type CronExpression struct {
+	expression   string
+	minutes      []bool
+	hours        []bool
+	daysOfMonth  []bool
+	months       []bool
+	daysOfWeek   []bool
+	years        []bool
+	anyDay       bool
+	useDayOfWeek bool
+}
+
+type cronFieldSpec struct {
+	name  string
+	min   int
+	max   int
+	names []string
+}
+
+var (
+	cronMinutes     = cronFieldSpec{name: "minutes", min: 0, max: 59}
+	cronHours       = cronFieldSpec{name: "hours", min: 0, max: 23}
+	cronDaysOfMonth = cronFieldSpec{name: "day-of-month", min: 1, max: 31}
+	cronMonths      = cronFieldSpec{name: "month", min: 1, max: 12, names: []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}}
+	cronDaysOfWeek  = cronFieldSpec{name: "day-of-week", min: 1, max: 7, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}}
+	cronYears       = cronFieldSpec{name: "year", min: 1970, max: 2199}
+)
+
+func parseCronExpression(expression string) (*CronExpression, error) {
+	trimmed := strings.TrimSpace(expression)
+	if !strings.HasPrefix(trimmed, "cron(") || !strings.HasSuffix(trimmed, ")") {
+		return nil, fmt.Errorf("expression '%v' must be in the format 'cron(...)'", expression)
+	}
+	fields := strings.Fields(strings.TrimSuffix(strings.TrimPrefix(trimmed, "cron("), ")"))
+	if len(fields) != 5 && len(fields) != 6 {
+		return nil, fmt.Errorf("expression '%v' must have 5 or 6 fields, found %v", expression, len(fields))
+	}
+	if len(fields) == 5 {
+		fields = append(fields, "*")
+	}
+
+	cron := &CronExpression{expression: trimmed}
+	var err error
+	if cron.minutes, err = parseCronField(fields[0], cronMinutes); err != nil {
+		return nil, err
+	}
+	if cron.hours, err = parseCronField(fields[1], cronHours); err != nil {
+		return nil, err
+	}
+	if cron.daysOfMonth, err = parseCronField(fields[2], cronDaysOfMonth); err != nil {
+		return nil, err
+	}
+	if cron.months, err = parseCronField(fields[3], cronMonths); err != nil {
+		return nil, err
+	}
+	if cron.daysOfWeek, err = parseCronField(fields[4], cronDaysOfWeek); err != nil {
+		return nil, err
+	}
+	if cron.years, err = parseCronField(fields[5], cronYears); err != nil {
+		return nil, err
+	}
+
+	anyDayOfMonth := isCronWildcard(fields[2])
+	anyDayOfWeek := isCronWildcard(fields[4])
+	if !anyDayOfMonth && !anyDayOfWeek {
+		return nil, fmt.Errorf("expression '%v' cannot specify both day-of-month and day-of-week, one of them must be '?'", expression)
+	}
+	cron.anyDay = anyDayOfMonth && anyDayOfWeek
+	cron.useDayOfWeek = anyDayOfMonth && !anyDayOfWeek
+	return cron, nil
+}
+
+func isCronWildcard(field string) bool {
+	return field == "*" || field == "?"
+}
+
+// parseCronField parses a comma separated list of values, ranges (a-b) and steps (*/n, a/n, a-b/n)
+func parseCronField(field string, spec cronFieldSpec) ([]bool, error) {
+	values := make([]bool, spec.max+1)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rangePart = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step in %v field '%v'", spec.name, field)
+			}
+		}
+
+		first, last := spec.min, spec.max
+		if !isCronWildcard(rangePart) {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if first, err = parseCronValue(bounds[0], spec); err != nil {
+				return nil, fmt.Errorf("invalid %v field '%v': %w", spec.name, field, err)
+			}
+			last = first
+			if len(bounds) == 2 {
+				if last, err = parseCronValue(bounds[1], spec); err != nil {
+					return nil, fmt.Errorf("invalid %v field '%v': %w", spec.name, field, err)
+				}
+			} else if step > 1 {
+				last = spec.max
+			}
+			if first > last {
+				return nil, fmt.Errorf("invalid %v field '%v': range start is after range end", spec.name, field)
+			}
+		}
+
+		for value := first; value <= last; value += step {
+			values[value] = true
+		}
+	}
+	return values, nil
+}
+
+func parseCronValue(value string, spec cronFieldSpec) (int, error) {
+	for i, name := range spec.names {
+		if strings.EqualFold(value, name) {
+			return spec.min + i, nil
+		}
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("'%v' is not a valid value", value)
+	}
+	if number < spec.min || number > spec.max {
+		return 0, fmt.Errorf("'%v' is out of range %v-%v", value, spec.min, spec.max)
+	}
+	return number, nil
+}
+
+func (cron *CronExpression) String() string {
+	return cron.expression
+}
+
+func (cron *CronExpression) matchesDay(year int, month time.Month, day int, weekday time.Weekday) bool {
+	if year < cronYears.min || year > cronYears.max || !cron.years[year] || !cron.months[int(month)] {
+		return false
+	}
+	if cron.anyDay {
+		return true
+	}
+	if cron.useDayOfWeek {
+		// Day-of-week values run from 1 (SUN) to 7 (SAT)
+		return cron.daysOfWeek[int(weekday)+1]
+	}
+	return cron.daysOfMonth[day]
+}
+
+// previous returns the latest time not after t at which the expression fires in the given location
+func (cron *CronExpression) previous(t time.Time, location *time.Location) (time.Time, bool) {
+	year, month, day := t.In(location).Date()
+	for days := 0; days <= scheduleLookbackDays; days++ {
+		// Walk back through calendar days in UTC so that the date arithmetic is not affected by clock changes
+		date := time.Date(year, month, day-days, 0, 0, 0, 0, time.UTC)
+		if !cron.matchesDay(date.Year(), date.Month(), date.Day(), date.Weekday()) {
+			continue
+		}
+
+		// Clock changes mean the times within a day are not always in order, so take the latest one
+		var latest time.Time
+		for hour := cronHours.min; hour <= cronHours.max; hour++ {
+			if !cron.hours[hour] {
+				continue
+			}
+			for minute := cronMinutes.min; minute <= cronMinutes.max; minute++ {
+				if !cron.minutes[minute] {
+					continue
+				}
+				fire := localTime(date.Year(), date.Month(), date.Day(), hour, minute, location)
+				if !fire.After(t) && fire.After(latest) {
+					latest = fire
+				}
+			}
+		}
+		if !latest.IsZero() {
+			return latest, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// localTime converts a wall clock time in location into an instant. A time which does not exist because the clocks
+// went forward is moved forward by the length of the gap, and a time which happens twice because the clocks went back
+// resolves to its first occurrence, so that every expression fires exactly once per matching day.
+func localTime(year int, month time.Month, day int, hour int, minute int, location *time.Location) time.Time {
+	wall := time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	_, offsetBefore := wall.Add(-24 * time.Hour).In(location).Zone()
+	_, offsetAfter := wall.Add(24 * time.Hour).In(location).Zone()
+
+	var earliest time.Time
+	for _, offset := range []int{offsetBefore, offsetAfter} {
+		candidate := wall.Add(-time.Duration(offset) * time.Second)
+		local := candidate.In(location)
+		if local.Day() == day && local.Hour() == hour && local.Minute() == minute {
+			if earliest.IsZero() || candidate.Before(earliest) {
+				earliest = candidate
+			}
+		}
+	}
+	if earliest.IsZero() {
+		return wall.Add(-time.Duration(offsetBefore) * time.Second)
+	}
+	return earliest
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The default catalogue bundled with the lambda
+var testScheduleCatalogue = mustParseScheduleCatalogue(defaultScheduleDocument)
+
+func mustParseScheduleCatalogue(document string) ScheduleCatalogue {
+	catalogue, err := parseScheduleCatalogue(document)
+	if err != nil {
+		panic(err)
+	}
+	return catalogue
+}
+
+func mustParseCronExpression(expression string) *CronExpression {
+	cron, err := parseCronExpression(expression)
+	if err != nil {
+		panic(err)
+	}
+	return cron
+}
+
+func TestParseCronExpression(t *testing.T) {
+	tests := []struct {
+		title       string
+		expression  string
+		expectError bool
+	}{
+		{
+			title:       "accepts 5 fields with day-of-week names",
+			expression:  "cron(0 7 ? * MON-FRI)",
+			expectError: false,
+		},
+		{
+			title:       "accepts 6 fields including year",
+			expression:  "cron(30 19 ? * MON-FRI 2026)",
+			expectError: false,
+		},
+		{
+			title:       "accepts lists, ranges and steps",
+			expression:  "cron(0/15 7-19/2 1,15 JAN-MAR ?)",
+			expectError: false,
+		},
+		{
+			title:       "rejects an expression without the cron() wrapper",
+			expression:  "0 7 ? * MON-FRI",
+			expectError: true,
+		},
+		{
+			title:       "rejects an expression with too few fields",
+			expression:  "cron(0 7 ? *)",
+			expectError: true,
+		},
+		{
+			title:       "rejects out of range values",
+			expression:  "cron(0 25 ? * MON-FRI)",
+			expectError: true,
+		},
+		{
+			title:       "rejects unknown names",
+			expression:  "cron(0 7 ? * MON-FUN)",
+			expectError: true,
+		},
+		{
+			title:       "rejects both day-of-month and day-of-week",
+			expression:  "cron(0 7 1 * MON)",
+			expectError: true,
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.title, func(t *testing.T) {
+			_, err := parseCronExpression(subtest.expression)
+			if subtest.expectError {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestCronExpressionPrevious(t *testing.T) {
+	london := mustLoadLocation("Europe/London")
+	tests := []struct {
+		title      string
+		expression string
+		location   *time.Location
+		now        time.Time
+		want       time.Time
+	}{
+		{
+			title:      "returns the same day when the expression already fired today",
+			expression: "cron(0 7 ? * MON-FRI)",
+			location:   time.UTC,
+			now:        time.Date(2026, time.October, 14, 9, 30, 0, 0, time.UTC), // Wednesday
+			want:       time.Date(2026, time.October, 14, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			title:      "returns the previous working day before the expression fires today",
+			expression: "cron(0 7 ? * MON-FRI)",
+			location:   time.UTC,
+			now:        time.Date(2026, time.October, 14, 6, 59, 0, 0, time.UTC),
+			want:       time.Date(2026, time.October, 13, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			title:      "skips the weekend",
+			expression: "cron(0 7 ? * MON-FRI)",
+			location:   time.UTC,
+			now:        time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC), // Sunday
+			want:       time.Date(2026, time.October, 16, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			title:      "includes the exact minute the expression fires",
+			expression: "cron(0 19 ? * MON-FRI)",
+			location:   time.UTC,
+			now:        time.Date(2026, time.October, 14, 19, 0, 30, 0, time.UTC),
+			want:       time.Date(2026, time.October, 14, 19, 0, 0, 0, time.UTC),
+		},
+		{
+			title:      "supports day-of-month",
+			expression: "cron(15 6 1 * ?)",
+			location:   time.UTC,
+			now:        time.Date(2026, time.October, 14, 9, 30, 0, 0, time.UTC),
+			want:       time.Date(2026, time.October, 1, 6, 15, 0, 0, time.UTC),
+		},
+		{
+			title:      "fires at 07:00 BST during British Summer Time",
+			expression: "cron(0 7 ? * MON-FRI)",
+			location:   london,
+			now:        time.Date(2026, time.October, 14, 6, 30, 0, 0, time.UTC),
+			want:       time.Date(2026, time.October, 14, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			title:      "fires at 07:00 GMT during winter",
+			expression: "cron(0 7 ? * MON-FRI)",
+			location:   london,
+			now:        time.Date(2026, time.November, 4, 7, 30, 0, 0, time.UTC),
+			want:       time.Date(2026, time.November, 4, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			title:      "fires after the gap when the time is skipped as the clocks go forward",
+			expression: "cron(30 1 ? * *)",
+			location:   london,
+			now:        time.Date(2026, time.March, 29, 1, 45, 0, 0, time.UTC),
+			want:       time.Date(2026, time.March, 29, 1, 30, 0, 0, time.UTC), // 02:30 BST
+		},
+		{
+			title:      "does not fire before the gap when the time is skipped as the clocks go forward",
+			expression: "cron(30 1 ? * *)",
+			location:   london,
+			now:        time.Date(2026, time.March, 29, 1, 20, 0, 0, time.UTC), // 02:20 BST
+			want:       time.Date(2026, time.March, 28, 1, 30, 0, 0, time.UTC),
+		},
+		{
+			title:      "fires only on the first occurrence of a time repeated as the clocks go back",
+			expression: "cron(30 1 ? * *)",
+			location:   london,
+			now:        time.Date(2026, time.October, 25, 1, 45, 0, 0, time.UTC), // 01:45 GMT, second time round
+			want:       time.Date(2026, time.October, 25, 0, 30, 0, 0, time.UTC), // 01:30 BST
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.title, func(t *testing.T) {
+			cron := mustParseCronExpression(subtest.expression)
+			got, ok := cron.previous(subtest.now, subtest.location)
+			assert.True(t, ok)
+			assert.Equal(t, subtest.want, got)
+		})
+	}
+}
+
+func TestGetSchedule(t *testing.T) {
+	assert.Nil(t, testScheduleCatalogue.getSchedule(&SchedulingRun{}, ""))
+	assert.Nil(t, testScheduleCatalogue.getSchedule(&SchedulingRun{}, "default"))
+	assert.Nil(t, testScheduleCatalogue.getSchedule(&SchedulingRun{}, "skip-auto-stop"))
+	assert.Nil(t, testScheduleCatalogue.getSchedule(&SchedulingRun{}, "cron(0 7 ? * MON-FRI)"))
+	assert.Nil(t, testScheduleCatalogue.getSchedule(&SchedulingRun{}, "cron(0 7 ? * MON-FRI)/cron(0 99 ? * MON-FRI)"))
+
+	schedule := testScheduleCatalogue.getSchedule(&SchedulingRun{}, "office-hours")
+	assert.NotNil(t, schedule)
+	assert.Equal(t, "office-hours", schedule.Name)
+
+	schedule = testScheduleCatalogue.getSchedule(&SchedulingRun{}, "cron(0 7 ? * MON-FRI)/cron(0 19 ? * MON-FRI)")
+	assert.NotNil(t, schedule)
+	assert.Equal(t, "cron(0 7 ? * MON-FRI)", schedule.Start.String())
+	assert.Equal(t, "cron(0 19 ? * MON-FRI)", schedule.Stop.String())
+	assert.Equal(t, "Europe/London", schedule.Location.String())
+
+	schedule = testScheduleCatalogue.getSchedule(&SchedulingRun{}, "cron(0 9 ? * MON-FRI)/cron(0 17 ? * MON-FRI)@America/New_York")
+	assert.NotNil(t, schedule)
+	assert.Equal(t, "cron(0 17 ? * MON-FRI)", schedule.Stop.String())
+	assert.Equal(t, "America/New_York", schedule.Location.String())
+
+	assert.Nil(t, testScheduleCatalogue.getSchedule(&SchedulingRun{}, "cron(0 9 ? * MON-FRI)/cron(0 17 ? * MON-FRI)@Europe/Nowhere"))
+}
+
+func TestScheduleDesiredState(t *testing.T) {
+	tests := []struct {
+		title    string
+		schedule string
+		now      time.Time
+		want     string
+	}{
+		{
+			title:    "running during office hours",
+			schedule: "office-hours",
+			now:      time.Date(2026, time.October, 14, 12, 0, 0, 0, time.UTC),
+			want:     scheduleStateRunning,
+		},
+		{
+			title:    "stopped in the evening",
+			schedule: "office-hours",
+			now:      time.Date(2026, time.October, 14, 20, 0, 0, 0, time.UTC),
+			want:     scheduleStateStopped,
+		},
+		{
+			title:    "running at 07:30 BST in summer",
+			schedule: "office-hours",
+			now:      time.Date(2026, time.October, 14, 6, 30, 0, 0, time.UTC),
+			want:     scheduleStateRunning,
+		},
+		{
+			title:    "stopped at 06:30 GMT in winter",
+			schedule: "office-hours",
+			now:      time.Date(2026, time.November, 4, 6, 30, 0, 0, time.UTC),
+			want:     scheduleStateStopped,
+		},
+		{
+			title:    "stopped at the weekend",
+			schedule: "office-hours",
+			now:      time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC),
+			want:     scheduleStateStopped,
+		},
+		{
+			title:    "stopped before office hours in another time zone",
+			schedule: "cron(0 9 ? * MON-FRI)/cron(0 17 ? * MON-FRI)@America/New_York",
+			now:      time.Date(2026, time.October, 14, 12, 0, 0, 0, time.UTC),
+			want:     scheduleStateStopped,
+		},
+		{
+			title:    "running during office hours in another time zone",
+			schedule: "cron(0 9 ? * MON-FRI)/cron(0 17 ? * MON-FRI)@America/New_York",
+			now:      time.Date(2026, time.October, 14, 14, 0, 0, 0, time.UTC),
+			want:     scheduleStateRunning,
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.title, func(t *testing.T) {
+			assert.Equal(t, subtest.want, testScheduleCatalogue.getSchedule(&SchedulingRun{}, subtest.schedule).desiredState(subtest.now))
+		})
+	}
+}
+
+func TestParseScheduleCatalogue(t *testing.T) {
+	t.Run("parses the default catalogue", func(t *testing.T) {
+		catalogue, err := parseScheduleCatalogue(defaultScheduleDocument)
+		assert.Nil(t, err)
+		assert.Len(t, catalogue, 4)
+		for _, name := range []string{"office-hours", "extended-hours", "weekdays-only", "nights-only"} {
+			assert.Contains(t, catalogue, name)
+		}
+	})
+
+	t.Run("parses a JSON document", func(t *testing.T) {
+		catalogue, err := parseScheduleCatalogue(`{"schedules": {"late-shift": {"start": "cron(0 12 ? * MON-FRI)", "stop": "cron(0 23 ? * MON-FRI)", "time_zone": "Europe/Dublin"}}}`)
+		assert.Nil(t, err)
+		assert.Equal(t, "Europe/Dublin", catalogue["late-shift"].Location.String())
+		assert.Equal(t, "cron(0 12 ? * MON-FRI)", catalogue["late-shift"].Start.String())
+	})
+
+	t.Run("defaults the time zone and allows a schedule which only stops", func(t *testing.T) {
+		catalogue, err := parseScheduleCatalogue("schedules:\n  stop-at-night:\n    stop: cron(0 22 ? * *)\n")
+		assert.Nil(t, err)
+		assert.Equal(t, "Europe/London", catalogue["stop-at-night"].Location.String())
+		assert.Nil(t, catalogue["stop-at-night"].Start)
+	})
+
+	t.Run("lists every invalid definition", func(t *testing.T) {
+		_, err := parseScheduleCatalogue(`
+schedules:
+  valid:
+    start: cron(0 7 ? * MON-FRI)
+    stop: cron(0 19 ? * MON-FRI)
+  bad-start:
+    start: cron(0 77 ? * MON-FRI)
+    stop: cron(0 19 ? * MON-FRI)
+  bad-time-zone:
+    start: cron(0 7 ? * MON-FRI)
+    time_zone: Europe/Nowhere
+  empty:
+    description: Neither starts nor stops
+  skip-scheduling:
+    stop: cron(0 19 ? * MON-FRI)
+`)
+		invalidScheduleCatalogueError, ok := err.(*InvalidScheduleCatalogueError)
+		assert.True(t, ok)
+		assert.Len(t, invalidScheduleCatalogueError.InvalidSchedules, 4)
+		assert.Contains(t, invalidScheduleCatalogueError.InvalidSchedules[0], "bad-start:")
+		assert.Contains(t, invalidScheduleCatalogueError.InvalidSchedules[1], "bad-time-zone:")
+		assert.Contains(t, invalidScheduleCatalogueError.InvalidSchedules[2], "empty:")
+		assert.Contains(t, invalidScheduleCatalogueError.InvalidSchedules[3], "skip-scheduling:")
+	})
+
+	t.Run("rejects unknown fields", func(t *testing.T) {
+		_, err := parseScheduleCatalogue("schedules:\n  office-hours:\n    begin: cron(0 7 ? * MON-FRI)\n")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestLoadScheduleDocument(t *testing.T) {
+	ssmClient := mockGetParameter(func(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+		assert.Equal(t, "instance-scheduler-schedules", *params.Name)
+		return &ssm.GetParameterOutput{
+			Parameter: &types.Parameter{
+				Value: aws.String("schedules: {}"),
+			},
+		}, nil
+	})
+
+	t.Run("returns the default catalogue when nothing is configured", func(t *testing.T) {
+		document, err := loadScheduleDocument(context.Background(), ssmClient)
+		assert.Nil(t, err)
+		assert.Equal(t, defaultScheduleDocument, document)
+	})
+
+	t.Run("returns the catalogue from SSM Parameter Store", func(t *testing.T) {
+		t.Setenv("INSTANCE_SCHEDULING_SCHEDULES_PARAMETER", "instance-scheduler-schedules")
+		document, err := loadScheduleDocument(context.Background(), ssmClient)
+		assert.Nil(t, err)
+		assert.Equal(t, "schedules: {}", document)
+	})
+
+	t.Run("returns the catalogue from a local file", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "schedules.json")
+		os.WriteFile(fileName, []byte(`{"schedules": {}}`), 0600)
+		t.Setenv("INSTANCE_SCHEDULING_SCHEDULES_FILE", fileName)
+		document, err := loadScheduleDocument(context.Background(), ssmClient)
+		assert.Nil(t, err)
+		assert.Equal(t, `{"schedules": {}}`, document)
+	})
+
+	t.Run("returns an error when the local file cannot be read", func(t *testing.T) {
+		t.Setenv("INSTANCE_SCHEDULING_SCHEDULES_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+		_, err := loadScheduleDocument(context.Background(), ssmClient)
+		assert.NotNil(t, err)
+	})
+}
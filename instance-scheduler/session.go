@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// MemberAccountRole is the role assumed in every member account and how it is assumed
+type MemberAccountRole struct {
+	Name        string
+	Path        string
+	SessionName string
+	ExternalId  string
+	// SessionDuration of zero leaves the duration of the role session to AWS, which is 15 minutes
+	SessionDuration time.Duration
+}
+
+const (
+	defaultMemberAccountRoleName = "InstanceSchedulerAccess"
+	defaultRoleSessionNamePrefix = "instance-scheduler"
+	minRoleSessionDuration       = 15 * time.Minute
+	maxRoleSessionDuration       = 12 * time.Hour
+	maxRoleSessionNameLength     = 64
+)
+
+// memberAccountRoleAttempts and memberAccountRoleMaxBackoff configure the retryer of the STS client which assumes the
+// member account role, so that a throttled call or a network error is retried by the SDK alone
+const (
+	memberAccountRoleAttempts   = 3
+	memberAccountRoleMaxBackoff = 4 * time.Second
+)
+
+var invalidRoleSessionNameCharacters = regexp.MustCompile(`[^\w+=,.@-]`)
+
+// parseMemberAccountRole reads the member account role from the environment. The action is appended to the role
+// session name, so that CloudTrail shows which action of the scheduler made each call.
+func parseMemberAccountRole(action string) MemberAccountRole {
+	role := MemberAccountRole{
+		Name:       getEnv("INSTANCE_SCHEDULING_ROLE_NAME", defaultMemberAccountRoleName),
+		Path:       getEnv("INSTANCE_SCHEDULING_ROLE_PATH", "/"),
+		ExternalId: getEnv("INSTANCE_SCHEDULING_ROLE_EXTERNAL_ID", ""),
+	}
+	if !strings.HasPrefix(role.Path, "/") {
+		role.Path = "/" + role.Path
+	}
+	if !strings.HasSuffix(role.Path, "/") {
+		role.Path += "/"
+	}
+
+	sessionName := fmt.Sprintf("%v-%v", getEnv("INSTANCE_SCHEDULING_ROLE_SESSION_NAME", defaultRoleSessionNamePrefix), action)
+	sessionName = invalidRoleSessionNameCharacters.ReplaceAllString(sessionName, "-")
+	role.SessionName = sessionName[:min(len(sessionName), maxRoleSessionNameLength)]
+
+	if value := getEnv("INSTANCE_SCHEDULING_ROLE_SESSION_DURATION", ""); value != "" {
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration < minRoleSessionDuration || duration > maxRoleSessionDuration {
+			log.Printf("WARN: Ignored invalid role session duration '%v', which must be between %v and %v\n", value, minRoleSessionDuration, maxRoleSessionDuration)
+		} else {
+			role.SessionDuration = duration
+		}
+	}
+	return role
+}
+
+// arn returns the ARN of the role in the member account
+func (role MemberAccountRole) arn(accountId string) string {
+	return fmt.Sprintf("arn:aws:iam::%v:role%v%v", accountId, role.Path, role.Name)
+}
+
+// assumeRoleOptions sets how the stscreds provider assumes the role
+func (role MemberAccountRole) assumeRoleOptions(options *stscreds.AssumeRoleOptions) {
+	options.RoleSessionName = role.SessionName
+	if role.ExternalId != "" {
+		options.ExternalID = aws.String(role.ExternalId)
+	}
+	if role.SessionDuration > 0 {
+		options.Duration = role.SessionDuration
+	}
+}
+
+// MemberAccountSession holds the role assumed in a member account. The clients of every service are created from
+// its config, so they share the cached credentials of a single AssumeRole call.
+type MemberAccountSession struct {
+	AccountName string
+	AccountId   string
+	Config      aws.Config
+}
+
+// createMemberAccountSession assumes the run's member account role in the account, returning nil without an
+// error when the account does not have the role and is therefore not a member account
+func createMemberAccountSession(run *SchedulingRun, cfg aws.Config, accountName string, accountId string) (*MemberAccountSession, error) {
+	client := sts.NewFromConfig(cfg, func(options *sts.Options) {
+		options.Retryer = retry.NewStandard(func(standardOptions *retry.StandardOptions) {
+			standardOptions.MaxAttempts = memberAccountRoleAttempts
+			standardOptions.MaxBackoff = memberAccountRoleMaxBackoff
+		})
+	})
+	provider := stscreds.NewAssumeRoleProvider(client, run.MemberAccountRole.arn(accountId), run.MemberAccountRole.assumeRoleOptions)
+	return openMemberAccountSession(run, cfg, accountName, accountId, provider)
+}
+
+// openMemberAccountSession retrieves the credentials of the provider once to establish whether the account is a
+// member account, and caches them for the clients created from the session
+func openMemberAccountSession(run *SchedulingRun, cfg aws.Config, accountName string, accountId string, provider aws.CredentialsProvider) (*MemberAccountSession, error) {
+	cfg.Credentials = aws.NewCredentialsCache(provider)
+	if _, err := cfg.Credentials.Retrieve(run.ctx()); err != nil {
+		if classifyError(err) == errorClassRoleMissing {
+			run.Printf("WARN: account %v is ignored because it does not have the role %v, therefore is not a member account\n", accountName, run.MemberAccountRole.Name)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to assume the %v role in account %v: %w", run.MemberAccountRole.Name, accountName, err)
+	}
+
+	return &MemberAccountSession{
+		AccountName: accountName,
+		AccountId:   accountId,
+		Config:      cfg,
+	}, nil
+}
+
+// inRegion returns a copy of the session whose clients call AWS in the region, sharing its cached credentials
+func (session *MemberAccountSession) inRegion(region string) *MemberAccountSession {
+	regionalSession := *session
+	regionalSession.Config = session.Config.Copy()
+	regionalSession.Config.Region = region
+	return &regionalSession
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockAssumeRoleProvider struct {
+	Err   error
+	Calls int
+}
+
+func (m *mockAssumeRoleProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	m.Calls++
+	if m.Err != nil {
+		return aws.Credentials{}, m.Err
+	}
+	return aws.Credentials{
+		AccessKeyID:     "test-access-key-id",
+		SecretAccessKey: "test-secret-access-key",
+		CanExpire:       true,
+		Expires:         time.Now().Add(time.Hour),
+	}, nil
+}
+
+func TestOpenMemberAccountSession(t *testing.T) {
+	t.Run("assumes the role once for the clients of every service", func(t *testing.T) {
+		provider := &mockAssumeRoleProvider{}
+
+		session, err := openMemberAccountSession(&SchedulingRun{MemberAccountRole: MemberAccountRole{Name: "InstanceSchedulerAccess"}}, aws.Config{Region: "eu-west-2"}, "test-account-development", "1", provider)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "test-account-development", session.AccountName)
+		assert.Equal(t, "1", session.AccountId)
+		assert.NotNil(t, getEc2ClientForMemberAccount(session))
+		assert.NotNil(t, getRDSClientForMemberAccount(session))
+		for range 3 {
+			credentials, err := session.Config.Credentials.Retrieve(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "test-access-key-id", credentials.AccessKeyID)
+		}
+		assert.Equal(t, 1, provider.Calls)
+	})
+
+	t.Run("returns no session for an account without the role", func(t *testing.T) {
+		provider := &mockAssumeRoleProvider{Err: &smithy.GenericAPIError{Code: "AccessDenied", Message: "User: arn:aws:sts::123456789012:assumed-role/instance-scheduler/session is not authorized to perform: sts:AssumeRole on resource: arn:aws:iam::1:role/InstanceSchedulerAccess"}}
+
+		session, err := openMemberAccountSession(&SchedulingRun{MemberAccountRole: MemberAccountRole{Name: "InstanceSchedulerAccess"}}, aws.Config{}, "test-account-development", "1", provider)
+
+		assert.NoError(t, err)
+		assert.Nil(t, session)
+		assert.Equal(t, 1, provider.Calls)
+	})
+
+	t.Run("returns an error when the role cannot be assumed", func(t *testing.T) {
+		provider := &mockAssumeRoleProvider{Err: &smithy.GenericAPIError{Code: "ExpiredToken", Message: "The security token included in the request is expired"}}
+
+		session, err := openMemberAccountSession(&SchedulingRun{MemberAccountRole: MemberAccountRole{Name: "InstanceSchedulerAccess"}}, aws.Config{}, "test-account-development", "1", provider)
+
+		assert.ErrorContains(t, err, "failed to assume the InstanceSchedulerAccess role in account test-account-development")
+		assert.Equal(t, errorClassExpiredToken, classifyError(err))
+		assert.Nil(t, session)
+		assert.Equal(t, 1, provider.Calls)
+	})
+}
+
+func TestParseMemberAccountRole(t *testing.T) {
+	tests := []struct {
+		title string
+		env   map[string]string
+		want  MemberAccountRole
+	}{
+		{
+			title: "returns the InstanceSchedulerAccess role by default",
+			env:   map[string]string{},
+			want:  MemberAccountRole{Name: "InstanceSchedulerAccess", Path: "/", SessionName: "instance-scheduler-stop"},
+		},
+		{
+			title: "returns the configured role",
+			env: map[string]string{
+				"INSTANCE_SCHEDULING_ROLE_NAME":             "Scheduler",
+				"INSTANCE_SCHEDULING_ROLE_PATH":             "/automation/",
+				"INSTANCE_SCHEDULING_ROLE_SESSION_NAME":     "team-scheduler",
+				"INSTANCE_SCHEDULING_ROLE_EXTERNAL_ID":      "test-external-id",
+				"INSTANCE_SCHEDULING_ROLE_SESSION_DURATION": "1h",
+			},
+			want: MemberAccountRole{Name: "Scheduler", Path: "/automation/", SessionName: "team-scheduler-stop", ExternalId: "test-external-id", SessionDuration: time.Hour},
+		},
+		{
+			title: "adds the slashes missing from the path",
+			env:   map[string]string{"INSTANCE_SCHEDULING_ROLE_PATH": "automation"},
+			want:  MemberAccountRole{Name: "InstanceSchedulerAccess", Path: "/automation/", SessionName: "instance-scheduler-stop"},
+		},
+		{
+			title: "replaces characters not allowed in a session name and truncates it to 64 characters",
+			env:   map[string]string{"INSTANCE_SCHEDULING_ROLE_SESSION_NAME": "instance scheduler for the modernisation platform non-production accounts"},
+			want:  MemberAccountRole{Name: "InstanceSchedulerAccess", Path: "/", SessionName: "instance-scheduler-for-the-modernisation-platform-non-production"},
+		},
+		{
+			title: "ignores a session duration that AWS does not allow",
+			env:   map[string]string{"INSTANCE_SCHEDULING_ROLE_SESSION_DURATION": "5m"},
+			want:  MemberAccountRole{Name: "InstanceSchedulerAccess", Path: "/", SessionName: "instance-scheduler-stop"},
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.title, func(t *testing.T) {
+			for key, value := range subtest.env {
+				t.Setenv(key, value)
+			}
+			assert.Equal(t, subtest.want, parseMemberAccountRole("stop"))
+		})
+	}
+}
+
+func TestMemberAccountRoleArn(t *testing.T) {
+	assert.Equal(t, "arn:aws:iam::123456789012:role/InstanceSchedulerAccess", MemberAccountRole{Name: "InstanceSchedulerAccess", Path: "/"}.arn("123456789012"))
+	assert.Equal(t, "arn:aws:iam::123456789012:role/automation/Scheduler", MemberAccountRole{Name: "Scheduler", Path: "/automation/"}.arn("123456789012"))
+}
+
+func TestMemberAccountRoleAssumeRoleOptions(t *testing.T) {
+	t.Run("sets the session name and leaves the external ID and duration unset by default", func(t *testing.T) {
+		options := stscreds.AssumeRoleOptions{}
+
+		MemberAccountRole{SessionName: "instance-scheduler-stop"}.assumeRoleOptions(&options)
+
+		assert.Equal(t, "instance-scheduler-stop", options.RoleSessionName)
+		assert.Nil(t, options.ExternalID)
+		assert.Zero(t, options.Duration)
+	})
+
+	t.Run("sets the external ID and duration when configured", func(t *testing.T) {
+		options := stscreds.AssumeRoleOptions{}
+
+		MemberAccountRole{SessionName: "instance-scheduler-start", ExternalId: "test-external-id", SessionDuration: time.Hour}.assumeRoleOptions(&options)
+
+		assert.Equal(t, "instance-scheduler-start", options.RoleSessionName)
+		assert.Equal(t, "test-external-id", *options.ExternalID)
+		assert.Equal(t, time.Hour, options.Duration)
+	})
+}
+
+func TestMemberAccountSessionInRegion(t *testing.T) {
+	provider := &mockAssumeRoleProvider{}
+	session, err := openMemberAccountSession(&SchedulingRun{}, aws.Config{Region: "eu-west-2"}, "test-account-development", "1", provider)
+	assert.NoError(t, err)
+
+	regionalSession := session.inRegion("eu-west-1")
+
+	assert.Equal(t, "eu-west-1", regionalSession.Config.Region)
+	assert.Equal(t, "eu-west-2", session.Config.Region)
+	assert.Equal(t, "test-account-development", regionalSession.AccountName)
+	_, err = regionalSession.Config.Credentials.Retrieve(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, provider.Calls)
+}
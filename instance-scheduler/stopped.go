@@ -0,0 +1,53 @@
+package main
+
+import "time"
+
+// The stop action tags each resource it stops, so that the start action only starts resources stopped by the
+// scheduler and leaves alone ones that engineers stopped deliberately. The tags are removed once started.
+
+const (
+	stoppedByTagKey   string = "instance-scheduler:stopped-by"
+	stoppedByTagValue string = "scheduler"
+	stoppedAtTagKey   string = "instance-scheduler:stopped-at"
+)
+
+// isStoppedBySchedulerTag reports whether a tag records that the scheduler stopped the resource
+func isStoppedBySchedulerTag(key string, value string) bool {
+	return key == stoppedByTagKey && value == stoppedByTagValue
+}
+
+// parseStoppedAtTag parses the time recorded in an instance-scheduler:stopped-at tag, returning the zero time when it
+// is not a valid RFC 3339 timestamp
+func parseStoppedAtTag(value string) time.Time {
+	stoppedAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return stoppedAt
+}
+
+// stoppedBySchedulerTagKeys are the keys of the tags added by stoppedBySchedulerTags
+var stoppedBySchedulerTagKeys = []string{stoppedByTagKey, stoppedAtTagKey}
+
+// stoppedBySchedulerTags record that the scheduler stopped a resource at the time of the run
+func stoppedBySchedulerTags(run *SchedulingRun) []resourceTag {
+	return []resourceTag{
+		{key: stoppedByTagKey, value: stoppedByTagValue},
+		{key: stoppedAtTagKey, value: run.Now.UTC().Format(time.RFC3339)},
+	}
+}
+
+// tagResourceStoppedByScheduler records on a resource that the scheduler stopped it, so that the start action restarts it
+func tagResourceStoppedByScheduler(run *SchedulingRun, resource taggedResource) {
+	err := resource.addTags(run, stoppedBySchedulerTags(run))
+	if err != nil {
+		run.Printf("ERROR: Could not tag %v %v as stopped by the scheduler, so it will not be started: %v\n", resource.kind(), resource.id(), err)
+	}
+}
+
+func untagResourceStoppedByScheduler(run *SchedulingRun, resource taggedResource) {
+	err := resource.removeTags(run, stoppedBySchedulerTagKeys)
+	if err != nil {
+		run.Printf("ERROR: Could not remove stopped by the scheduler tags from %v %v: %v\n", resource.kind(), resource.id(), err)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import "time"
+
+// Each service tags its resources through its own API and tag type. A service wraps its resources in a
+// taggedResource, so that the tags which the scheduler reads and writes are handled the same way for all of them.
+
+// resourceTag is a tag written by the scheduler, which each service converts to its own tag type
+type resourceTag struct {
+	key   string
+	value string
+}
+
+// taggedResource adapts a resource of one service to the tag helpers shared by every service
+type taggedResource interface {
+	// kind names the type of the resource in log lines, e.g. "Redshift cluster"
+	kind() string
+	// id identifies the resource in log lines and in the lists of skipped resources
+	id() string
+	tags() map[string]string
+	addTags(run *SchedulingRun, tags []resourceTag) error
+	removeTags(run *SchedulingRun, keys []string) error
+}
+
+// parseSchedulingTags reads the instance-scheduling tag, the time in the instance-scheduling-override-until tag and
+// whether the scheduler stopped the resource
+func parseSchedulingTags(run *SchedulingRun, tags map[string]string) (string, time.Time, bool) {
+	var overrideUntil time.Time
+	if value, ok := tags[overrideUntilTagKey]; ok {
+		overrideUntil = parseOverrideUntilTag(run, value)
+	}
+	return tags["instance-scheduling"], overrideUntil, isStoppedBySchedulerTag(stoppedByTagKey, tags[stoppedByTagKey])
+}
+
+// parseResourceTags reads the scheduling tags of a resource. A resource whose instance-scheduling tag has the value
+// 'skip-scheduling' is reported as skipped and added to skippedResources.
+func parseResourceTags(run *SchedulingRun, resource taggedResource, skippedResources []string) (string, time.Time, bool, bool, []string) {
+	instanceSchedulingTag, overrideUntil, stoppedByScheduler := parseSchedulingTags(run, resource.tags())
+	isSkipSchedulingTag, skippedResources := isSkipScheduling(run, resource, instanceSchedulingTag, skippedResources)
+	return instanceSchedulingTag, overrideUntil, stoppedByScheduler, isSkipSchedulingTag, skippedResources
+}
+
+// isSkipScheduling reports whether an instance-scheduling tag opts the resource out of scheduling, adding the resource
+// to skippedResources when it does
+func isSkipScheduling(run *SchedulingRun, resource taggedResource, instanceSchedulingTag string, skippedResources []string) (bool, []string) {
+	if instanceSchedulingTag != "skip-scheduling" {
+		return false, skippedResources
+	}
+	run.Printf("INFO: Skip %v because instance-scheduling tag having value 'skip-scheduling'\n", resource.kind())
+	return true, append(skippedResources, resource.id())
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockTaggedResource struct {
+	tagMap      map[string]string
+	AddedTags   [][]resourceTag
+	RemovedTags [][]string
+}
+
+func (m *mockTaggedResource) kind() string {
+	return "test resource"
+}
+
+func (m *mockTaggedResource) id() string {
+	return "resource-1"
+}
+
+func (m *mockTaggedResource) tags() map[string]string {
+	return m.tagMap
+}
+
+func (m *mockTaggedResource) addTags(run *SchedulingRun, tags []resourceTag) error {
+	m.AddedTags = append(m.AddedTags, tags)
+	return nil
+}
+
+func (m *mockTaggedResource) removeTags(run *SchedulingRun, keys []string) error {
+	m.RemovedTags = append(m.RemovedTags, keys)
+	return nil
+}
+
+func TestParseResourceTags(t *testing.T) {
+	run := &SchedulingRun{}
+	resource := &mockTaggedResource{tagMap: map[string]string{
+		"instance-scheduling":                "office-hours",
+		"instance-scheduling-override-until": "2026-10-20T22:00Z",
+		"instance-scheduler:stopped-by":      "scheduler",
+	}}
+	instanceSchedulingTag, overrideUntil, stoppedByScheduler, isSkipSchedulingTag, skipped := parseResourceTags(run, resource, []string{})
+	assert.Equal(t, "office-hours", instanceSchedulingTag)
+	assert.Equal(t, time.Date(2026, time.October, 20, 22, 0, 0, 0, time.UTC), overrideUntil)
+	assert.True(t, stoppedByScheduler)
+	assert.False(t, isSkipSchedulingTag)
+	assert.Empty(t, skipped)
+
+	resource = &mockTaggedResource{tagMap: map[string]string{"instance-scheduling": "skip-scheduling"}}
+	_, overrideUntil, stoppedByScheduler, isSkipSchedulingTag, skipped = parseResourceTags(run, resource, []string{})
+	assert.True(t, overrideUntil.IsZero())
+	assert.False(t, stoppedByScheduler)
+	assert.True(t, isSkipSchedulingTag)
+	assert.Equal(t, []string{"resource-1"}, skipped)
+}
+
+func TestStoppedBySchedulerResourceTags(t *testing.T) {
+	run := &SchedulingRun{Now: testSchedulingTime}
+	resource := &mockTaggedResource{}
+
+	tagResourceStoppedByScheduler(run, resource)
+	assert.Equal(t, [][]resourceTag{{
+		{key: "instance-scheduler:stopped-by", value: "scheduler"},
+		{key: "instance-scheduler:stopped-at", value: "2026-10-14T12:00:00Z"},
+	}}, resource.AddedTags)
+
+	untagResourceStoppedByScheduler(run, resource)
+	assert.Equal(t, [][]string{{"instance-scheduler:stopped-by", "instance-scheduler:stopped-at"}}, resource.RemovedTags)
+}
+
+func TestRemoveExpiredOverride(t *testing.T) {
+	overrideUntil := testSchedulingTime.Add(-time.Hour)
+	tests := []struct {
+		testTitle              string
+		action                 string
+		overrideUntil          time.Time
+		removeExpiredOverrides bool
+		expectedRemoved        bool
+	}{
+		{
+			testTitle:              "removes an expired override when configured to",
+			action:                 "stop",
+			overrideUntil:          overrideUntil,
+			removeExpiredOverrides: true,
+			expectedRemoved:        true,
+		},
+		{
+			testTitle:     "keeps an expired override unless configured to remove it",
+			action:        "stop",
+			overrideUntil: overrideUntil,
+		},
+		{
+			testTitle:              "keeps an expired override for the test action",
+			action:                 "test",
+			overrideUntil:          overrideUntil,
+			removeExpiredOverrides: true,
+		},
+		{
+			testTitle:              "keeps an active override",
+			action:                 "stop",
+			overrideUntil:          testSchedulingTime.Add(time.Hour),
+			removeExpiredOverrides: true,
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.testTitle, func(t *testing.T) {
+			resource := &mockTaggedResource{}
+			run := &SchedulingRun{Action: subtest.action, Now: testSchedulingTime, RemoveExpiredOverrides: subtest.removeExpiredOverrides}
+			removeExpiredOverride(run, resource, subtest.overrideUntil)
+			if subtest.expectedRemoved {
+				assert.Equal(t, [][]string{{"instance-scheduling-override-until"}}, resource.RemovedTags)
+			} else {
+				assert.Empty(t, resource.RemovedTags)
+			}
+		})
+	}
+}
@@ -4,9 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
-	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -18,15 +23,15 @@ type ISSMGetParameter interface {
 	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
 }
 
-func getParameter(client ISSMGetParameter, parameterName string) string {
-	result, err := client.GetParameter(context.TODO(), &ssm.GetParameterInput{
+func getParameter(ctx context.Context, client ISSMGetParameter, parameterName string) (string, error) {
+	result, err := client.GetParameter(ctx, &ssm.GetParameterInput{
 		Name:           aws.String(parameterName),
 		WithDecryption: aws.Bool(true),
 	})
 	if err != nil {
-		log.Fatal(err)
+		return "", fmt.Errorf("failed to get parameter %v: %w", parameterName, err)
 	}
-	return *result.Parameter.Value
+	return *result.Parameter.Value, nil
 }
 
 func CreateSSMClient(config aws.Config) ISSMGetParameter {
@@ -37,24 +42,32 @@ type ISecretManagerGetSecretValue interface {
 	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
 }
 
-func getSecret(client ISecretManagerGetSecretValue, secretId string) string {
-	result, err := client.GetSecretValue(context.TODO(), &secretsmanager.GetSecretValueInput{
+func getSecret(ctx context.Context, client ISecretManagerGetSecretValue, secretId string) (string, error) {
+	result, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
 		SecretId:     aws.String(secretId),
 		VersionStage: aws.String("AWSCURRENT"),
 	})
 
 	if err != nil {
-		log.Fatal(err)
+		return "", fmt.Errorf("failed to get secret %v: %w", secretId, err)
 	}
-	return *result.SecretString
+	return *result.SecretString, nil
 }
 
 func CreateSecretManagerClient(config aws.Config) ISecretManagerGetSecretValue {
 	return secretsmanager.NewFromConfig(config)
 }
 
-func getNonProductionAccounts(environments string) map[string]string {
-    accounts := make(map[string]string)
+// NonProductionAccount is an account in scope of the instance scheduler, with the options set for its environment
+type NonProductionAccount struct {
+	Id                  string
+	StartOnBankHolidays bool
+	// Regions overrides the regions scheduled in the account when it is not empty
+	Regions []string
+}
+
+func getNonProductionAccounts(environments string) (map[string]NonProductionAccount, error) {
+    accounts := make(map[string]NonProductionAccount)
 
     // Fetch the list of in-scope environments from modernisation-platform/environments
     baseURL := "https://api.github.com/repos"
@@ -66,17 +79,19 @@ func getNonProductionAccounts(environments string) map[string]string {
     // Step 1: Fetch the JSON data from GitHub
     body, err := fetchGitHubData(baseURL, repoOwner, repoName, branch, directory)
     if err != nil {
-        log.Fatalf("getNonProductionAccounts - Failed to fetch directory listing from GitHub: %v", err)
+        return nil, fmt.Errorf("getNonProductionAccounts - Failed to fetch directory listing from GitHub: %w", err)
     }
 
     // Step 2: Process the JSON data
     files, err := processGitHubData(body)
     if err != nil {
-        log.Fatalf("getNonProductionAccounts - Failed to process GitHub data: %v", err)
+        return nil, fmt.Errorf("getNonProductionAccounts - Failed to process GitHub data: %w", err)
     }
 
 	// Step 3: Iterate through returned files, check the JSON of each file and obtain a list of accounts to be inlcuded by the scheduler
     var result []string
+    var startOnBankHolidays []string
+    regions := make(map[string][]string)
 
     for _, file := range files {
         // Only process JSON files
@@ -105,6 +120,14 @@ func getNonProductionAccounts(environments string) map[string]string {
                     for _, name := range names {
                         finalName := fmt.Sprintf("%s-%s", fileNameWithoutExt, name)
                         result = append(result, finalName)
+                    }
+					// Environments may opt out of staying stopped on bank holidays, and may be scheduled in other regions than the default ones
+                    options := extractSchedulingOptions(content, fileNameWithoutExt)
+                    for _, name := range options.startOnBankHolidays {
+                        startOnBankHolidays = append(startOnBankHolidays, fmt.Sprintf("%s-%s", fileNameWithoutExt, name))
+                    }
+                    for name, nameRegions := range options.regions {
+                        regions[fmt.Sprintf("%s-%s", fileNameWithoutExt, name)] = nameRegions
                     }
                 }
             }
@@ -116,7 +139,9 @@ func getNonProductionAccounts(environments string) map[string]string {
 
     // Parse the environments secret into a json object
     var allAccounts map[string]interface{}
-    json.Unmarshal([]byte(environments), &allAccounts)
+    if err := json.Unmarshal([]byte(environments), &allAccounts); err != nil {
+        return nil, fmt.Errorf("getNonProductionAccounts - Failed to parse the environments secret: %w", err)
+    }
 
     // This checks the secret of account names & numbers against those from "result" above to get definative list of numbers to be included in the scheduler run.
     log.Printf("getNonProductionAccounts - Iterating over the fetched JSON from environments")
@@ -124,14 +149,14 @@ func getNonProductionAccounts(environments string) map[string]string {
         if rec, ok := record.(map[string]interface{}); ok {
             for key, val := range rec {
                 // Include if the account's name is in the fetched list
-                if contains(recordSlice, key) {
-                    accounts[key] = val.(string)
+                if slices.Contains(recordSlice, key) {
+                    accounts[key] = NonProductionAccount{Id: val.(string), StartOnBankHolidays: slices.Contains(startOnBankHolidays, key), Regions: regions[key]}
                     fmt.Println("getNonProductionAccounts - Added account to list:", key)
                 }
             }
         }
     }
-    return accounts
+    return accounts, nil
 }
 
 func parseAction(action string) (string, error) {
@@ -139,24 +164,112 @@ func parseAction(action string) (string, error) {
 	actionAsLower := strings.ToLower(action)
 
 	switch actionAsLower {
-	case "test", "start", "stop":
+	case "test", "start", "stop", "reconcile":
 		return actionAsLower, nil
 	}
-	return "", errors.New("ERROR: Invalid Action. Must be one of 'start' 'stop' 'test'")
+	return "", errors.New("ERROR: Invalid Action. Must be one of 'start' 'stop' 'test' 'reconcile'")
 }
 
-func LoadDefaultConfig() (aws.Config, error) {
-	return config.LoadDefaultConfig(context.TODO(), config.WithRegion("eu-west-2"))
+// invalidActionError is returned when a resource type is scheduled for an action that parseAction does not accept
+func invalidActionError(action string) error {
+	return fmt.Errorf("invalid action: [ %v ]", action)
 }
 
-// Helper function to check if a slice contains a string
-func contains(slice []string, item string) bool {
-    for _, s := range slice {
-        if s == item {
-            return true
-        }
-    }
-    return false
+func LoadDefaultConfig(ctx context.Context) (aws.Config, error) {
+	return config.LoadDefaultConfig(ctx, config.WithRegion(defaultRegion))
 }
 
+// Helper function to read an environment variable, falling back to a default when it is not set
+func getEnv(key string, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+// defaultPageSize is the number of resources requested per Describe call, the largest that DescribeDBInstances accepts
+const defaultPageSize int32 = 100
+
+// parsePageSize reads the number of resources requested per Describe call, where zero leaves the page size to AWS.
+// Each Describe call fits it into the range it accepts with clampPageSize.
+func parsePageSize(value string) int32 {
+	if value == "" {
+		return defaultPageSize
+	}
+	pageSize, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || pageSize < 0 {
+		log.Printf("WARN: Ignored invalid page size '%v', requesting %v resources per page\n", value, defaultPageSize)
+		return defaultPageSize
+	}
+	return int32(pageSize)
+}
+
+// clampPageSize fits a page size into the range accepted by a Describe call
+func clampPageSize(pageSize int32, minPageSize int32, maxPageSize int32) int32 {
+	return max(minPageSize, min(pageSize, maxPageSize))
+}
 
+// defaultConcurrency is the number of member accounts scheduled at the same time
+const defaultConcurrency = 5
+
+// parseConcurrency reads the number of member accounts scheduled at the same time, which is at least one
+func parseConcurrency(value string) int {
+	if value == "" {
+		return defaultConcurrency
+	}
+	concurrency, err := strconv.Atoi(value)
+	if err != nil || concurrency < 1 {
+		log.Printf("WARN: Ignored invalid concurrency '%v', scheduling %v accounts at a time\n", value, defaultConcurrency)
+		return defaultConcurrency
+	}
+	return concurrency
+}
+
+// defaultDeadlineMargin is how much of the Lambda's remaining time must be left to start scheduling another account
+const defaultDeadlineMargin = 30 * time.Second
+
+// parseDeadlineMargin reads how much time must remain before the Lambda deadline to start scheduling another
+// account, as a duration such as 45s
+func parseDeadlineMargin(value string) time.Duration {
+	if value == "" {
+		return defaultDeadlineMargin
+	}
+	margin, err := time.ParseDuration(value)
+	if err != nil || margin < 0 {
+		log.Printf("WARN: Ignored invalid deadline margin '%v', stopping %v before the deadline\n", value, defaultDeadlineMargin)
+		return defaultDeadlineMargin
+	}
+	return margin
+}
+
+// defaultRegion is the region of the lambda, where resources are scheduled unless other regions are configured
+const defaultRegion = "eu-west-2"
+
+// regionPattern matches the names of AWS regions, such as eu-west-2, us-gov-west-1 or us-iso-east-1
+var regionPattern = regexp.MustCompile(`^[a-z]{2}(-gov|-iso[a-z]?)?-[a-z]+-[0-9]+$`)
+
+// isValidRegion reports whether the value is the name of an AWS region
+func isValidRegion(region string) bool {
+	return regionPattern.MatchString(region)
+}
+
+// parseRegions reads a comma separated list of the regions scheduled in every member account, ignoring those that
+// are not region names
+func parseRegions(value string) []string {
+	var regions []string
+	for _, region := range strings.Split(value, ",") {
+		region = strings.TrimSpace(region)
+		if region == "" || slices.Contains(regions, region) {
+			continue
+		}
+		if !isValidRegion(region) {
+			log.Printf("WARN: Ignored invalid region '%v'\n", region)
+			continue
+		}
+		regions = append(regions, region)
+	}
+	if len(regions) == 0 {
+		return []string{defaultRegion}
+	}
+	return regions
+}
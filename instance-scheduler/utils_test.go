@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	// "reflect"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
@@ -50,7 +52,8 @@ func TestGetParameter(t *testing.T) {
 
 	for i, subtest := range tests {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			parameter := getParameter(subtest.client(t), subtest.name)
+			parameter, err := getParameter(context.Background(), subtest.client(t), subtest.name)
+			assert.NoError(t, err)
 			if want, got := subtest.want, parameter; want != got {
 				t.Errorf("want %v, got %v", subtest.want, got)
 			}
@@ -93,7 +96,8 @@ func TestGetSecret(t *testing.T) {
 
 	for i, subtest := range cases {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			secret := getSecret(subtest.client(t), subtest.secretId)
+			secret, err := getSecret(context.Background(), subtest.client(t), subtest.secretId)
+			assert.NoError(t, err)
 			if want, got := subtest.want, secret; want != got {
 				t.Errorf("want %v, got %v", subtest.want, got)
 			}
@@ -193,6 +197,12 @@ func TestParseAction(t *testing.T) {
 			want:        "stop",
 			expectError: false,
 		},
+		{
+			title:       "returns 'reconcile' for `Reconcile`",
+			action:      "Reconcile",
+			want:        "reconcile",
+			expectError: false,
+		},
 		{
 			title:       "returns empty string and error for invalid action`",
 			action:      "Invalid action name! 😱",
@@ -213,3 +223,208 @@ func TestParseAction(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePageSize(t *testing.T) {
+	tests := []struct {
+		title    string
+		pageSize string
+		want     int32
+	}{
+		{
+			title:    "returns the default page size when unset",
+			pageSize: "",
+			want:     100,
+		},
+		{
+			title:    "returns the configured page size",
+			pageSize: "50",
+			want:     50,
+		},
+		{
+			title:    "returns zero to leave the page size to AWS",
+			pageSize: "0",
+			want:     0,
+		},
+		{
+			title:    "returns the default page size for an invalid value",
+			pageSize: "lots",
+			want:     100,
+		},
+		{
+			title:    "returns the default page size for a negative value",
+			pageSize: "-1",
+			want:     100,
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.title, func(t *testing.T) {
+			assert.Equal(t, subtest.want, parsePageSize(subtest.pageSize))
+		})
+	}
+}
+
+func TestClampPageSize(t *testing.T) {
+	tests := []struct {
+		title    string
+		pageSize int32
+		want     int32
+	}{
+		{
+			title:    "raises a page size below the minimum",
+			pageSize: 19,
+			want:     20,
+		},
+		{
+			title:    "keeps the minimum page size",
+			pageSize: 20,
+			want:     20,
+		},
+		{
+			title:    "keeps the maximum page size",
+			pageSize: 100,
+			want:     100,
+		},
+		{
+			title:    "lowers a page size above the maximum",
+			pageSize: 101,
+			want:     100,
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.title, func(t *testing.T) {
+			assert.Equal(t, subtest.want, clampPageSize(subtest.pageSize, rdsMinPageSize, rdsMaxPageSize))
+		})
+	}
+}
+
+func TestParseConcurrency(t *testing.T) {
+	tests := []struct {
+		title       string
+		concurrency string
+		want        int
+	}{
+		{
+			title:       "returns the default concurrency when unset",
+			concurrency: "",
+			want:        5,
+		},
+		{
+			title:       "returns the configured concurrency",
+			concurrency: "20",
+			want:        20,
+		},
+		{
+			title:       "returns the default concurrency for zero",
+			concurrency: "0",
+			want:        5,
+		},
+		{
+			title:       "returns the default concurrency for an invalid value",
+			concurrency: "many",
+			want:        5,
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.title, func(t *testing.T) {
+			assert.Equal(t, subtest.want, parseConcurrency(subtest.concurrency))
+		})
+	}
+}
+
+func TestParseDeadlineMargin(t *testing.T) {
+	tests := []struct {
+		title  string
+		margin string
+		want   time.Duration
+	}{
+		{
+			title:  "returns the default margin when unset",
+			margin: "",
+			want:   30 * time.Second,
+		},
+		{
+			title:  "returns the configured margin",
+			margin: "2m",
+			want:   2 * time.Minute,
+		},
+		{
+			title:  "returns zero to use all the remaining time",
+			margin: "0s",
+			want:   0,
+		},
+		{
+			title:  "returns the default margin for a negative duration",
+			margin: "-10s",
+			want:   30 * time.Second,
+		},
+		{
+			title:  "returns the default margin for a number without a unit",
+			margin: "45",
+			want:   30 * time.Second,
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.title, func(t *testing.T) {
+			assert.Equal(t, subtest.want, parseDeadlineMargin(subtest.margin))
+		})
+	}
+}
+
+func TestGetParameterReturnsError(t *testing.T) {
+	client := mockGetParameter(func(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+		return nil, errors.New("ParameterNotFound")
+	})
+
+	_, err := getParameter(context.Background(), client, "test-parameter-name")
+
+	assert.ErrorContains(t, err, "failed to get parameter test-parameter-name: ParameterNotFound")
+}
+
+func TestGetSecretReturnsError(t *testing.T) {
+	client := mockGetSecretValue(func(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+		return nil, errors.New("AccessDeniedException")
+	})
+
+	_, err := getSecret(context.Background(), client, "test-mod-platform-account-development")
+
+	assert.ErrorContains(t, err, "failed to get secret test-mod-platform-account-development: AccessDeniedException")
+}
+
+func TestParseRegions(t *testing.T) {
+	tests := []struct {
+		title   string
+		regions string
+		want    []string
+	}{
+		{
+			title:   "returns the region of the lambda when unset",
+			regions: "",
+			want:    []string{"eu-west-2"},
+		},
+		{
+			title:   "returns the configured regions in order",
+			regions: "eu-west-2,eu-west-1,us-east-1",
+			want:    []string{"eu-west-2", "eu-west-1", "us-east-1"},
+		},
+		{
+			title:   "ignores spaces, empty entries and duplicates",
+			regions: " eu-west-1, ,eu-west-2,eu-west-1,",
+			want:    []string{"eu-west-1", "eu-west-2"},
+		},
+		{
+			title:   "ignores values that are not region names",
+			regions: "eu-west-1,eu-west-2a,London,us-gov-west-1",
+			want:    []string{"eu-west-1", "us-gov-west-1"},
+		},
+	}
+
+	for _, subtest := range tests {
+		t.Run(subtest.title, func(t *testing.T) {
+			assert.Equal(t, subtest.want, parseRegions(subtest.regions))
+		})
+	}
+}